@@ -1,9 +1,15 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/georgib0y/relientldap/internal/app"
 	d "github.com/georgib0y/relientldap/internal/domain"
@@ -15,16 +21,96 @@ var logger = log.New(os.Stderr, "main: ", log.Lshortfile)
 type Config struct {
 	attributeLdifPath   string
 	objectClassLdifPath string
+	ldapAddr            string
+	// LDAPS and StartTLS are only enabled when a certificate and key are given
+	ldapsAddr   string
+	tlsCertPath string
+	tlsKeyPath  string
+	// client certificates signed by these CAs can bind with SASL EXTERNAL
+	tlsClientCAPath string
+	// where changes made to the schema through the subschema are kept, they
+	// are not kept if empty
+	schemaDir string
+	// the scheme new userPassword values are hashed with
+	passwordScheme string
+	// also store SCRAM-SHA-256 verifiers with new userPassword values
+	scramVerifiers bool
+	// RFC 4513 binds that do not authenticate the connection
+	allowAnonymousBind       bool
+	allowUnauthenticatedBind bool
+	// where SASL usernames are looked up
+	saslIdentityBase string
+	saslIdentityAttr string
 }
 
+func loadTLSConfig(config Config) (*tls.Config, error) {
+	if config.tlsCertPath == "" && config.tlsKeyPath == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.tlsCertPath, config.tlsKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	if config.tlsClientCAPath != "" {
+		pem, err := os.ReadFile(config.tlsClientCAPath)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.tlsClientCAPath)
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+func serve(l net.Listener, mux *server.Mux) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		go mux.Serve(c)
+		logger.Print("accepted connection, serving...")
+	}
+}
+
+// the files in the schema dir that the schema is persisted to
+const (
+	persistedAttributeLdif   = "attributes.ldif"
+	persistedObjectClassLdif = "objClasses.ldif"
+)
+
+// Loads the schema persisted in the schema dir, or the configured ldif files
+// if nothing has been persisted yet
 func loadSchema(config Config) (*d.Schema, error) {
-	fattr, err := os.Open(config.attributeLdifPath)
+	attrPath, ocPath := config.attributeLdifPath, config.objectClassLdifPath
+	if config.schemaDir != "" {
+		persistedAttrs := filepath.Join(config.schemaDir, persistedAttributeLdif)
+		persistedOcs := filepath.Join(config.schemaDir, persistedObjectClassLdif)
+		// object classes are written last, so both files exist if they do
+		if _, err := os.Stat(persistedOcs); err == nil {
+			attrPath, ocPath = persistedAttrs, persistedOcs
+		}
+	}
+
+	fattr, err := os.Open(attrPath)
 	if err != nil {
 		return nil, err
 	}
 	defer fattr.Close()
 
-	focs, err := os.Open(config.objectClassLdifPath)
+	focs, err := os.Open(ocPath)
 	if err != nil {
 		return nil, err
 	}
@@ -34,12 +120,60 @@ func loadSchema(config Config) (*d.Schema, error) {
 
 }
 
+// Writes the schema to the schema dir, attributes first as object classes
+// refer to them
+func persistSchema(config Config) func(attrs, objClasses []byte) error {
+	return func(attrs, objClasses []byte) error {
+		if err := os.MkdirAll(config.schemaDir, 0755); err != nil {
+			return err
+		}
+
+		if err := replaceFile(filepath.Join(config.schemaDir, persistedAttributeLdif), attrs); err != nil {
+			return err
+		}
+		return replaceFile(filepath.Join(config.schemaDir, persistedObjectClassLdif), objClasses)
+	}
+}
+
+// Writes to a temporary file that is renamed over path, so that a failed write
+// leaves path as it was
+func replaceFile(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 func main() {
 	// TODO remove hardcoded config
 	config := Config{
 		attributeLdifPath:   "ldif/attributes.ldif",
 		objectClassLdifPath: "ldif/objClasses.ldif",
 	}
+	flag.StringVar(&config.schemaDir, "schema-dir", "", "directory that changes made to the schema are written to and loaded from, changes are not kept if empty")
+	flag.StringVar(&config.ldapAddr, "ldap-addr", ":8000", "address to listen for ldap connections on")
+	flag.StringVar(&config.ldapsAddr, "ldaps-addr", ":8636", "address to listen for ldaps connections on")
+	flag.StringVar(&config.tlsCertPath, "tls-cert", "", "path to the PEM encoded tls certificate")
+	flag.StringVar(&config.tlsKeyPath, "tls-key", "", "path to the PEM encoded tls private key")
+	flag.StringVar(&config.tlsClientCAPath, "tls-client-ca", "", "path to the PEM encoded CAs that client certificates are verified with")
+	flag.StringVar(&config.passwordScheme, "password-scheme", "ARGON2", "scheme to hash new passwords with, one of "+strings.Join(app.PasswordSchemes(), ", "))
+	flag.BoolVar(&config.scramVerifiers, "scram-verifiers", false, "also store a SCRAM-SHA-256 verifier with new passwords so they can be used for SCRAM binds, simple binds accept the verifier too")
+	flag.BoolVar(&config.allowAnonymousBind, "allow-anonymous-bind", true, "allow binds with an empty name and password")
+	flag.BoolVar(&config.allowUnauthenticatedBind, "allow-unauthenticated-bind", false, "allow binds with a name and an empty password as anonymous")
+	flag.StringVar(&config.saslIdentityBase, "sasl-identity-base", "", "base dn that SASL usernames are looked up under, the whole DIT if empty")
+	flag.StringVar(&config.saslIdentityAttr, "sasl-identity-attr", "uid", "attribute that holds SASL usernames")
+	flag.Parse()
+
+	if err := app.SetDefaultPasswordScheme(config.passwordScheme); err != nil {
+		logger.Fatal(err)
+	}
+	app.SetStoreScramVerifiers(config.scramVerifiers)
+
+	tlsConfig, err := loadTLSConfig(config)
+	if err != nil {
+		logger.Fatalf("could not load tls certificate: %s", err)
+	}
 
 	schema, err := loadSchema(config)
 	if err != nil {
@@ -56,33 +190,67 @@ func main() {
 
 	mux := server.NewMux()
 
-	bindService := app.NewBindService(schema, scheduler)
+	bindService := app.NewBindService(schema, scheduler,
+		app.WithAnonymousBind(config.allowAnonymousBind),
+		app.WithUnauthenticatedBind(config.allowUnauthenticatedBind),
+		app.WithSaslIdentityMapping(config.saslIdentityBase, config.saslIdentityAttr),
+	)
 	mux.AddHandler(server.NewBindHandler(bindService))
 	mux.AddHandler(server.UnbindHandler)
+	mux.AddHandler(server.AbandonHandler)
+	mux.AddExtendedHandler(server.WhoAmIHandler)
 
 	addService := app.NewAddService(schema, scheduler)
 	mux.AddHandler(server.NewAddHandler(addService))
 
-	modifyService := app.NewModifyService(schema, scheduler)
+	modifyOptions := []app.ModifyOption{}
+	if config.schemaDir != "" {
+		modifyOptions = append(modifyOptions, app.WithSchemaPersistence(persistSchema(config)))
+	}
+	modifyService := app.NewModifyService(schema, scheduler, modifyOptions...)
 	mux.AddHandler(server.NewModifyHandler(modifyService))
 	mux.AddHandler(server.NewModifyDnHandler(modifyService))
 
-	logger.Print("added handlers to mux")
+	deleteService := app.NewDeleteService(schema, scheduler)
+	mux.AddHandler(server.NewDeleteHandler(deleteService))
 
-	l, err := net.Listen("tcp", ":8000")
-	if err != nil {
-		logger.Fatal(err)
+	passwordService := app.NewPasswordService(schema, scheduler)
+	mux.AddExtendedHandler(server.NewPasswordModifyHandler(passwordService))
+
+	compareService := app.NewCompareService(schema, scheduler)
+	mux.AddHandler(server.NewCompareHandler(compareService))
+
+	// read when the root DSE is searched, so includes handlers added after
+	searchService := app.NewSearchService(schema, scheduler, app.WithRootDSE(func() map[string][]string {
+		return map[string][]string{
+			"supportedControl":        mux.SupportedControls(),
+			"supportedExtension":      mux.SupportedExtensions(),
+			"supportedSASLMechanisms": bindService.SupportedSaslMechanisms(),
+		}
+	}))
+	mux.AddHandler(server.NewSearchHandler(searchService))
+
+	if tlsConfig != nil {
+		mux.AddExtendedHandler(server.NewStartTLSHandler(tlsConfig))
 	}
 
-	logger.Print("created new listner, listening...")
-	for {
-		c, err := l.Accept()
+	logger.Print("added handlers to mux")
+
+	if tlsConfig != nil {
+		ls, err := tls.Listen("tcp", config.ldapsAddr, tlsConfig)
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		go mux.Serve(c)
-		logger.Print("accepted connection, serving...")
+		logger.Printf("listening for ldaps on %s", config.ldapsAddr)
+		go serve(ls, mux)
+	}
+
+	l, err := net.Listen("tcp", config.ldapAddr)
+	if err != nil {
+		logger.Fatal(err)
 	}
 
+	logger.Print("created new listner, listening...")
+	serve(l, mux)
 }
@@ -2,4 +2,10 @@ module github.com/georgib0y/relientldap
 
 go 1.24.0
 
-require github.com/go-asn1-ber/asn1-ber v1.5.7
+require (
+	github.com/go-asn1-ber/asn1-ber v1.5.7
+	golang.org/x/crypto v0.48.0
+	golang.org/x/text v0.34.0
+)
+
+require golang.org/x/sys v0.41.0 // indirect
@@ -57,6 +57,14 @@ func (a *AddService) attributeOpts(reqAttrs map[string][]string) ([]d.EntryOptio
 		if !ok {
 			return nil, d.NewLdapError(d.UndefinedAttributeType, nil, "unknown attribute %s", name)
 		}
+		if attr.NoUserMod() {
+			return nil, d.NewLdapError(d.ConstraintViolation, nil, "attribute %s cannot be set by users", name)
+		}
+
+		vals, err := encodeAttrVals(a.schema, attr, vals)
+		if err != nil {
+			return nil, err
+		}
 
 		opts = append(opts, d.WithEntryAttr(attr, vals...))
 	}
@@ -64,7 +72,8 @@ func (a *AddService) attributeOpts(reqAttrs map[string][]string) ([]d.EntryOptio
 	return opts, nil
 }
 
-func (a *AddService) AddEntry(ar AddRequest) (*d.Entry, error) {
+// Adds the entry in the request, created by the bound entry or anonymously if nil
+func (a *AddService) AddEntry(bound *d.Entry, ar AddRequest) (*d.Entry, error) {
 	dn, err := d.NormaliseDN(a.schema, ar.Dn())
 	if err != nil {
 		return nil, err
@@ -92,6 +101,6 @@ func (a *AddService) AddEntry(ar AddRequest) (*d.Entry, error) {
 	}
 
 	return entry, ScheduleAwaitError(a.scheduler, func(dit d.DIT) error {
-		return dit.InsertEntry(dn, entry)
+		return dit.InsertEntry(dn, entry, changeStamp(bound))
 	})
 }
@@ -1,12 +1,22 @@
 package app
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	d "github.com/georgib0y/relientldap/internal/domain"
 	"github.com/georgib0y/relientldap/internal/util"
@@ -130,6 +140,285 @@ func TestBindService(t *testing.T) {
 	}
 }
 
+func TestBindPolicy(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	test1 := "cn=Test1,dc=georgiboy,dc=dev"
+	anonymous := TestSimpleBindRequest{"", ""}
+	unauthenticated := TestSimpleBindRequest{test1, ""}
+
+	tests := []struct {
+		options []BindOption
+		req     BindRequest
+		err     error
+	}{
+		{req: anonymous},
+		{req: unauthenticated, err: d.NewLdapError(d.UnwillingToPerform, nil, "")},
+		{req: TestSimpleBindRequest{"", "password123"}, err: d.NewLdapError(d.InvalidCredentials, nil, "")},
+		{options: []BindOption{WithAnonymousBind(false)}, req: anonymous, err: d.NewLdapError(d.InappropriateAuthentication, nil, "")},
+		{options: []BindOption{WithUnauthenticatedBind(true)}, req: unauthenticated},
+		{options: []BindOption{WithUnauthenticatedBind(true)}, req: TestSimpleBindRequest{"cn", ""}, err: d.NewLdapError(d.InvalidDnSyntax, nil, "")},
+		{options: []BindOption{WithUnauthenticatedBind(true), WithAnonymousBind(false)}, req: unauthenticated, err: d.NewLdapError(d.InappropriateAuthentication, nil, "")},
+	}
+
+	for i, test := range tests {
+		bs := NewBindService(schema, scheduler, test.options...)
+		entry, err := bs.Bind(test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d returned error %v, expected %v", i, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d returned unexpected error: %s", i, err)
+		}
+		if entry != nil {
+			t.Errorf("test %d should be anonymous but bound as %s", i, entry.Dn())
+		}
+	}
+}
+
+type TestSaslBindRequest struct {
+	mechanism, credentials string
+	external               *ExternalIdentity
+}
+
+func (r TestSaslBindRequest) Dn() string {
+	return ""
+}
+
+func (r TestSaslBindRequest) Version() int {
+	return 3
+}
+
+func (r TestSaslBindRequest) Simple() (string, bool) {
+	return "", false
+}
+
+func (r TestSaslBindRequest) SaslMechanism() (string, bool) {
+	return r.mechanism, true
+}
+
+func (r TestSaslBindRequest) SaslCredentials() (string, bool) {
+	return r.credentials, true
+}
+
+func (r TestSaslBindRequest) ExternalIdentity() (ExternalIdentity, bool) {
+	if r.external == nil {
+		return ExternalIdentity{}, false
+	}
+	return *r.external, true
+}
+
+func TestSaslBind(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	bs := NewBindService(schema, scheduler, WithSaslIdentityMapping("dc=georgiboy,dc=dev", "cn"))
+
+	test1 := "cn=Test1,dc=georgiboy,dc=dev"
+	plain := func(authzId, authcId, password string) TestSaslBindRequest {
+		return TestSaslBindRequest{mechanism: "PLAIN", credentials: authzId + "\x00" + authcId + "\x00" + password}
+	}
+
+	tests := []struct {
+		req   TestSaslBindRequest
+		bound string
+		err   error
+	}{
+		{req: plain("", "Test1", "password123"), bound: test1},
+		{req: plain("", "u:test1", "password123"), bound: test1},
+		{req: plain("", "dn:"+test1, "password123"), bound: test1},
+		{req: plain("dn:"+test1, "Test1", "password123"), bound: test1},
+		{req: plain("", "Test1", "wrong"), err: d.NewLdapError(d.InvalidCredentials, nil, "")},
+		{req: plain("", "Nobody", "password123"), err: d.NewLdapError(d.InvalidCredentials, nil, "")},
+		{req: plain("u:Test2", "Test1", "password123"), err: d.NewLdapError(d.InsufficientAccessRights, nil, "")},
+		{req: TestSaslBindRequest{mechanism: "PLAIN", credentials: "Test1\x00password123"}, err: d.NewLdapError(d.InvalidCredentials, nil, "")},
+		{req: TestSaslBindRequest{mechanism: "EXTERNAL", external: &ExternalIdentity{SubjectDN: "CN=Test1,DC=georgiboy,DC=dev"}}, bound: test1},
+		{req: TestSaslBindRequest{mechanism: "EXTERNAL", external: &ExternalIdentity{SubjectDN: "CN=Test2,O=Example", CommonName: "Test2"}}, bound: "cn=Test2,ou=TestOu,dc=georgiboy,dc=dev"},
+		{req: TestSaslBindRequest{mechanism: "EXTERNAL", credentials: "u:Test1", external: &ExternalIdentity{SubjectDN: "CN=Test1,DC=georgiboy,DC=dev"}}, bound: test1},
+		{req: TestSaslBindRequest{mechanism: "EXTERNAL", credentials: "u:Test2", external: &ExternalIdentity{SubjectDN: "CN=Test1,DC=georgiboy,DC=dev"}}, err: d.NewLdapError(d.InsufficientAccessRights, nil, "")},
+		{req: TestSaslBindRequest{mechanism: "EXTERNAL"}, err: d.NewLdapError(d.InappropriateAuthentication, nil, "")},
+		{req: TestSaslBindRequest{mechanism: "CRAM-MD5"}, err: d.NewLdapError(d.AuthMethodNotSupported, nil, "")},
+	}
+
+	for i, test := range tests {
+		entry, err := bs.Bind(test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d returned error %v, expected %v", i, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d returned unexpected error: %s", i, err)
+			continue
+		}
+
+		dn := entry.Dn()
+		if exp := util.Unwrap(d.NormaliseDN(schema, test.bound)); !d.CompareDNs(dn, exp) {
+			t.Errorf("test %d bound as %s, expected %s", i, dn.String(), test.bound)
+		}
+	}
+
+	if mechs := bs.SupportedSaslMechanisms(); !slices.Equal(mechs, []string{"EXTERNAL", "PLAIN", "SCRAM-SHA-256"}) {
+		t.Errorf("unexpected supported mechanisms %v", mechs)
+	}
+}
+
+type TestSessionBindRequest struct {
+	TestSaslBindRequest
+	session *SaslSession
+}
+
+func (r TestSessionBindRequest) SaslSession() *SaslSession {
+	return r.session
+}
+
+// the client side of RFC 5802 3, with the server-first message's salt and iterations
+func testScramClientFinal(t *testing.T, clientFirstBare, serverFirst, password string) (clientFinal, serverSignature string) {
+	attrs := strings.Split(serverFirst, ",")
+	if len(attrs) != 3 {
+		t.Fatalf("unexpected server-first message %q", serverFirst)
+	}
+	salt := util.Unwrap(base64.StdEncoding.DecodeString(strings.TrimPrefix(attrs[1], "s=")))
+	iter := util.Unwrap(strconv.Atoi(strings.TrimPrefix(attrs[2], "i=")))
+
+	mac := func(key []byte, s string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(s))
+		return h.Sum(nil)
+	}
+
+	salted := util.Unwrap(pbkdf2.Key(sha256.New, password, salt, iter, sha256.Size))
+	clientKey := mac(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+
+	withoutProof := "c=biws," + attrs[0]
+	authMessage := clientFirstBare + "," + serverFirst + "," + withoutProof
+
+	proof := mac(storedKey[:], authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+
+	serverSignature = base64.StdEncoding.EncodeToString(mac(mac(salted, "Server Key"), authMessage))
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), serverSignature
+}
+
+func TestScramBind(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	bs := NewBindService(schema, scheduler, WithSaslIdentityMapping("dc=georgiboy,dc=dev", "cn"))
+
+	SetStoreScramVerifiers(true)
+	defer SetStoreScramVerifiers(false)
+
+	// binding rehashes the plaintext password of the test dit, storing a verifier
+	test1 := "cn=Test1,dc=georgiboy,dc=dev"
+	if _, err := bs.Bind(TestSimpleBindRequest{test1, "password123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	session := &SaslSession{}
+	scram := func(credentials string) TestSessionBindRequest {
+		return TestSessionBindRequest{TestSaslBindRequest{mechanism: "SCRAM-SHA-256", credentials: credentials}, session}
+	}
+	inProgress := d.NewLdapError(d.SaslBindInProgress, nil, "")
+	invalid := d.NewLdapError(d.InvalidCredentials, nil, "")
+
+	tests := []struct {
+		username, password string
+		err                error
+	}{
+		{username: "Test1", password: "password123"},
+		{username: "dn:" + test1, password: "password123"},
+		{username: "Test1", password: "wrong", err: invalid},
+		{username: "Nobody", password: "password123", err: invalid},
+		{username: "Test2", password: "", err: invalid},
+	}
+
+	saslname := strings.NewReplacer("=", "=3D", ",", "=2C")
+
+	for i, test := range tests {
+		clientFirstBare := "n=" + saslname.Replace(test.username) + ",r=fyko+d2lbbFgONRv9qkxdawL"
+		if _, err := bs.Bind(scram("n,," + clientFirstBare)); !errors.Is(err, inProgress) {
+			t.Errorf("test %d expected the exchange to be in progress, got %v", i, err)
+			continue
+		}
+
+		serverFirst, ok := session.ServerCredentials()
+		if !ok || !strings.HasPrefix(serverFirst, "r=fyko+d2lbbFgONRv9qkxdawL") {
+			t.Errorf("test %d got unexpected server-first message %q", i, serverFirst)
+			continue
+		}
+
+		clientFinal, serverSignature := testScramClientFinal(t, clientFirstBare, serverFirst, test.password)
+		entry, err := bs.Bind(scram(clientFinal))
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d returned error %v, expected %v", i, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("test %d returned unexpected error: %s", i, err)
+			continue
+		}
+
+		dn := entry.Dn()
+		if exp := util.Unwrap(d.NormaliseDN(schema, test1)); !d.CompareDNs(dn, exp) {
+			t.Errorf("test %d bound as %s, expected %s", i, dn.String(), test1)
+		}
+		if serverFinal, _ := session.ServerCredentials(); serverFinal != "v="+serverSignature {
+			t.Errorf("test %d got server-final message %q, expected v=%s", i, serverFinal, serverSignature)
+		}
+	}
+
+	// a bind of another kind aborts the exchange, so the client-final message starts a new one
+	clientFirstBare := "n=Test1,r=fyko+d2lbbFgONRv9qkxdawL"
+	bs.Bind(scram("n,," + clientFirstBare))
+	serverFirst, _ := session.ServerCredentials()
+	clientFinal, _ := testScramClientFinal(t, clientFirstBare, serverFirst, "password123")
+	if _, err := bs.Bind(TestSessionBindRequest{TestSaslBindRequest{mechanism: "EXTERNAL"}, session}); err == nil {
+		t.Fatal("expected an external bind without an identity to fail")
+	}
+	if _, err := bs.Bind(scram(clientFinal)); !errors.Is(err, invalid) {
+		t.Errorf("expected the aborted exchange not to continue, got %v", err)
+	}
+
+	if _, err := bs.Bind(scram("p=tls-unique,,n=Test1,r=fyko+d2lbbFgONRv9qkxdawL")); !errors.Is(err, d.NewLdapError(d.AuthMethodNotSupported, nil, "")) {
+		t.Errorf("expected channel binding not to be supported, got %v", err)
+	}
+
+	// the example exchange of RFC 7677 3
+	e := &scramExchange{
+		b:               bs.(*bindService),
+		gs2Header:       "n,,",
+		clientFirstBare: "n=user,r=rOprNGfwEbeRWgbNEkqO",
+		entry:           util.Unwrap(ScheduleAwait(scheduler, func(dit d.DIT) (*d.Entry, error) { return dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, test1))) })),
+		verifier:        util.Unwrap(parseScramVerifier("4096:W22ZaJ0SNY7soEsUEjb6gQ==$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU=")),
+		nonce:           "rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0",
+		serverFirst:     "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+	}
+	res, err := e.Next("c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=")
+	if err != nil || !res.done || res.serverCreds == nil {
+		t.Fatalf("expected the example exchange to succeed, got %+v: %v", res, err)
+	}
+	if *res.serverCreds != "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=" {
+		t.Errorf("unexpected server-final message %q", *res.serverCreds)
+	}
+}
+
 type TestAddRequest struct {
 	dn    string
 	attrs map[string][]string
@@ -168,7 +457,7 @@ func TestAddService(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		res, err := as.AddEntry(test.req)
+		res, err := as.AddEntry(nil, test.req)
 		if err != nil {
 			if test.err == nil {
 				t.Fatalf("Add service returned unexpected err: %s", err)
@@ -236,3 +525,1088 @@ func TestAddService(t *testing.T) {
 
 	}
 }
+
+type TestSearchRequest struct {
+	baseDn    string
+	scope     d.SearchScope
+	sizeLimit int
+	timeLimit time.Duration
+	typesOnly bool
+	filter    string
+	attrs     []string
+}
+
+func (r TestSearchRequest) BaseDn() string {
+	return r.baseDn
+}
+
+func (r TestSearchRequest) SearchScope() d.SearchScope {
+	return r.scope
+}
+
+func (r TestSearchRequest) Deref() DerefAliases {
+	return NeverDerefAliases
+}
+
+func (r TestSearchRequest) MaxEntries() int {
+	return r.sizeLimit
+}
+
+func (r TestSearchRequest) MaxTime() time.Duration {
+	return r.timeLimit
+}
+
+func (r TestSearchRequest) AttrsOnly() bool {
+	return r.typesOnly
+}
+
+func (r TestSearchRequest) DomainFilter(schema *d.Schema) (d.Filter, error) {
+	return d.ParseFilter(schema, r.filter)
+}
+
+func (r TestSearchRequest) RequestedAttrs() []string {
+	return r.attrs
+}
+
+func TestSearchService(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	ss := NewSearchService(schema, scheduler)
+
+	// searches that hit a limit return partial entries, each of them from dns
+	tests := []struct {
+		req     SearchRequest
+		dns     []string
+		partial int
+		attrs   map[string][]string
+		err     error
+	}{
+		{
+			req: TestSearchRequest{
+				baseDn: "dc=georgiboy,dc=dev",
+				scope:  d.WholeSubtree,
+				filter: "(sn=Tester)",
+				attrs:  []string{"cn"},
+			},
+			dns: []string{
+				"cn=Test1,dc=georgiboy,dc=dev",
+				"cn=Test2,ou=TestOu,dc=georgiboy,dc=dev",
+				"cn=Test3,ou=TestOu,dc=georgiboy,dc=dev",
+			},
+		},
+		{
+			req: TestSearchRequest{
+				baseDn: "cn=Test1,dc=georgiboy,dc=dev",
+				scope:  d.BaseObject,
+				filter: "(objectClass=person)",
+			},
+			dns: []string{"cn=Test1,dc=georgiboy,dc=dev"},
+			attrs: map[string][]string{
+				"cn":           {"Test1"},
+				"sn":           {"One", "Tester"},
+				"objectClass":  {"top", "person"},
+				"userPassword": {"password123"},
+			},
+		},
+		{
+			req: TestSearchRequest{
+				baseDn:    "cn=Test1,dc=georgiboy,dc=dev",
+				scope:     d.BaseObject,
+				typesOnly: true,
+				filter:    "(cn=Test1)",
+				attrs:     []string{"sn"},
+			},
+			dns:   []string{"cn=Test1,dc=georgiboy,dc=dev"},
+			attrs: map[string][]string{"sn": {}},
+		},
+		{
+			req: TestSearchRequest{
+				baseDn:    "ou=TestOu,dc=georgiboy,dc=dev",
+				scope:     d.SingleLevel,
+				sizeLimit: 1,
+				filter:    "(sn=Tester)",
+				attrs:     []string{"1.1"},
+			},
+			dns: []string{
+				"cn=Test2,ou=TestOu,dc=georgiboy,dc=dev",
+				"cn=Test3,ou=TestOu,dc=georgiboy,dc=dev",
+			},
+			partial: 1,
+			err:     d.NewLdapError(d.SizeLimitExceeded, nil, ""),
+		},
+		{
+			// the deadline passes before the walk reaches the first entry
+			req: TestSearchRequest{
+				baseDn:    "dc=georgiboy,dc=dev",
+				scope:     d.WholeSubtree,
+				timeLimit: time.Nanosecond,
+				filter:    "(sn=Tester)",
+			},
+			dns: []string{
+				"cn=Test1,dc=georgiboy,dc=dev",
+				"cn=Test2,ou=TestOu,dc=georgiboy,dc=dev",
+				"cn=Test3,ou=TestOu,dc=georgiboy,dc=dev",
+			},
+			partial: 0,
+			err:     d.NewLdapError(d.TimeLimitExceeded, nil, ""),
+		},
+		{
+			req: TestSearchRequest{
+				baseDn: "dc=georgiboy,dc=dev",
+				scope:  d.WholeSubtree,
+				filter: "(&(objectClass=person)(!(cn=Test1))(|(cn=test*)(unknownAttr=x)))",
+				attrs:  []string{"1.1"},
+			},
+			dns: []string{
+				"cn=Test2,ou=TestOu,dc=georgiboy,dc=dev",
+				"cn=Test3,ou=TestOu,dc=georgiboy,dc=dev",
+			},
+		},
+		{
+			req: TestSearchRequest{
+				baseDn: "ou=Missing,dc=georgiboy,dc=dev",
+				scope:  d.WholeSubtree,
+				filter: "(sn=Tester)",
+			},
+			err: d.NewLdapError(d.NoSuchObject, nil, ""),
+		},
+	}
+
+	for _, test := range tests {
+		res, err := ss.Search(context.Background(), test.req)
+		if test.err == nil && err != nil {
+			t.Fatalf("Search service returned unexpected error: %s", err)
+		}
+
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Fatalf("Search service returned error: %q but expected: %q", err, test.err)
+		}
+
+		// limited results could be any of the matching entries
+		if test.err != nil {
+			if len(res) != test.partial {
+				t.Fatalf("Search service returned %d entries but expected %d", len(res), test.partial)
+			}
+
+			for i, e := range res {
+				if !slices.Contains(test.dns, e.Dn) {
+					t.Fatalf("Search service returned %q which does not match", e.Dn)
+				}
+				if slices.ContainsFunc(res[:i], func(o SearchEntry) bool { return o.Dn == e.Dn }) {
+					t.Fatalf("Search service returned %q more than once", e.Dn)
+				}
+			}
+			continue
+		}
+
+		if len(res) != len(test.dns) {
+			t.Fatalf("Search service returned %d entries but expected %d", len(res), len(test.dns))
+		}
+
+		for _, dn := range test.dns {
+			if !slices.ContainsFunc(res, func(e SearchEntry) bool { return e.Dn == dn }) {
+				t.Fatalf("Search service results did not contain %q", dn)
+			}
+		}
+
+		if test.attrs == nil {
+			continue
+		}
+
+		if !util.CmpMapKeys(res[0].Attrs, test.attrs) {
+			t.Fatalf("Search service returned attrs %v but expected %v", res[0].Attrs, test.attrs)
+		}
+
+		for name, vals := range test.attrs {
+			if len(vals) != len(res[0].Attrs[name]) {
+				t.Fatalf("Search service returned %v for %q but expected %v", res[0].Attrs[name], name, vals)
+			}
+			for _, v := range vals {
+				if !slices.Contains(res[0].Attrs[name], v) {
+					t.Fatalf("Search service result is missing %q for %q", v, name)
+				}
+			}
+		}
+	}
+}
+
+func TestRootDSE(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	ss := NewSearchService(schema, scheduler, WithRootDSE(func() map[string][]string {
+		return map[string][]string{
+			"supportedExtension":      {"1.3.6.1.4.1.4203.1.11.3"},
+			"supportedSASLMechanisms": {"PLAIN"},
+			"supportedControl":        {},
+		}
+	}))
+
+	tests := []struct {
+		req   TestSearchRequest
+		attrs map[string][]string
+	}{
+		{
+			req:   TestSearchRequest{filter: "(objectClass=*)"},
+			attrs: map[string][]string{"objectClass": {"top"}},
+		},
+		{
+			req: TestSearchRequest{filter: "(objectClass=*)", attrs: []string{"+"}},
+			attrs: map[string][]string{
+				"namingContexts":          {"dc=dev"},
+				"supportedLDAPVersion":    {"3"},
+				"supportedFeatures":       supportedFeatures,
+				"supportedExtension":      {"1.3.6.1.4.1.4203.1.11.3"},
+				"supportedSASLMechanisms": {"PLAIN"},
+				"subschemaSubentry":       {"cn=Subschema"},
+			},
+		},
+		{
+			req:   TestSearchRequest{filter: "(supportedFeatures=1.3.6.1.4.1.4203.1.5.3)", attrs: []string{"supportedsaslmechanisms", "namingContexts"}},
+			attrs: map[string][]string{"supportedSASLMechanisms": {"PLAIN"}, "namingContexts": {"dc=dev"}},
+		},
+		{
+			req: TestSearchRequest{filter: "(supportedFeatures=1.2.3)"},
+		},
+	}
+
+	for i, test := range tests {
+		res, err := ss.Search(context.Background(), test.req)
+		if err != nil {
+			t.Fatalf("test %d returned unexpected error: %s", i, err)
+		}
+
+		if test.attrs == nil {
+			if len(res) != 0 {
+				t.Errorf("test %d expected no entries, got %v", i, res)
+			}
+			continue
+		}
+
+		if len(res) != 1 || res[0].Dn != "" {
+			t.Fatalf("test %d expected only the root DSE, got %v", i, res)
+		}
+
+		if !util.CmpMapKeys(res[0].Attrs, test.attrs) {
+			t.Errorf("test %d returned attrs %v but expected %v", i, res[0].Attrs, test.attrs)
+			continue
+		}
+		for name, vals := range test.attrs {
+			if !slices.Equal(slices.Sorted(slices.Values(res[0].Attrs[name])), slices.Sorted(slices.Values(vals))) {
+				t.Errorf("test %d returned %v for %s but expected %v", i, res[0].Attrs[name], name, vals)
+			}
+		}
+	}
+}
+
+func TestSubschema(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	ss := NewSearchService(schema, scheduler)
+
+	tests := []struct {
+		req   TestSearchRequest
+		attrs []string
+	}{
+		{
+			req:   TestSearchRequest{baseDn: "cn=Subschema", filter: "(objectClass=subschema)"},
+			attrs: []string{"objectClass", "cn"},
+		},
+		{
+			req:   TestSearchRequest{baseDn: "CN=subschema", filter: "(objectClass=*)", attrs: []string{"+"}},
+			attrs: []string{"attributeTypes", "objectClasses", "ldapSyntaxes", "matchingRules", "matchingRuleUse", "entryDN"},
+		},
+		{
+			req:   TestSearchRequest{baseDn: "cn=Subschema", scope: d.WholeSubtree, filter: "(objectClass=*)", attrs: []string{"objectClasses"}},
+			attrs: []string{"objectClasses"},
+		},
+		{
+			req: TestSearchRequest{baseDn: "cn=Subschema", scope: d.SingleLevel, filter: "(objectClass=*)"},
+		},
+		{
+			req: TestSearchRequest{baseDn: "cn=Subschema", filter: "(objectClass=person)"},
+		},
+	}
+
+	for i, test := range tests {
+		res, err := ss.Search(context.Background(), test.req)
+		if err != nil {
+			t.Fatalf("test %d returned unexpected error: %s", i, err)
+		}
+
+		if test.attrs == nil {
+			if len(res) != 0 {
+				t.Errorf("test %d expected no entries, got %v", i, res)
+			}
+			continue
+		}
+
+		if len(res) != 1 || res[0].Dn != "cn=Subschema" {
+			t.Fatalf("test %d expected only the subschema subentry, got %v", i, res)
+		}
+
+		names := slices.Sorted(maps.Keys(res[0].Attrs))
+		if !slices.Equal(names, slices.Sorted(slices.Values(test.attrs))) {
+			t.Errorf("test %d returned attrs %v but expected %v", i, names, test.attrs)
+		}
+	}
+
+	res, err := ss.Search(context.Background(), TestSearchRequest{baseDn: "cn=Subschema", filter: "(objectClass=*)", attrs: []string{"objectClasses"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	person := "( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( cn $ sn ) MAY ( description $ seeAlso $ telephoneNumber $ userPassword ) )"
+	if !slices.Contains(res[0].Attrs["objectClasses"], person) {
+		t.Errorf("expected objectClasses to contain %s", person)
+	}
+}
+
+type TestModification struct {
+	op   ModifyOperation
+	attr string
+	vals []string
+}
+
+func (m TestModification) ModOp() ModifyOperation {
+	return m.op
+}
+
+func (m TestModification) Attribute() string {
+	return m.attr
+}
+
+func (m TestModification) Vals() []string {
+	return m.vals
+}
+
+type TestModifyRequest struct {
+	dn   string
+	mods []TestModification
+}
+
+func (r TestModifyRequest) Dn() string {
+	return r.dn
+}
+
+func (r TestModifyRequest) Modifications() []Modification {
+	mods := []Modification{}
+	for _, m := range r.mods {
+		mods = append(mods, m)
+	}
+	return mods
+}
+
+func TestModifySubschema(t *testing.T) {
+	// the schema is changed so it is not shared with the other tests
+	schema := util.Unwrap(d.LoadSchemaFromReaders(attrLdifFile(), ocsLdifFile()))
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	var persisted []string
+	var persistErr error
+	ms := NewModifyService(schema, scheduler, WithSchemaPersistence(func(attrs, ocs []byte) error {
+		if persistErr != nil {
+			return persistErr
+		}
+		if _, ok := schema.FindAttribute("appId"); persisted == nil && ok {
+			t.Errorf("expected the change to be persisted before it was applied")
+		}
+		persisted = []string{string(attrs), string(ocs)}
+		return nil
+	}))
+	as := NewAddService(schema, scheduler)
+	ss := NewSearchService(schema, scheduler)
+
+	bound, err := ScheduleAwait(scheduler, func(dit d.DIT) (*d.Entry, error) {
+		return dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, "cn=Test1,dc=georgiboy,dc=dev")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appId := "( 1.3.6.1.4.1.99999.1.1 NAME 'appId' EQUALITY caseIgnoreMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )"
+	appThing := "( 1.3.6.1.4.1.99999.2.1 NAME 'appThing' SUP top AUXILIARY MUST appId )"
+
+	addAppThing := TestModifyRequest{"cn=Subschema", []TestModification{
+		{ModifyAdd, "attributeTypes", []string{appId}},
+		{ModifyAdd, "objectClasses", []string{appThing}},
+	}}
+
+	err = ms.ModifyEntry(nil, addAppThing)
+	if !errors.Is(err, d.NewLdapError(d.InsufficientAccessRights, nil, "")) {
+		t.Fatalf("expected an anonymous client not to be able to modify the schema, got %v", err)
+	}
+	if persisted != nil {
+		t.Fatalf("expected nothing to be persisted for an anonymous client")
+	}
+
+	err = ms.ModifyEntry(bound, addAppThing)
+	if err != nil {
+		t.Fatalf("could not add to the schema: %s", err)
+	}
+
+	if !strings.Contains(persisted[0], appId) || !strings.Contains(persisted[1], appThing) {
+		t.Errorf("expected the new definitions to be persisted")
+	}
+
+	_, err = as.AddEntry(nil, TestAddRequest{"cn=App,dc=georgiboy,dc=dev", map[string][]string{
+		"objectClass": {"person", "appThing"},
+		"cn":          {"App"},
+		"sn":          {"App"},
+		"appId":       {"app-1"},
+	}})
+	if err != nil {
+		t.Errorf("could not add an entry with the new object class: %s", err)
+	}
+
+	res, err := ss.Search(context.Background(), TestSearchRequest{baseDn: "cn=Subschema", filter: "(attributeTypes=1.3.6.1.4.1.99999.1.1)", attrs: []string{"attributeTypes"}})
+	if err != nil || len(res) != 1 || !slices.Contains(res[0].Attrs["attributeTypes"], appId) {
+		t.Errorf("expected the subschema to publish the new attribute type, got %v: %v", res, err)
+	}
+
+	obsolete := "( 1.3.6.1.4.1.99999.2.1 NAME 'appThing' OBSOLETE SUP top AUXILIARY MUST appId )"
+	tests := []struct {
+		mods       []TestModification
+		persistErr error
+		err        error
+	}{
+		{
+			mods: []TestModification{
+				{ModifyDelete, "objectClasses", []string{appThing}},
+				{ModifyAdd, "objectClasses", []string{obsolete}},
+			},
+		},
+		{
+			mods: []TestModification{{ModifyAdd, "objectClasses", []string{appThing}}},
+			err:  d.NewLdapError(d.AttributeOrValueExists, nil, ""),
+		},
+		{
+			mods: []TestModification{{ModifyReplace, "attributeTypes", []string{appId}}},
+			err:  d.NewLdapError(d.UnwillingToPerform, nil, ""),
+		},
+		{
+			mods: []TestModification{{ModifyDelete, "attributeTypes", nil}},
+			err:  d.NewLdapError(d.UnwillingToPerform, nil, ""),
+		},
+		{
+			mods: []TestModification{{ModifyAdd, "ldapSyntaxes", []string{"( 1.2.3.4 DESC 'New' )"}}},
+			err:  d.NewLdapError(d.UnwillingToPerform, nil, ""),
+		},
+		{
+			mods:       []TestModification{{ModifyAdd, "attributeTypes", []string{"( 1.3.6.1.4.1.99999.1.2 NAME 'appName' SUP name )"}}},
+			persistErr: errors.New("disk full"),
+			err:        d.NewLdapError(d.OperationsError, nil, ""),
+		},
+	}
+
+	for i, test := range tests {
+		persistErr = test.persistErr
+		err := ms.ModifyEntry(bound, TestModifyRequest{"cn=Subschema", test.mods})
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d expected error %v, got %v", i, test.err, err)
+		}
+	}
+
+	if o, ok := schema.FindObjectClass("appThing"); !ok || o.Description() != obsolete {
+		t.Errorf("expected appThing to be obsolete")
+	}
+	if _, ok := schema.FindAttribute("appName"); ok {
+		t.Errorf("expected a change that could not be persisted to be undone")
+	}
+}
+
+func TestOperationalAttributes(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	as := NewAddService(schema, scheduler)
+	ms := NewModifyService(schema, scheduler)
+	ss := NewSearchService(schema, scheduler)
+
+	bound, err := ScheduleAwait(scheduler, func(dit d.DIT) (*d.Entry, error) {
+		return dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, "cn=Test1,dc=georgiboy,dc=dev")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dn := "cn=Stamped,dc=georgiboy,dc=dev"
+	_, err = as.AddEntry(bound, TestAddRequest{dn, map[string][]string{
+		"objectClass": {"person"},
+		"cn":          {"Stamped"},
+		"sn":          {"Stamped"},
+	}})
+	if err != nil {
+		t.Fatalf("could not add entry: %s", err)
+	}
+
+	res := util.Unwrap(ss.Search(context.Background(), TestSearchRequest{baseDn: dn, filter: "(objectClass=*)"}))
+	if attrs := slices.Sorted(maps.Keys(res[0].Attrs)); !slices.Equal(attrs, []string{"cn", "objectClass", "sn"}) {
+		t.Errorf("expected only user attributes without +, got %v", attrs)
+	}
+
+	res = util.Unwrap(ss.Search(context.Background(), TestSearchRequest{baseDn: dn, filter: "(objectClass=*)", attrs: []string{"+"}}))
+	created := res[0].Attrs
+	for name, exp := range map[string]string{
+		"creatorsName":  "cn=Test1,dc=georgiboy,dc=dev",
+		"modifiersName": "cn=Test1,dc=georgiboy,dc=dev",
+		"entryDN":       dn,
+	} {
+		if !slices.Equal(created[name], []string{exp}) {
+			t.Errorf("expected %s to be %s, got %v", name, exp, created[name])
+		}
+	}
+	if len(created["entryUUID"]) != 1 || len(created["createTimestamp"]) != 1 ||
+		!slices.Equal(created["createTimestamp"], created["modifyTimestamp"]) {
+		t.Errorf("expected a uuid and matching timestamps, got %v", created)
+	}
+
+	err = ms.ModifyEntry(nil, TestModifyRequest{dn, []TestModification{{ModifyAdd, "sn", []string{"Again"}}}})
+	if err != nil {
+		t.Fatalf("could not modify entry: %s", err)
+	}
+
+	filter := "(modifyTimestamp>=" + created["createTimestamp"][0] + ")"
+	res = util.Unwrap(ss.Search(context.Background(), TestSearchRequest{baseDn: dn, filter: filter, attrs: []string{"modifiersName", "entryUUID", "createTimestamp"}}))
+	if len(res) != 1 {
+		t.Fatalf("expected the modified entry to match %s", filter)
+	}
+	if !slices.Equal(res[0].Attrs["modifiersName"], []string{""}) {
+		t.Errorf("expected an anonymous modifier, got %v", res[0].Attrs["modifiersName"])
+	}
+	if !slices.Equal(res[0].Attrs["entryUUID"], created["entryUUID"]) || !slices.Equal(res[0].Attrs["createTimestamp"], created["createTimestamp"]) {
+		t.Errorf("expected the entryUUID and createTimestamp not to change")
+	}
+
+	_, err = as.AddEntry(nil, TestAddRequest{"cn=Forged,dc=georgiboy,dc=dev", map[string][]string{
+		"objectClass":     {"person"},
+		"cn":              {"Forged"},
+		"sn":              {"Forged"},
+		"createTimestamp": {"20000101000000Z"},
+	}})
+	if !errors.Is(err, d.NewLdapError(d.ConstraintViolation, nil, "")) {
+		t.Errorf("expected adding createTimestamp to be a constraint violation, got %v", err)
+	}
+
+	err = ms.ModifyEntry(nil, TestModifyRequest{dn, []TestModification{{ModifyReplace, "entryUUID", []string{"597ae2f6-16a6-1027-98f4-d28b5365dc14"}}}})
+	if !errors.Is(err, d.NewLdapError(d.ConstraintViolation, nil, "")) {
+		t.Errorf("expected replacing entryUUID to be a constraint violation, got %v", err)
+	}
+}
+
+type TestModifyDnRequest struct {
+	dn, newRdn  string
+	deleteOld   bool
+	newSuperior *string
+}
+
+func (r TestModifyDnRequest) Dn() string {
+	return r.dn
+}
+
+func (r TestModifyDnRequest) UpdatedRdn() string {
+	return r.newRdn
+}
+
+func (r TestModifyDnRequest) RemoveExistingRdn() bool {
+	return r.deleteOld
+}
+
+func (r TestModifyDnRequest) NewParentDn() (string, bool) {
+	return optionalString(r.newSuperior)
+}
+
+func TestModifyEntryDn(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	ms := NewModifyService(schema, scheduler)
+	ss := NewSearchService(schema, scheduler)
+
+	bound, err := ScheduleAwait(scheduler, func(dit d.DIT) (*d.Entry, error) {
+		return dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, "cn=Test1,dc=georgiboy,dc=dev")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSuperior, missing := "dc=dev", "ou=Missing,dc=dev"
+	tests := []struct {
+		req   TestModifyDnRequest
+		moved []string
+		err   error
+	}{
+		{
+			req:   TestModifyDnRequest{dn: "cn=Test3,ou=TestOu,dc=georgiboy,dc=dev", newRdn: "cn=Renamed", deleteOld: true},
+			moved: []string{"cn=Renamed,ou=TestOu,dc=georgiboy,dc=dev"},
+		},
+		{
+			req:   TestModifyDnRequest{dn: "ou=TestOu,dc=georgiboy,dc=dev", newRdn: "ou=Moved", deleteOld: true, newSuperior: &newSuperior},
+			moved: []string{"ou=Moved,dc=dev", "cn=Test2,ou=Moved,dc=dev", "cn=Renamed,ou=Moved,dc=dev"},
+		},
+		{
+			req: TestModifyDnRequest{dn: "cn=Test1,dc=georgiboy,dc=dev", newRdn: "cn=Test1", newSuperior: &missing},
+			err: d.NewLdapError(d.NoSuchObject, nil, ""),
+		},
+	}
+
+	for i, test := range tests {
+		err := ms.ModifyEntryDn(bound, test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d returned error %v, expected %v", i, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d returned unexpected error: %s", i, err)
+			continue
+		}
+
+		for _, dn := range test.moved {
+			res, err := ss.Search(context.Background(), TestSearchRequest{baseDn: dn, filter: "(objectClass=*)", attrs: []string{"entryDN"}})
+			if err != nil || len(res) != 1 || !slices.Equal(res[0].Attrs["entryDN"], []string{dn}) {
+				t.Errorf("test %d expected an entry at %s, got %v: %v", i, dn, res, err)
+			}
+		}
+
+		res := util.Unwrap(ss.Search(context.Background(), TestSearchRequest{baseDn: test.moved[0], filter: "(objectClass=*)", attrs: []string{"modifiersName"}}))
+		if !slices.Equal(res[0].Attrs["modifiersName"], []string{"cn=Test1,dc=georgiboy,dc=dev"}) {
+			t.Errorf("test %d expected the bound entry as the modifier, got %v", i, res[0].Attrs["modifiersName"])
+		}
+	}
+}
+
+type TestDeleteRequest struct {
+	dn         string
+	treeDelete bool
+}
+
+func (r TestDeleteRequest) Dn() string {
+	return r.dn
+}
+
+func (r TestDeleteRequest) TreeDelete() bool {
+	return r.treeDelete
+}
+
+func TestDeleteService(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	ds := NewDeleteService(schema, scheduler)
+
+	tests := []struct {
+		req     TestDeleteRequest
+		err     error
+		removed []string
+	}{
+		{
+			req: TestDeleteRequest{dn: "ou=TestOu,dc=georgiboy,dc=dev"},
+			err: d.NewLdapError(d.NotAllowedOnNonLeaf, nil, ""),
+		},
+		{
+			req: TestDeleteRequest{dn: "ou=Missing,dc=georgiboy,dc=dev"},
+			err: d.NewLdapError(d.NoSuchObject, nil, ""),
+		},
+		{
+			req:     TestDeleteRequest{dn: "cn=Test1,dc=georgiboy,dc=dev"},
+			removed: []string{"cn=Test1,dc=georgiboy,dc=dev"},
+		},
+		{
+			req:     TestDeleteRequest{dn: "ou=TestOu,dc=georgiboy,dc=dev", treeDelete: true},
+			removed: []string{"ou=TestOu,dc=georgiboy,dc=dev", "cn=Test2,ou=TestOu,dc=georgiboy,dc=dev"},
+		},
+	}
+
+	for _, test := range tests {
+		err := ds.DeleteEntry(test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Fatalf("deleting %q returned error %v, expected %v", test.req.dn, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("deleting %q returned unexpected error: %s", test.req.dn, err)
+		}
+
+		for _, removed := range test.removed {
+			dn, err := d.NormaliseDN(schema, removed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := dit.GetEntry(dn); !errors.Is(err, d.NewLdapError(d.NoSuchObject, nil, "")) {
+				t.Fatalf("expected %q to be removed, got: %v", removed, err)
+			}
+		}
+	}
+}
+
+type TestCompareRequest struct {
+	dn, attr, val string
+}
+
+func (r TestCompareRequest) Dn() string {
+	return r.dn
+}
+
+func (r TestCompareRequest) Attribute() string {
+	return r.attr
+}
+
+func (r TestCompareRequest) Value() string {
+	return r.val
+}
+
+func TestCompareService(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	cs := NewCompareService(schema, scheduler)
+
+	dn := "cn=Test1,dc=georgiboy,dc=dev"
+	bound, err := ScheduleAwait(scheduler, func(dit d.DIT) (*d.Entry, error) {
+		return dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, dn)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		req  TestCompareRequest
+		anon bool
+		exp  bool
+		err  error
+	}{
+		{req: TestCompareRequest{dn, "sn", "one"}, exp: true},
+		{req: TestCompareRequest{dn, "name", "test1"}, exp: true},
+		{req: TestCompareRequest{dn, "name", "one"}, exp: true},
+		{req: TestCompareRequest{dn, "name", "Nobody"}, exp: false},
+		{req: TestCompareRequest{dn, "sn", "one"}, anon: true, exp: true},
+		{req: TestCompareRequest{dn, "userPassword", "password123"}, anon: true, err: d.NewLdapError(d.InsufficientAccessRights, nil, "")},
+		{req: TestCompareRequest{dn, "SN", "  TESTER "}, exp: true},
+		{req: TestCompareRequest{dn, "sn", "Nobody"}, exp: false},
+		{req: TestCompareRequest{dn, "userPassword", "password123"}, exp: true},
+		{req: TestCompareRequest{dn, "userPassword", "PASSWORD123"}, exp: false},
+		{req: TestCompareRequest{dn, "givenName", "Test"}, exp: false},
+		{req: TestCompareRequest{dn, "unknownAttr", "x"}, err: d.NewLdapError(d.UndefinedAttributeType, nil, "")},
+		{req: TestCompareRequest{dn, "facsimileTelephoneNumber", "12345"}, err: d.NewLdapError(d.InappropriateMatching, nil, "")},
+		{req: TestCompareRequest{"cn=Missing,dc=georgiboy,dc=dev", "sn", "One"}, err: d.NewLdapError(d.NoSuchObject, nil, "")},
+	}
+
+	for _, test := range tests {
+		b := bound
+		if test.anon {
+			b = nil
+		}
+
+		res, err := cs.Compare(b, test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("comparing %v returned error %v, expected %v", test.req, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("comparing %v returned unexpected error: %s", test.req, err)
+			continue
+		}
+
+		if res != test.exp {
+			t.Errorf("comparing %v returned %t, expected %t", test.req, res, test.exp)
+		}
+	}
+}
+
+type TestPasswordModifyRequest struct {
+	id, old, new *string
+}
+
+func optionalString(s *string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	return *s, true
+}
+
+func (r TestPasswordModifyRequest) UserIdentity() (string, bool) {
+	return optionalString(r.id)
+}
+
+func (r TestPasswordModifyRequest) OldPassword() (string, bool) {
+	return optionalString(r.old)
+}
+
+func (r TestPasswordModifyRequest) NewPassword() (string, bool) {
+	return optionalString(r.new)
+}
+
+func TestPasswordService(t *testing.T) {
+	str := func(s string) *string { return &s }
+	test1 := "cn=Test1,dc=georgiboy,dc=dev"
+	test2 := "cn=Test2,ou=TestOu,dc=georgiboy,dc=dev"
+
+	tests := []struct {
+		bound    string
+		req      TestPasswordModifyRequest
+		password string
+		err      error
+	}{
+		{bound: test1, req: TestPasswordModifyRequest{new: str("newpass")}, password: "newpass"},
+		{bound: test1, req: TestPasswordModifyRequest{old: str("password123"), new: str("newpass")}, password: "newpass"},
+		{bound: test1, req: TestPasswordModifyRequest{id: str(test1), new: str("newpass")}, password: "newpass"},
+		{bound: test1, req: TestPasswordModifyRequest{}},
+		{req: TestPasswordModifyRequest{id: str(test1), old: str("password123"), new: str("newpass")}, password: "newpass"},
+		{req: TestPasswordModifyRequest{id: str(test1), old: str("password123")}},
+		{bound: test2, req: TestPasswordModifyRequest{new: str("newpass")}, password: "newpass"},
+		{bound: test1, req: TestPasswordModifyRequest{old: str("wrong"), new: str("newpass")}, err: d.NewLdapError(d.InvalidCredentials, nil, "")},
+		{bound: test1, req: TestPasswordModifyRequest{id: str(test2), new: str("newpass")}, err: d.NewLdapError(d.InsufficientAccessRights, nil, "")},
+		{req: TestPasswordModifyRequest{new: str("newpass")}, err: d.NewLdapError(d.UnwillingToPerform, nil, "")},
+		{bound: test1, req: TestPasswordModifyRequest{new: str("")}, err: d.NewLdapError(d.UnwillingToPerform, nil, "")},
+		{req: TestPasswordModifyRequest{id: str("cn=Missing,dc=georgiboy,dc=dev"), old: str("password123")}, err: d.NewLdapError(d.NoSuchObject, nil, "")},
+	}
+
+	for i, test := range tests {
+		dit := d.GenerateTestDIT(schema)
+
+		var bound *d.Entry
+		if test.bound != "" {
+			bound = util.Unwrap(dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, test.bound))))
+		}
+
+		scheduler := NewScheduler(dit, schema)
+		ps := NewPasswordService(schema, scheduler)
+		bs := NewBindService(schema, scheduler)
+
+		generated, err := ps.ModifyPassword(bound, test.req)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("test %d returned error %v, expected %v", i, err, test.err)
+			}
+			scheduler.Close()
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d returned unexpected error: %s", i, err)
+			scheduler.Close()
+			continue
+		}
+
+		password := test.password
+		if test.req.new == nil {
+			if generated == "" {
+				t.Errorf("test %d did not generate a password", i)
+			}
+			password = generated
+		} else if generated != "" {
+			t.Errorf("test %d generated a password when one was given", i)
+		}
+
+		dn := test.bound
+		if test.req.id != nil {
+			dn = *test.req.id
+		}
+
+		if _, err := bs.Bind(TestSimpleBindRequest{dn, password}); err != nil {
+			t.Errorf("test %d could not bind with the new password: %s", i, err)
+		}
+		if _, err := bs.Bind(TestSimpleBindRequest{dn, "password123"}); err == nil {
+			t.Errorf("test %d could still bind with the old password", i)
+		}
+
+		scheduler.Close()
+	}
+}
+
+func TestPasswordSchemes(t *testing.T) {
+	// values hashed by other implementations
+	stored := []string{
+		"secret",
+		"{SSHA}1G904nLkTkGWjKNnQuB/hpWXC/hzYWx0c2FsdA==",
+		"{ssha512}aCu7JRc+kLsuEmFs1zTY+AiP7DSGnjjG+dH28Dp+E5usqoAixeTPihKqZmkWal4mUfp63tqvCAkFV1LKTDFH6XNhbHRzYWx0",
+		"{PBKDF2}1000$MDEyMzQ1Njc4OWFiY2RlZg$21EupWTmSOvnK3Sp99FL7THUyuQ",
+		"{PBKDF2-SHA256}1000$MDEyMzQ1Njc4OWFiY2RlZg$tiKWHy4FAGCWE8gn6GtKhaxD2OeeAUUWXFT/p1aaNl8",
+		"{PBKDF2-SHA512}1000$MDEyMzQ1Njc4OWFiY2RlZg$vgFvU7zWIDgDAUi7d8ayt.cfRiPWVVWfv8iQRsGZaZviWzsSNgWYXEE5PmvI/VELMsOmEbqLz0PKuePNjOk41A",
+		"{BCRYPT}$2b$04$abcdefghijklmnopqrstuu2r9OfJnfCsdneAXAGHnS4UpFFP8WIrW",
+		"{SCRAM-SHA-256}4096:MDEyMzQ1Njc4OWFiY2RlZg==$bpSY5Ze9NUH+I35LC3gVq+DpBfK46iXBxvhAKqVu9pE=:VpYlBuxyzeCI1KnctrefdljpB1mk3Gp7sBI/t11+NkQ=",
+	}
+
+	for _, name := range PasswordSchemes() {
+		hashed, err := passwordSchemes[name].Hash("secret")
+		if err != nil {
+			t.Fatalf("could not hash with %s: %s", name, err)
+		}
+		stored = append(stored, "{"+name+"}"+hashed)
+	}
+
+	for _, s := range stored {
+		if ok, err := verifyPassword(s, "secret"); !ok || err != nil {
+			t.Errorf("expected %q to verify, got %t: %v", s, ok, err)
+		}
+		if ok, err := verifyPassword(s, "Secret"); ok || err != nil {
+			t.Errorf("expected %q not to verify a wrong password, got %t: %v", s, ok, err)
+		}
+	}
+
+	if ok, _ := verifyPassword("{UNKNOWN}secret", "{UNKNOWN}secret"); ok {
+		t.Errorf("expected a value with an unknown scheme not to verify")
+	}
+	if ok, err := verifyPassword("{SSHA}not base64", "secret"); ok || err == nil {
+		t.Errorf("expected a malformed value to return an error")
+	}
+
+	// verifying these would exhaust the server, so they fail without hashing
+	for _, s := range overLimitPasswords {
+		if ok, err := verifyPassword(s, "secret"); ok || err == nil {
+			t.Errorf("expected %q to be over the cost limits", s)
+		}
+	}
+}
+
+var overLimitPasswords = []string{
+	"{ARGON2}$argon2id$v=19$m=4294967295,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY",
+	"{ARGON2}$argon2id$v=19$m=19456,t=4294967295,p=1$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY",
+	"{PBKDF2}2147483647$MDEyMzQ1Njc4OWFiY2RlZg$21EupWTmSOvnK3Sp99FL7THUyuQ",
+	"{PBKDF2-SHA256}1000$MDEyMzQ1Njc4OWFiY2RlZg$" + strings.Repeat("A", 4096),
+	"{BCRYPT}$2b$31$abcdefghijklmnopqrstuu2r9OfJnfCsdneAXAGHnS4UpFFP8WIrW",
+	"{SCRAM-SHA-256}2147483647:MDEyMzQ1Njc4OWFiY2RlZg==$bpSY5Ze9NUH+I35LC3gVq+DpBfK46iXBxvhAKqVu9pE=:VpYlBuxyzeCI1KnctrefdljpB1mk3Gp7sBI/t11+NkQ=",
+}
+
+func TestPasswordsAreHashed(t *testing.T) {
+	dit := d.GenerateTestDIT(schema)
+	scheduler := NewScheduler(dit, schema)
+	defer scheduler.Close()
+
+	bs := NewBindService(schema, scheduler)
+	as := NewAddService(schema, scheduler)
+	cs := NewCompareService(schema, scheduler)
+	userPassword, _ := schema.FindAttribute("userPassword")
+
+	storedPasswords := func(dn string) []string {
+		entry, err := ScheduleAwait(scheduler, func(dit d.DIT) (*d.Entry, error) {
+			return dit.GetEntry(util.Unwrap(d.NormaliseDN(schema, dn)))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals, _ := entry.AttrVals(userPassword)
+		return vals
+	}
+
+	// the test dit stores a plaintext password which is rehashed on bind
+	test1 := "cn=Test1,dc=georgiboy,dc=dev"
+	if _, err := bs.Bind(TestSimpleBindRequest{test1, "password123"}); err != nil {
+		t.Fatal(err)
+	}
+
+	hasScheme := func(vals []string, name string) bool {
+		return slices.ContainsFunc(vals, func(v string) bool {
+			return strings.HasPrefix(v, "{"+name+"}")
+		})
+	}
+
+	// only the default scheme is stored unless scram verifiers are enabled
+	vals := storedPasswords(test1)
+	if len(vals) != 1 || !hasScheme(vals, defaultPasswordScheme) {
+		t.Fatalf("expected the plaintext password to be rehashed with only the default scheme, got %v", vals)
+	}
+
+	bound, err := bs.Bind(TestSimpleBindRequest{test1, "password123"})
+	if err != nil {
+		t.Fatalf("could not bind with a rehashed password: %s", err)
+	}
+	if ok, err := cs.Compare(bound, TestCompareRequest{test1, "userPassword", "password123"}); !ok || err != nil {
+		t.Errorf("expected compare to verify a rehashed password, got %t: %v", ok, err)
+	}
+
+	added := "cn=Hashed,dc=georgiboy,dc=dev"
+	prehashed := "{SSHA}1G904nLkTkGWjKNnQuB/hpWXC/hzYWx0c2FsdA=="
+	_, err = as.AddEntry(nil, TestAddRequest{added, map[string][]string{
+		"objectClass":  {"person"},
+		"cn":           {"Hashed"},
+		"sn":           {"Hashed"},
+		"userPassword": {"plaintext", prehashed},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals = storedPasswords(added)
+	if !slices.Contains(vals, prehashed) || slices.Contains(vals, "plaintext") || len(vals) != 2 {
+		t.Fatalf("expected the plaintext password to be hashed and the hashed one kept, got %v", vals)
+	}
+
+	for _, password := range []string{"plaintext", "secret"} {
+		if _, err := bs.Bind(TestSimpleBindRequest{added, password}); err != nil {
+			t.Errorf("could not bind with %q: %s", password, err)
+		}
+	}
+
+	ms := NewModifyService(schema, scheduler)
+	replace := func(password string) []string {
+		err := ms.ModifyEntry(nil, TestModifyRequest{added, []TestModification{{ModifyReplace, "userPassword", []string{password}}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return storedPasswords(added)
+	}
+
+	if vals := replace("replaced"); len(vals) != 1 || !hasScheme(vals, defaultPasswordScheme) {
+		t.Fatalf("expected a written password to be stored with only the default scheme, got %v", vals)
+	}
+
+	SetStoreScramVerifiers(true)
+	vals = replace("replaced")
+	SetStoreScramVerifiers(false)
+	if len(vals) != 2 || !hasScheme(vals, defaultPasswordScheme) || !hasScheme(vals, "SCRAM-SHA-256") {
+		t.Fatalf("expected a written password to be stored with a scram verifier once enabled, got %v", vals)
+	}
+
+	// a deleted password is verified against the stored values like a bind
+	deletePassword := func(password string) []string {
+		err := ms.ModifyEntry(nil, TestModifyRequest{added, []TestModification{{ModifyDelete, "userPassword", []string{password}}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return storedPasswords(added)
+	}
+
+	if vals := deletePassword("wrong"); len(vals) != 2 {
+		t.Fatalf("expected a password that matches nothing to delete nothing, got %v", vals)
+	}
+	if vals := deletePassword("replaced"); len(vals) != 0 {
+		t.Fatalf("expected the hashed password and its scram verifier to be deleted, got %v", vals)
+	}
+	if _, err := bs.Bind(TestSimpleBindRequest{added, "replaced"}); err == nil {
+		t.Errorf("expected a deleted password not to bind")
+	}
+
+	for _, v := range append(slices.Clone(overLimitPasswords), "{SSHA}not base64", "{ARGON2}$argon2id$v=19$m=19456") {
+		err := ms.ModifyEntry(nil, TestModifyRequest{added, []TestModification{{ModifyAdd, "userPassword", []string{v}}}})
+		if !errors.Is(err, d.NewLdapError(d.ConstraintViolation, nil, "")) {
+			t.Errorf("expected adding %q to be a constraint violation, got %v", v, err)
+		}
+	}
+
+	_, err = as.AddEntry(nil, TestAddRequest{"cn=Costly,dc=georgiboy,dc=dev", map[string][]string{
+		"objectClass":  {"person"},
+		"cn":           {"Costly"},
+		"sn":           {"Costly"},
+		"userPassword": {overLimitPasswords[0]},
+	}})
+	if !errors.Is(err, d.NewLdapError(d.ConstraintViolation, nil, "")) {
+		t.Errorf("expected adding an entry with an over limit password to be a constraint violation, got %v", err)
+	}
+}
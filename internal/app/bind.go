@@ -3,6 +3,7 @@ package app
 import (
 	"log"
 	"os"
+	"slices"
 
 	d "github.com/georgib0y/relientldap/internal/domain"
 )
@@ -19,19 +20,68 @@ type BindRequest interface {
 
 type BindService interface {
 	Bind(BindRequest) (*d.Entry, error)
+	// For the supportedSASLMechanisms attribute of the root DSE
+	SupportedSaslMechanisms() []string
 }
 
 type bindService struct {
 	schema    *d.Schema
 	scheduler *Scheduler
+	// allow binds with an empty name and password
+	anonymous bool
+	// allow binds with a name and an empty password, which are treated as anonymous
+	unauthenticated bool
+	// where SASL usernames are looked up, an empty base is the whole DIT
+	identityBase string
+	identityAttr string
 }
 
-func NewBindService(schema *d.Schema, scheduler *Scheduler) BindService {
+type BindOption func(*bindService)
+
+// Anonymous binds are allowed by default
+func WithAnonymousBind(allow bool) BindOption {
+	return func(b *bindService) {
+		b.anonymous = allow
+	}
+}
+
+// Unauthenticated binds are rejected by default (RFC 4513 5.1.2)
+func WithUnauthenticatedBind(allow bool) BindOption {
+	return func(b *bindService) {
+		b.unauthenticated = allow
+	}
+}
+
+// SASL usernames are mapped to the entry under base with the username as the
+// value of attr, by default uid anywhere in the DIT
+func WithSaslIdentityMapping(base, attr string) BindOption {
+	return func(b *bindService) {
+		b.identityBase = base
+		b.identityAttr = attr
+	}
+}
+
+func NewBindService(schema *d.Schema, scheduler *Scheduler, options ...BindOption) BindService {
 	bindLogger.Print("creating new bind service")
-	return &bindService{schema, scheduler}
+	b := &bindService{schema: schema, scheduler: scheduler, anonymous: true, identityAttr: "uid"}
+	for _, opt := range options {
+		opt(b)
+	}
+	return b
 }
 
 func (b *bindService) Bind(br BindRequest) (*d.Entry, error) {
+	session := &SaslSession{}
+	if sbr, ok := br.(SessionBindRequest); ok {
+		session = sbr.SaslSession()
+	}
+
+	mechanism, isSasl := br.SaslMechanism()
+	if !isSasl {
+		// any other bind aborts a SASL exchange in progress
+		session.reset()
+	}
+
 	if br.Version() != 3 {
 		return nil, d.NewLdapError(
 			d.ProtocolError,
@@ -41,10 +91,47 @@ func (b *bindService) Bind(br BindRequest) (*d.Entry, error) {
 	}
 
 	if simple, ok := br.Simple(); ok {
+		switch {
+		case br.Dn() == "" && simple == "":
+			return b.authenticateAnonymous()
+		case simple == "":
+			return b.authenticateUnauthenticated(br.Dn())
+		case br.Dn() == "":
+			return nil, d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
+		}
 		return b.authenticateSimple(br.Dn(), simple)
 	}
 
-	return nil, d.NewLdapError(d.AuthMethodNotSupported, nil, "sasl or unknown method not supported")
+	if isSasl {
+		return b.authenticateSasl(br, mechanism, session)
+	}
+
+	return nil, d.NewLdapError(d.AuthMethodNotSupported, nil, "unknown authentication method")
+}
+
+// Returns a nil entry as the connection is anonymous (RFC 4513 5.1.1)
+func (b *bindService) authenticateAnonymous() (*d.Entry, error) {
+	if !b.anonymous {
+		return nil, d.NewLdapError(d.InappropriateAuthentication, nil, "anonymous binds are not allowed")
+	}
+	return nil, nil
+}
+
+// A name without a password does not authenticate the connection, so if
+// allowed it is anonymous (RFC 4513 5.1.2)
+func (b *bindService) authenticateUnauthenticated(entryDn string) (*d.Entry, error) {
+	if !b.unauthenticated {
+		return nil, d.NewLdapError(d.UnwillingToPerform, nil, "unauthenticated binds are not allowed")
+	}
+
+	if _, err := d.NormaliseDN(b.schema, entryDn); err != nil {
+		return nil, err
+	}
+
+	if !b.anonymous {
+		return nil, d.NewLdapError(d.InappropriateAuthentication, nil, "anonymous binds are not allowed")
+	}
+	return nil, nil
 }
 
 func (b *bindService) authenticateSimple(entryDn string, simple string) (*d.Entry, error) {
@@ -56,13 +143,6 @@ func (b *bindService) authenticateSimple(entryDn string, simple string) (*d.Entr
 		return nil, err
 	}
 
-	bindLogger.Printf("silly me logging your password in plain text: %s", simple)
-
-	userPassword, ok := b.schema.FindAttribute("userPassword")
-	if !ok {
-		return nil, d.NewLdapError(d.UndefinedAttributeType, nil, "userPassword is not defined in schema")
-	}
-
 	entry, err := ScheduleAwait(b.scheduler, func(dit d.DIT) (*d.Entry, error) {
 		return dit.GetEntry(dn)
 	})
@@ -71,14 +151,62 @@ func (b *bindService) authenticateSimple(entryDn string, simple string) (*d.Entr
 		return nil, err
 	}
 
-	ok, err = entry.ContainsAttrVal(userPassword, simple)
-	if err != nil {
-		return nil, err
+	return b.checkEntryPassword(entry, simple)
+}
+
+// Returns the entry if password matches one of its userPassword values,
+// rehashing the value if it was stored in plaintext
+func (b *bindService) checkEntryPassword(entry *d.Entry, password string) (*d.Entry, error) {
+	userPassword, ok := b.schema.FindAttribute("userPassword")
+	if !ok {
+		return nil, d.NewLdapError(d.UndefinedAttributeType, nil, "userPassword is not defined in schema")
 	}
 
+	stored, ok := checkPassword(entry, userPassword, password)
 	if !ok {
 		return nil, d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
 	}
 
+	if _, _, hashed := splitPasswordScheme(stored); !hashed {
+		if rehashed := b.rehashPassword(entry.Dn(), userPassword, stored); rehashed != nil {
+			entry = rehashed
+		}
+	}
+
 	return entry, nil
 }
+
+// Replaces a plaintext userPassword value with values hashed by the default
+// scheme and returns the updated entry. The bind has already succeeded so
+// failures are only logged and nil is returned.
+func (b *bindService) rehashPassword(dn d.DN, userPassword *d.Attribute, plaintext string) *d.Entry {
+	encoded, err := encodePassword(plaintext)
+	if err != nil {
+		bindLogger.Printf("could not hash plaintext password of %s: %s", dn.String(), err)
+		return nil
+	}
+
+	entry, err := ScheduleAwait(b.scheduler, func(dit d.DIT) (*d.Entry, error) {
+		entry, err := dit.GetEntry(dn)
+		if err != nil {
+			return nil, err
+		}
+
+		// the password may have changed since it was checked
+		if vals, _ := entry.AttrVals(userPassword); !slices.Contains(vals, plaintext) {
+			return entry, nil
+		}
+
+		// the entry is bound as itself once the bind succeeds
+		err = dit.ModifyEntry(dn, d.NewChangeStamp(dn), d.DeleteOperation(userPassword, plaintext), d.AddOperation(userPassword, encoded...))
+		if err != nil {
+			return nil, err
+		}
+		return dit.GetEntry(dn)
+	})
+	if err != nil {
+		bindLogger.Printf("could not rehash plaintext password of %s: %s", dn.String(), err)
+		return nil
+	}
+	return entry
+}
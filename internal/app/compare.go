@@ -0,0 +1,73 @@
+package app
+
+import (
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+type CompareService struct {
+	schema    *d.Schema
+	scheduler *Scheduler
+}
+
+func NewCompareService(schema *d.Schema, scheduler *Scheduler) *CompareService {
+	return &CompareService{schema, scheduler}
+}
+
+type CompareRequest interface {
+	Dn() string
+	Attribute() string
+	Value() string
+}
+
+// Returns true if the entry holds the value for the attribute or one of its
+// subtypes, compared with the attribute's equality rule (RFC 4511 4.10).
+// Anonymous clients cannot compare passwords, bound is nil if anonymous.
+func (cs *CompareService) Compare(bound *d.Entry, cr CompareRequest) (bool, error) {
+	dn, err := d.NormaliseDN(cs.schema, cr.Dn())
+	if err != nil {
+		return false, err
+	}
+
+	attr, ok := cs.schema.FindAttributeDesc(cr.Attribute())
+	if !ok {
+		return false, d.NewLdapError(d.UndefinedAttributeType, nil, "unknown attribute %q", cr.Attribute())
+	}
+
+	if _, ok := attr.EqRule(); !ok {
+		return false, d.NewLdapError(d.InappropriateMatching, nil, "attribute %q has no equality matching rule", attr.Name())
+	}
+
+	// stored passwords are hashed, so the asserted password is verified against them
+	if userPassword, ok := cs.schema.FindAttribute("userPassword"); ok && attr == userPassword {
+		if bound == nil {
+			return false, d.NewLdapError(d.InsufficientAccessRights, nil, "anonymous clients cannot compare userPassword")
+		}
+
+		entry, err := ScheduleAwait(cs.scheduler, func(dit d.DIT) (*d.Entry, error) {
+			return dit.GetEntry(dn)
+		})
+		if err != nil {
+			return false, err
+		}
+
+		_, matched := checkPassword(entry, userPassword, cr.Value())
+		return matched, nil
+	}
+
+	return ScheduleAwait(cs.scheduler, func(dit d.DIT) (bool, error) {
+		entry, err := dit.GetEntry(dn)
+		if err != nil {
+			return false, err
+		}
+
+		// evaluated as an equality filter would be, so values of subtypes match
+		switch (&d.EqualityFilter{Attr: attr, Value: cr.Value()}).Evaluate(entry) {
+		case d.FilterTrue:
+			return true, nil
+		case d.FilterUndefined:
+			return false, d.NewLdapError(d.InvalidAttributeSyntax, nil, "value %q cannot be compared with attribute %q", cr.Value(), attr.Name())
+		default:
+			return false, nil
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"errors"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+type DeleteService struct {
+	schema    *d.Schema
+	scheduler *Scheduler
+}
+
+func NewDeleteService(schema *d.Schema, scheduler *Scheduler) *DeleteService {
+	return &DeleteService{schema, scheduler}
+}
+
+type DeleteRequest interface {
+	Dn() string
+	// true if the tree delete control was sent with the request
+	TreeDelete() bool
+}
+
+func (ds *DeleteService) DeleteEntry(dr DeleteRequest) error {
+	dn, err := d.NormaliseDN(ds.schema, dr.Dn())
+	if err != nil {
+		return err
+	}
+
+	return ScheduleAwaitError(ds.scheduler, func(dit d.DIT) error {
+		if dr.TreeDelete() {
+			return dit.DeleteSubtree(dn)
+		}
+
+		err := dit.DeleteEntry(dn)
+		if errors.Is(err, d.ErrNodeNotLeaf) {
+			return d.NewLdapError(d.NotAllowedOnNonLeaf, nil, "entry %s has subordinates", dn.String())
+		}
+		return err
+	})
+}
@@ -1,14 +1,47 @@
 package app
 
-import d "github.com/georgib0y/relientldap/internal/domain"
+import (
+	"slices"
+	"sync"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
 
 type ModifyService struct {
 	schema    *d.Schema
 	scheduler *Scheduler
+	// called with the ldif of the schema each time it is modified, nil if
+	// changes are not kept
+	persistSchema func(attrs, objClasses []byte) error
+	// held while the schema is modified, so that what is persisted is what
+	// gets applied
+	schemaMu sync.Mutex
+}
+
+type ModifyOption func(*ModifyService)
+
+// Keeps changes made to the schema by calling persist with the attribute types
+// and object classes as ldif. A change is only applied once it is persisted.
+func WithSchemaPersistence(persist func(attrs, objClasses []byte) error) ModifyOption {
+	return func(m *ModifyService) {
+		m.persistSchema = persist
+	}
+}
+
+func NewModifyService(schema *d.Schema, scheduler *Scheduler, options ...ModifyOption) *ModifyService {
+	m := &ModifyService{schema: schema, scheduler: scheduler}
+	for _, opt := range options {
+		opt(m)
+	}
+	return m
 }
 
-func NewModifyService(schema *d.Schema, scheduler *Scheduler) *ModifyService {
-	return &ModifyService{schema, scheduler}
+// A change made now by the bound entry, or anonymously if bound is nil
+func changeStamp(bound *d.Entry) d.ChangeStamp {
+	if bound == nil {
+		return d.NewChangeStamp(d.DN{})
+	}
+	return d.NewChangeStamp(bound.Dn())
 }
 
 type ModifyOperation int
@@ -30,35 +63,94 @@ type ModifyRequest interface {
 	Modifications() []Modification
 }
 
-func (m *ModifyService) ModifyEntry(mr ModifyRequest) error {
+func (m *ModifyService) ModifyEntry(bound *d.Entry, mr ModifyRequest) error {
 	dn, err := d.NormaliseDN(m.schema, mr.Dn())
 	if err != nil {
 		return err
 	}
 
+	if isSubschemaDn(m.schema, dn) {
+		return m.modifySubschema(bound, mr.Modifications())
+	}
+
 	changes := []d.ChangeOperation{}
+	userPassword, _ := m.schema.FindAttribute("userPassword")
 
 	for _, mod := range mr.Modifications() {
 		attr, ok := m.schema.FindAttribute(mod.Attribute())
 		if !ok {
 			return d.NewLdapError(d.NoSuchAttribute, nil, "could not find attr: %q", mod.Attribute())
 		}
+		if attr.NoUserMod() {
+			return d.NewLdapError(d.ConstraintViolation, nil, "attribute %q cannot be modified by users", mod.Attribute())
+		}
+
+		vals := mod.Vals()
+		if mod.ModOp() == ModifyAdd || mod.ModOp() == ModifyReplace {
+			if vals, err = encodeAttrVals(m.schema, attr, vals); err != nil {
+				return err
+			}
+		}
 
 		switch mod.ModOp() {
 		case ModifyAdd:
-			changes = append(changes, d.AddOperation(attr, mod.Vals()...))
+			changes = append(changes, d.AddOperation(attr, vals...))
 		case ModifyDelete:
-			changes = append(changes, d.DeleteOperation(attr, mod.Vals()...))
+			if attr == userPassword && len(vals) > 0 {
+				if vals, err = m.storedPasswordVals(dn, userPassword, vals); err != nil {
+					return err
+				}
+			}
+			changes = append(changes, d.DeleteOperation(attr, vals...))
 		case ModifyReplace:
-			changes = append(changes, d.ReplaceOperation(attr, mod.Vals()...))
+			changes = append(changes, d.ReplaceOperation(attr, vals...))
 		default:
 			return d.NewLdapError(d.ProtocolError, nil, "unknown modification operation type: %d", mod.ModOp())
 		}
 	}
 
 	return ScheduleAwaitError(m.scheduler, func(dit d.DIT) error {
-		return dit.ModifyEntry(dn, changes...)
+		return dit.ModifyEntry(dn, changeStamp(bound), changes...)
+	})
+}
+
+// Finds the stored userPassword values of the entry that vals name, either
+// exactly or as a password that verifies against them as bind and compare do.
+// Every value a password verifies against is deleted, so the SCRAM verifier
+// stored alongside it goes too. Values that match nothing are kept as given.
+func (m *ModifyService) storedPasswordVals(dn d.DN, userPassword *d.Attribute, vals []string) ([]string, error) {
+	entry, err := ScheduleAwait(m.scheduler, func(dit d.DIT) (*d.Entry, error) {
+		return dit.GetEntry(dn)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	// verified outside of the scheduler as hashing is slow, the delete fails
+	// if the values change before it is applied
+	stored, _ := entry.AttrVals(userPassword)
+	matched := []string{}
+	for _, v := range vals {
+		if slices.Contains(stored, v) {
+			matched = append(matched, v)
+			continue
+		}
+
+		found := false
+		for _, s := range stored {
+			if ok, err := verifyPassword(s, v); err == nil && ok {
+				found = true
+				if !slices.Contains(matched, s) {
+					matched = append(matched, s)
+				}
+			}
+		}
+
+		if !found {
+			matched = append(matched, v)
+		}
+	}
+	return matched, nil
 }
 
 type ModifyDnRequest interface {
@@ -68,7 +160,7 @@ type ModifyDnRequest interface {
 	NewParentDn() (string, bool)
 }
 
-func (m *ModifyService) ModifyEntryDn(mr ModifyDnRequest) error {
+func (m *ModifyService) ModifyEntryDn(bound *d.Entry, mr ModifyDnRequest) error {
 	dn, err := d.NormaliseDN(m.schema, mr.Dn())
 	if err != nil {
 		return err
@@ -85,10 +177,10 @@ func (m *ModifyService) ModifyEntryDn(mr ModifyDnRequest) error {
 		if err != nil {
 			return err
 		}
-		*newParentDn = pdn
+		newParentDn = &pdn
 	}
 
 	return ScheduleAwaitError(m.scheduler, func(dit d.DIT) error {
-		return dit.ModifyEntryDN(dn, newRdn, mr.RemoveExistingRdn(), newParentDn)
+		return dit.ModifyEntryDN(dn, newRdn, mr.RemoveExistingRdn(), newParentDn, changeStamp(bound))
 	})
 }
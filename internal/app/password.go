@@ -0,0 +1,113 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"slices"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+func generatePassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type PasswordService struct {
+	schema    *d.Schema
+	scheduler *Scheduler
+}
+
+func NewPasswordService(schema *d.Schema, scheduler *Scheduler) *PasswordService {
+	return &PasswordService{schema, scheduler}
+}
+
+type PasswordModifyRequest interface {
+	UserIdentity() (string, bool)
+	OldPassword() (string, bool)
+	NewPassword() (string, bool)
+}
+
+// Changes the password of the user identity, or of the bound entry if there
+// is no user identity (RFC 3062). Without the old password only the bound
+// entry can change its own password. If no new password is given one is
+// generated and returned, otherwise the returned password is empty.
+func (ps *PasswordService) ModifyPassword(bound *d.Entry, pr PasswordModifyRequest) (string, error) {
+	userPassword, ok := ps.schema.FindAttribute("userPassword")
+	if !ok {
+		return "", d.NewLdapError(d.UndefinedAttributeType, nil, "userPassword is not defined in schema")
+	}
+
+	var dn d.DN
+	if id, ok := pr.UserIdentity(); ok {
+		var err error
+		if dn, err = d.NormaliseDN(ps.schema, id); err != nil {
+			return "", err
+		}
+	} else if bound != nil {
+		dn = bound.Dn()
+	} else {
+		return "", d.NewLdapError(d.UnwillingToPerform, nil, "anonymous connections must give a user identity")
+	}
+
+	oldPassword, hasOld := pr.OldPassword()
+	if !hasOld && (bound == nil || !d.CompareDNs(bound.Dn(), dn)) {
+		return "", d.NewLdapError(d.InsufficientAccessRights, nil, "the old password is needed to change the password of another user")
+	}
+
+	newPassword, ok := pr.NewPassword()
+	generated := ""
+	if !ok {
+		var err error
+		if generated, err = generatePassword(); err != nil {
+			return "", err
+		}
+		newPassword = generated
+	}
+
+	if newPassword == "" {
+		return "", d.NewLdapError(d.UnwillingToPerform, nil, "the new password cannot be empty")
+	}
+
+	encoded, err := encodePassword(newPassword)
+	if err != nil {
+		return "", err
+	}
+
+	entry, err := ScheduleAwait(ps.scheduler, func(dit d.DIT) (*d.Entry, error) {
+		return dit.GetEntry(dn)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// hashing is slow so the old password is checked outside of the scheduler
+	stored := ""
+	if hasOld {
+		if stored, ok = checkPassword(entry, userPassword, oldPassword); !ok {
+			return "", d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
+		}
+	}
+
+	err = ScheduleAwaitError(ps.scheduler, func(dit d.DIT) error {
+		entry, err := dit.GetEntry(dn)
+		if err != nil {
+			return err
+		}
+
+		// the password may have changed since the old password was checked
+		if vals, _ := entry.AttrVals(userPassword); hasOld && !slices.Contains(vals, stored) {
+			return d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
+		}
+
+		return dit.ModifyEntry(dn, changeStamp(bound), d.DeleteOperation(userPassword), d.AddOperation(userPassword, encoded...))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return generated, nil
+}
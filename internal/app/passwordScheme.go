@@ -0,0 +1,445 @@
+package app
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var passwordLogger = log.New(os.Stderr, "passwordScheme: ", log.Lshortfile)
+
+// A way of storing userPassword values, stored values are prefixed with the
+// scheme name in braces (RFC 2307 5.3), e.g. {SSHA}
+type passwordScheme interface {
+	// Returns the hashed password without the scheme prefix
+	Hash(password string) (string, error)
+	// Checks password against a hashed value without the scheme prefix
+	Verify(password, hashed string) (bool, error)
+	// Checks that a hashed value without the scheme prefix is well formed and
+	// within the cost limits, so that verifying it cannot exhaust the server
+	Validate(hashed string) error
+}
+
+// Upper bounds on the cost parameters of stored values, which clients can
+// write themselves. They are well above the costs new passwords are hashed with.
+const (
+	maxPbkdf2Iterations = 5000000
+	maxBcryptCost       = 14
+	maxArgon2Memory     = 256 * 1024 // KiB
+	maxArgon2Time       = 16
+	maxArgon2Threads    = 16
+	maxArgon2KeyLen     = 128
+)
+
+var passwordSchemes = map[string]passwordScheme{
+	"SSHA":          saltedHashScheme{sha1.New},
+	"SSHA512":       saltedHashScheme{sha512.New},
+	"PBKDF2":        pbkdf2Scheme{sha1.New, 1300000},
+	"PBKDF2-SHA256": pbkdf2Scheme{sha256.New, 600000},
+	"PBKDF2-SHA512": pbkdf2Scheme{sha512.New, 210000},
+	"BCRYPT":        bcryptScheme{bcrypt.DefaultCost},
+	"ARGON2":        argon2Scheme{time: 2, memory: 19 * 1024, threads: 1},
+	"SCRAM-SHA-256": scramScheme{scramIterations},
+}
+
+// the scheme new passwords are hashed with, only changed at startup
+var defaultPasswordScheme = "ARGON2"
+
+// whether a SCRAM-SHA-256 verifier is stored alongside each new password, off
+// by default as simple binds also accept the verifier, so it would bound the
+// cost of guessing the password to its iteration count
+var storeScramVerifiers = false
+
+// Stores a SCRAM-SHA-256 verifier with new passwords so that they can be used
+// for SCRAM binds, only changed at startup
+func SetStoreScramVerifiers(enabled bool) {
+	storeScramVerifiers = enabled
+}
+
+// Sets the scheme used to hash new passwords
+func SetDefaultPasswordScheme(name string) error {
+	name = strings.ToUpper(name)
+	if _, ok := passwordSchemes[name]; !ok {
+		return fmt.Errorf("unknown password scheme %q, expected one of %s", name, strings.Join(PasswordSchemes(), ", "))
+	}
+	defaultPasswordScheme = name
+	return nil
+}
+
+// The names of the supported password schemes
+func PasswordSchemes() []string {
+	names := []string{}
+	for name := range passwordSchemes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Splits a stored value into its scheme and hashed password, ok is false if
+// the value has no scheme prefix and is a plaintext password
+func splitPasswordScheme(stored string) (name, hashed string, ok bool) {
+	if !strings.HasPrefix(stored, "{") {
+		return "", "", false
+	}
+
+	name, hashed, ok = strings.Cut(stored[1:], "}")
+	return strings.ToUpper(name), hashed, ok
+}
+
+// Hashes password with the default scheme, along with a SCRAM-SHA-256
+// verifier if they are stored so that the password can be used for SCRAM binds
+func encodePassword(password string) ([]string, error) {
+	names := []string{defaultPasswordScheme}
+	if storeScramVerifiers && defaultPasswordScheme != "SCRAM-SHA-256" {
+		names = append(names, "SCRAM-SHA-256")
+	}
+
+	encoded := []string{}
+	for _, name := range names {
+		hashed, err := passwordSchemes[name].Hash(password)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, "{"+name+"}"+hashed)
+	}
+	return encoded, nil
+}
+
+// Hashes the plaintext values of userPassword being written by a client,
+// values that are already hashed with a known scheme are kept as is once
+// they are checked to be well formed and within the cost limits
+func encodePasswordVals(vals []string) ([]string, error) {
+	encoded := []string{}
+	for _, v := range vals {
+		if name, hashed, ok := splitPasswordScheme(v); ok {
+			if scheme, known := passwordSchemes[name]; known {
+				if err := scheme.Validate(hashed); err != nil {
+					return nil, d.NewLdapError(d.ConstraintViolation, nil, "invalid {%s} userPassword value: %s", name, err)
+				}
+				encoded = append(encoded, v)
+				continue
+			}
+		}
+
+		e, err := encodePassword(v)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, e...)
+	}
+	return encoded, nil
+}
+
+// Hashes vals if they are being written to userPassword
+func encodeAttrVals(schema *d.Schema, attr *d.Attribute, vals []string) ([]string, error) {
+	if userPassword, ok := schema.FindAttribute("userPassword"); !ok || attr != userPassword {
+		return vals, nil
+	}
+	return encodePasswordVals(vals)
+}
+
+// Checks password against a stored userPassword value. Values with an unknown
+// scheme never match, values without a scheme are compared as plaintext.
+func verifyPassword(stored, password string) (bool, error) {
+	name, hashed, ok := splitPasswordScheme(stored)
+	if !ok {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, nil
+	}
+
+	scheme, ok := passwordSchemes[name]
+	if !ok {
+		return false, nil
+	}
+	return scheme.Verify(password, hashed)
+}
+
+// Returns the userPassword value of the entry that password matches
+func checkPassword(entry *d.Entry, userPassword *d.Attribute, password string) (string, bool) {
+	vals, _ := entry.AttrVals(userPassword)
+	for _, v := range vals {
+		ok, err := verifyPassword(v, password)
+		if err != nil {
+			dn := entry.Dn()
+			passwordLogger.Printf("could not verify a userPassword value of %s: %s", dn.String(), err)
+			continue
+		}
+		if ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func randomSalt(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// {SSHA} and {SSHA512}, base64(H(password + salt) + salt)
+type saltedHashScheme struct {
+	h func() hash.Hash
+}
+
+func (s saltedHashScheme) digest(password string, salt []byte) []byte {
+	h := s.h()
+	h.Write([]byte(password))
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+func (s saltedHashScheme) Hash(password string) (string, error) {
+	salt, err := randomSalt(8)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(append(s.digest(password, salt), salt...)), nil
+}
+
+// Splits a stored value into its digest and salt
+func (s saltedHashScheme) parse(hashed string) (digest, salt []byte, err error) {
+	b, err := base64.StdEncoding.DecodeString(hashed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := s.h().Size()
+	if len(b) <= size {
+		return nil, nil, errors.New("salted hash has no salt")
+	}
+	return b[:size], b[size:], nil
+}
+
+func (s saltedHashScheme) Verify(password, hashed string) (bool, error) {
+	digest, salt, err := s.parse(hashed)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(digest, s.digest(password, salt)) == 1, nil
+}
+
+func (s saltedHashScheme) Validate(hashed string) error {
+	_, _, err := s.parse(hashed)
+	return err
+}
+
+// the base64 alphabet used by the OpenLDAP pbkdf2 module, with . in place of +
+var adaptedBase64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").WithPadding(base64.NoPadding)
+
+// {PBKDF2}, {PBKDF2-SHA256} and {PBKDF2-SHA512} in the OpenLDAP format,
+// iterations$salt$key
+type pbkdf2Scheme struct {
+	h    func() hash.Hash
+	iter int
+}
+
+func (s pbkdf2Scheme) Hash(password string) (string, error) {
+	salt, err := randomSalt(16)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(s.h, password, salt, s.iter, s.h().Size())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d$%s$%s", s.iter, adaptedBase64.EncodeToString(salt), adaptedBase64.EncodeToString(key)), nil
+}
+
+func (s pbkdf2Scheme) parse(hashed string) (iter int, salt, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 3 {
+		return 0, nil, nil, errors.New("expected pbkdf2 value to be iterations$salt$key")
+	}
+
+	iter, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("invalid pbkdf2 iterations: %w", err)
+	}
+	if iter < 1 || iter > maxPbkdf2Iterations {
+		return 0, nil, nil, fmt.Errorf("pbkdf2 iterations must be between 1 and %d", maxPbkdf2Iterations)
+	}
+
+	if salt, err = adaptedBase64.DecodeString(parts[1]); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if key, err = adaptedBase64.DecodeString(parts[2]); err != nil {
+		return 0, nil, nil, err
+	}
+	// each block of a longer key costs the iterations again
+	if len(key) < 1 || len(key) > s.h().Size() {
+		return 0, nil, nil, fmt.Errorf("pbkdf2 key must be between 1 and %d bytes", s.h().Size())
+	}
+
+	return iter, salt, key, nil
+}
+
+func (s pbkdf2Scheme) Verify(password, hashed string) (bool, error) {
+	iter, salt, expected, err := s.parse(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := pbkdf2.Key(s.h, password, salt, iter, len(expected))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+func (s pbkdf2Scheme) Validate(hashed string) error {
+	_, _, _, err := s.parse(hashed)
+	return err
+}
+
+// {BCRYPT}, the modular crypt format $2b$cost$saltkey
+type bcryptScheme struct {
+	cost int
+}
+
+func (s bcryptScheme) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", d.NewLdapError(d.ConstraintViolation, nil, "bcrypt passwords cannot be longer than 72 bytes")
+	}
+	return string(b), err
+}
+
+func (s bcryptScheme) Validate(hashed string) error {
+	// the modular crypt format is always 60 bytes
+	if len(hashed) != 60 {
+		return errors.New("expected bcrypt value to be 60 bytes")
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashed))
+	if err != nil {
+		return err
+	}
+	if cost > maxBcryptCost {
+		return fmt.Errorf("bcrypt cost must be at most %d", maxBcryptCost)
+	}
+	return nil
+}
+
+func (s bcryptScheme) Verify(password, hashed string) (bool, error) {
+	if err := s.Validate(hashed); err != nil {
+		return false, err
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// {ARGON2}, the PHC string format $argon2id$v=19$m=memory,t=time,p=threads$salt$key
+type argon2Scheme struct {
+	time, memory uint32
+	threads      uint8
+}
+
+func (s argon2Scheme) Hash(password string) (string, error) {
+	salt, err := randomSalt(16)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, s.time, s.memory, s.threads, 32)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, s.memory, s.time, s.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// The parameters and key of a stored value
+type argon2Params struct {
+	variant      string
+	time, memory uint32
+	threads      uint8
+	salt, key    []byte
+}
+
+func (s argon2Scheme) parse(hashed string) (argon2Params, error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return argon2Params{}, errors.New("expected argon2 value to be in the phc string format")
+	}
+
+	p := argon2Params{variant: parts[1]}
+	if p.variant != "argon2id" && p.variant != "argon2i" {
+		return argon2Params{}, fmt.Errorf("unsupported argon2 variant %q", p.variant)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return argon2Params{}, fmt.Errorf("invalid argon2 parameters %q: %w", parts[3], err)
+	}
+	if p.time < 1 || p.time > maxArgon2Time || p.threads < 1 || p.threads > maxArgon2Threads || p.memory > maxArgon2Memory {
+		return argon2Params{}, fmt.Errorf(
+			"argon2 parameters %q must be within m=%d,t=%d,p=%d",
+			parts[3], maxArgon2Memory, maxArgon2Time, maxArgon2Threads,
+		)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Params{}, err
+	}
+
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return argon2Params{}, err
+	}
+	if len(p.key) < 1 || len(p.key) > maxArgon2KeyLen {
+		return argon2Params{}, fmt.Errorf("argon2 key must be between 1 and %d bytes", maxArgon2KeyLen)
+	}
+
+	return p, nil
+}
+
+func (s argon2Scheme) Verify(password, hashed string) (bool, error) {
+	p, err := s.parse(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	var key []byte
+	if p.variant == "argon2id" {
+		key = argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	} else {
+		key = argon2.Key([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	}
+
+	return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+}
+
+func (s argon2Scheme) Validate(hashed string) error {
+	_, err := s.parse(hashed)
+	return err
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+// the features of RFC 4512 5.1.5 that searches support
+var supportedFeatures = []string{
+	// all operational attributes (RFC 3673)
+	"1.3.6.1.4.1.4203.1.5.1",
+	// absolute true and false filters (RFC 4526)
+	"1.3.6.1.4.1.4203.1.5.3",
+}
+
+// The root DSE is built each time it is searched so that it reflects the DIT
+// and what is registered at the time
+func (s *SearchService) rootDSE(dit d.DIT, external map[string][]string) *d.Entry {
+	rootDn := dit.RootDN()
+	attrs := map[*d.Attribute][]string{
+		d.NamingContextsAttribute:       {rootDn.String()},
+		d.SupportedLDAPVersionAttribute: {"3"},
+		d.SupportedFeaturesAttribute:    supportedFeatures,
+		d.SubschemaSubentryAttribute:    {subschemaDn},
+	}
+
+	for name, vals := range external {
+		if attr, ok := s.schema.FindAttribute(name); ok && len(vals) > 0 {
+			attrs[attr] = append(attrs[attr], vals...)
+		}
+	}
+
+	return d.NewRootDSE(s.schema, attrs)
+}
+
+// A base object search with an empty base reads the root DSE (RFC 4512 5.1)
+func (s *SearchService) searchRootDSE(filter d.Filter, sel attrSelection, typesOnly bool) ([]SearchEntry, error) {
+	var external map[string][]string
+	if s.rootDSEAttrs != nil {
+		external = s.rootDSEAttrs()
+	}
+
+	return ScheduleAwait(s.scheduler, func(dit d.DIT) ([]SearchEntry, error) {
+		e := s.rootDSE(dit, external)
+		if filter.Evaluate(e) != d.FilterTrue {
+			return []SearchEntry{}, nil
+		}
+		return []SearchEntry{newSearchEntry(e, sel, typesOnly)}, nil
+	})
+}
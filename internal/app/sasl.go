@@ -0,0 +1,252 @@
+package app
+
+import (
+	"slices"
+	"strings"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+// The identity of a connection established outside of LDAP, such as by a
+// verified TLS client certificate
+type ExternalIdentity struct {
+	SubjectDN  string
+	CommonName string
+}
+
+// Implemented by bind requests on connections that have an external identity
+type ExternalBindRequest interface {
+	BindRequest
+	ExternalIdentity() (ExternalIdentity, bool)
+}
+
+// The state of a SASL bind on a connection, kept between the bind requests of
+// a mechanism that needs more than one (RFC 4513 5.2.1.2)
+type SaslSession struct {
+	mechanism   string
+	exchange    saslExchange
+	serverCreds *string
+}
+
+// The credentials for the client in the response to the last bind request
+func (s *SaslSession) ServerCredentials() (string, bool) {
+	if s.serverCreds == nil {
+		return "", false
+	}
+	return *s.serverCreds, true
+}
+
+func (s *SaslSession) reset() {
+	s.mechanism, s.exchange, s.serverCreds = "", nil, nil
+}
+
+// Implemented by bind requests on connections that keep SASL state between
+// bind requests, without it every bind starts a new exchange
+type SessionBindRequest interface {
+	BindRequest
+	SaslSession() *SaslSession
+}
+
+// The outcome of a bind request in a SASL exchange
+type saslResult struct {
+	// the entry the connection is bound as once done
+	entry *d.Entry
+	// sent to the client if not nil
+	serverCreds *string
+	// false if the exchange needs another bind request
+	done bool
+}
+
+// A SASL mechanism (RFC 4422)
+type saslMechanism interface {
+	// Starts an exchange, br is its first bind request
+	Start(b *bindService, br BindRequest) saslExchange
+}
+
+type saslExchange interface {
+	// Takes the credentials of the next bind request in the exchange
+	Next(credentials string) (saslResult, error)
+}
+
+type saslExchangeFunc func(credentials string) (saslResult, error)
+
+func (f saslExchangeFunc) Next(credentials string) (saslResult, error) {
+	return f(credentials)
+}
+
+// A mechanism that authenticates with the credentials of a single bind request
+type singleStepMechanism func(b *bindService, br BindRequest, credentials string) (*d.Entry, error)
+
+func (m singleStepMechanism) Start(b *bindService, br BindRequest) saslExchange {
+	return saslExchangeFunc(func(credentials string) (saslResult, error) {
+		entry, err := m(b, br, credentials)
+		return saslResult{entry: entry, done: true}, err
+	})
+}
+
+var saslMechanisms = map[string]saslMechanism{
+	"PLAIN":         singleStepMechanism(authenticatePlain),
+	"EXTERNAL":      singleStepMechanism(authenticateExternal),
+	"SCRAM-SHA-256": scramMechanism{},
+}
+
+func (b *bindService) SupportedSaslMechanisms() []string {
+	names := []string{}
+	for name := range saslMechanisms {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Continues the exchange in progress on the session, or starts a new one if
+// there is none or the mechanism has changed. An exchange that needs another
+// bind request returns SaslBindInProgress.
+func (b *bindService) authenticateSasl(br BindRequest, mechanism string, session *SaslSession) (*d.Entry, error) {
+	session.serverCreds = nil
+
+	if session.exchange == nil || session.mechanism != mechanism {
+		m, ok := saslMechanisms[mechanism]
+		if !ok {
+			session.reset()
+			return nil, d.NewLdapError(d.AuthMethodNotSupported, nil, "sasl mechanism %q is not supported", mechanism)
+		}
+		session.mechanism, session.exchange = mechanism, m.Start(b, br)
+	}
+
+	credentials, _ := br.SaslCredentials()
+	res, err := session.exchange.Next(credentials)
+	if err != nil || res.done {
+		session.mechanism, session.exchange = "", nil
+	}
+	session.serverCreds = res.serverCreds
+
+	if err != nil {
+		return nil, err
+	}
+	if !res.done {
+		return nil, d.NewLdapError(d.SaslBindInProgress, nil, "")
+	}
+	return res.entry, nil
+}
+
+// Maps a SASL authentication or authorization identity (RFC 4513 5.2.1.8) to
+// an entry. dn: identities are the dn of the entry, u: and bare identities are
+// usernames looked up by the identity mapping attribute under its base.
+func (b *bindService) mapIdentity(id string) (*d.Entry, error) {
+	invalid := d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
+
+	if dnStr, ok := strings.CutPrefix(id, "dn:"); ok {
+		dn, err := d.NormaliseDN(b.schema, dnStr)
+		if err != nil {
+			return nil, invalid
+		}
+
+		entry, err := ScheduleAwait(b.scheduler, func(dit d.DIT) (*d.Entry, error) {
+			return dit.GetEntry(dn)
+		})
+		if err != nil {
+			return nil, invalid
+		}
+		return entry, nil
+	}
+
+	username := strings.TrimPrefix(id, "u:")
+	if username == "" {
+		return nil, invalid
+	}
+
+	attr, ok := b.schema.FindAttribute(b.identityAttr)
+	if !ok {
+		return nil, d.NewLdapError(d.OperationsError, nil, "identity mapping attribute %q is not defined in schema", b.identityAttr)
+	}
+	filter := &d.EqualityFilter{Desc: attr.Name(), Attr: attr, Value: username}
+
+	var base *d.DN
+	if b.identityBase != "" {
+		dn, err := d.NormaliseDN(b.schema, b.identityBase)
+		if err != nil {
+			return nil, d.NewLdapError(d.OperationsError, nil, "invalid identity mapping base %q", b.identityBase)
+		}
+		base = &dn
+	}
+
+	matched, err := ScheduleAwait(b.scheduler, func(dit d.DIT) ([]*d.Entry, error) {
+		if base == nil {
+			return dit.Search(dit.RootDN(), d.WholeSubtree, filter)
+		}
+		return dit.Search(*base, d.WholeSubtree, filter)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// an ambiguous username does not identify anyone
+	if len(matched) != 1 {
+		return nil, invalid
+	}
+	return matched[0], nil
+}
+
+// Checks that the requested authorization identity is the authenticated
+// entry, acting as another entry is not supported
+func (b *bindService) authorize(entry *d.Entry, authzId string) (*d.Entry, error) {
+	if authzId == "" {
+		return entry, nil
+	}
+
+	authz, err := b.mapIdentity(authzId)
+	if err != nil {
+		return nil, d.NewLdapError(d.InsufficientAccessRights, nil, "cannot authorize as %q", authzId)
+	}
+
+	if !d.CompareDNs(entry.Dn(), authz.Dn()) {
+		return nil, d.NewLdapError(d.InsufficientAccessRights, nil, "cannot authorize as %q", authzId)
+	}
+	return entry, nil
+}
+
+// authzid NUL authcid NUL passwd (RFC 4616)
+func authenticatePlain(b *bindService, br BindRequest, credentials string) (*d.Entry, error) {
+	parts := strings.Split(credentials, "\x00")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return nil, d.NewLdapError(d.InvalidCredentials, nil, "malformed PLAIN credentials")
+	}
+	authzId, authcId, password := parts[0], parts[1], parts[2]
+
+	entry, err := b.mapIdentity(authcId)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry, err = b.checkEntryPassword(entry, password); err != nil {
+		return nil, err
+	}
+
+	return b.authorize(entry, authzId)
+}
+
+// Binds as the entry of the connection's external identity, its subject dn
+// or if there is no such entry its common name as a username (RFC 4422 Appendix A)
+func authenticateExternal(b *bindService, br BindRequest, credentials string) (*d.Entry, error) {
+	var (
+		id ExternalIdentity
+		ok bool
+	)
+	if ebr, isExternal := br.(ExternalBindRequest); isExternal {
+		id, ok = ebr.ExternalIdentity()
+	}
+	if !ok {
+		return nil, d.NewLdapError(d.InappropriateAuthentication, nil, "the connection has no external identity")
+	}
+
+	entry, err := b.mapIdentity("dn:" + id.SubjectDN)
+	if err != nil && id.CommonName != "" {
+		entry, err = b.mapIdentity("u:" + id.CommonName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b.authorize(entry, credentials)
+}
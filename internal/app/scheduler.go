@@ -76,6 +76,7 @@ func ScheduleAwaitError(s *Scheduler, action AwaitError) error {
 		err := action(dit)
 		if err != nil {
 			errChan <- err
+			return
 		}
 		done <- struct{}{}
 	})
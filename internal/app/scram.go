@@ -0,0 +1,314 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+// the iteration count recommended by RFC 7677
+const scramIterations = 4096
+
+// stored verifiers can be written by clients, so their cost is bounded
+const maxScramIterations = 1000000
+
+// The keys of RFC 5802 3 that the server keeps for a password
+type scramVerifier struct {
+	iter                 int
+	salt                 []byte
+	storedKey, serverKey []byte
+}
+
+// The password is used as is rather than being prepared with SASLprep
+func newScramVerifier(password string, salt []byte, iter int) (scramVerifier, error) {
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iter, sha256.Size)
+	if err != nil {
+		return scramVerifier{}, err
+	}
+
+	storedKey := sha256.Sum256(scramHmac(salted, "Client Key"))
+	return scramVerifier{iter, salt, storedKey[:], scramHmac(salted, "Server Key")}, nil
+}
+
+func scramHmac(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// iterations:salt$StoredKey:ServerKey (RFC 5803 3)
+func (v scramVerifier) String() string {
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%d:%s$%s:%s", v.iter, b64(v.salt), b64(v.storedKey), b64(v.serverKey))
+}
+
+func parseScramVerifier(hashed string) (scramVerifier, error) {
+	params, keys, ok := strings.Cut(hashed, "$")
+	iterStr, saltStr, hasSalt := strings.Cut(params, ":")
+	storedStr, serverStr, hasKeys := strings.Cut(keys, ":")
+	if !ok || !hasSalt || !hasKeys {
+		return scramVerifier{}, errors.New("expected scram value to be iterations:salt$storedKey:serverKey")
+	}
+
+	iter, err := strconv.Atoi(iterStr)
+	if err != nil || iter < 1 || iter > maxScramIterations {
+		return scramVerifier{}, fmt.Errorf("scram iterations %q must be between 1 and %d", iterStr, maxScramIterations)
+	}
+
+	v := scramVerifier{iter: iter}
+	for _, field := range []struct {
+		dst *[]byte
+		src string
+	}{{&v.salt, saltStr}, {&v.storedKey, storedStr}, {&v.serverKey, serverStr}} {
+		if *field.dst, err = base64.StdEncoding.DecodeString(field.src); err != nil {
+			return scramVerifier{}, err
+		}
+	}
+
+	if len(v.storedKey) != sha256.Size || len(v.serverKey) != sha256.Size {
+		return scramVerifier{}, errors.New("scram keys are not sha256 sized")
+	}
+	return v, nil
+}
+
+// {SCRAM-SHA-256}, a verifier that can check a plaintext password as well as
+// authenticate a SCRAM exchange
+type scramScheme struct {
+	iter int
+}
+
+func (s scramScheme) Hash(password string) (string, error) {
+	salt, err := randomSalt(16)
+	if err != nil {
+		return "", err
+	}
+
+	v, err := newScramVerifier(password, salt, s.iter)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+func (s scramScheme) Verify(password, hashed string) (bool, error) {
+	v, err := parseScramVerifier(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	derived, err := newScramVerifier(password, v.salt, v.iter)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(derived.storedKey, v.storedKey) == 1, nil
+}
+
+func (s scramScheme) Validate(hashed string) error {
+	_, err := parseScramVerifier(hashed)
+	return err
+}
+
+// Returns the first SCRAM-SHA-256 verifier in the entry's userPassword values
+func findScramVerifier(entry *d.Entry, userPassword *d.Attribute) (scramVerifier, bool) {
+	vals, _ := entry.AttrVals(userPassword)
+	for _, val := range vals {
+		name, hashed, ok := splitPasswordScheme(val)
+		if !ok || name != "SCRAM-SHA-256" {
+			continue
+		}
+
+		v, err := parseScramVerifier(hashed)
+		if err != nil {
+			dn := entry.Dn()
+			passwordLogger.Printf("could not parse a scram verifier of %s: %s", dn.String(), err)
+			continue
+		}
+		return v, true
+	}
+	return scramVerifier{}, false
+}
+
+// salts users that cannot authenticate so that they look the same as users
+// that can, the salt of a username stays the same until restart
+var scramMockKey = func() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}()
+
+func mockScramVerifier(username string) scramVerifier {
+	return scramVerifier{iter: scramIterations, salt: scramHmac(scramMockKey, username)[:16]}
+}
+
+// Decodes the =2C and =3D escapes of a saslname (RFC 5802 5.1)
+func decodeSaslname(s string) (string, bool) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '=' {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1 : min(i+3, len(s))] {
+		case "2C":
+			sb.WriteByte(',')
+		case "3D":
+			sb.WriteByte('=')
+		default:
+			return "", false
+		}
+		i += 2
+	}
+	return sb.String(), true
+}
+
+// SCRAM-SHA-256 (RFC 5802, RFC 7677) without channel binding. The client
+// proves it knows the password against the verifier stored on the entry, so
+// the password itself is never sent. Verifiers are only stored when enabled
+// with SetStoreScramVerifiers or written by the client.
+type scramMechanism struct{}
+
+func (scramMechanism) Start(b *bindService, br BindRequest) saslExchange {
+	return &scramExchange{b: b}
+}
+
+type scramExchange struct {
+	b *bindService
+	// from the client-first message
+	gs2Header, clientFirstBare, authzId string
+	// nil if the username does not identify an entry with a verifier
+	entry              *d.Entry
+	verifier           scramVerifier
+	nonce, serverFirst string
+}
+
+func (e *scramExchange) Next(credentials string) (saslResult, error) {
+	if e.serverFirst == "" {
+		return e.clientFirst(credentials)
+	}
+	return e.clientFinal(credentials)
+}
+
+// gs2-cbind-flag,[a=authzid],n=username,r=nonce[,extensions]
+func (e *scramExchange) clientFirst(msg string) (saslResult, error) {
+	malformed := d.NewLdapError(d.InvalidCredentials, nil, "malformed SCRAM client-first message")
+
+	parts := strings.SplitN(msg, ",", 3)
+	if len(parts) != 3 {
+		return saslResult{}, malformed
+	}
+
+	switch {
+	case parts[0] == "n", parts[0] == "y":
+	case strings.HasPrefix(parts[0], "p="):
+		return saslResult{}, d.NewLdapError(d.AuthMethodNotSupported, nil, "SCRAM channel binding is not supported")
+	default:
+		return saslResult{}, malformed
+	}
+
+	if parts[1] != "" {
+		authzId, ok := strings.CutPrefix(parts[1], "a=")
+		if !ok {
+			return saslResult{}, malformed
+		}
+		if e.authzId, ok = decodeSaslname(authzId); !ok {
+			return saslResult{}, malformed
+		}
+	}
+
+	e.gs2Header = parts[0] + "," + parts[1] + ","
+	e.clientFirstBare = parts[2]
+
+	// a mandatory extension comes before the username and is not supported
+	attrs := strings.Split(parts[2], ",")
+	if len(attrs) < 2 || !strings.HasPrefix(attrs[0], "n=") || !strings.HasPrefix(attrs[1], "r=") || attrs[1] == "r=" {
+		return saslResult{}, malformed
+	}
+
+	username, ok := decodeSaslname(attrs[0][2:])
+	if !ok || username == "" {
+		return saslResult{}, malformed
+	}
+
+	userPassword, ok := e.b.schema.FindAttribute("userPassword")
+	if !ok {
+		return saslResult{}, d.NewLdapError(d.UndefinedAttributeType, nil, "userPassword is not defined in schema")
+	}
+
+	entry, err := e.b.mapIdentity(username)
+	if err != nil && !errors.Is(err, d.NewLdapError(d.InvalidCredentials, nil, "")) {
+		return saslResult{}, err
+	}
+
+	// unknown users fail at the client-final message, the same as a wrong password
+	e.verifier = mockScramVerifier(username)
+	if entry != nil {
+		if v, ok := findScramVerifier(entry, userPassword); ok {
+			e.entry, e.verifier = entry, v
+		}
+	}
+
+	serverNonce, err := randomSalt(18)
+	if err != nil {
+		return saslResult{}, err
+	}
+	e.nonce = attrs[1][2:] + base64.StdEncoding.EncodeToString(serverNonce)
+
+	e.serverFirst = fmt.Sprintf("r=%s,s=%s,i=%d", e.nonce, base64.StdEncoding.EncodeToString(e.verifier.salt), e.verifier.iter)
+	serverFirst := e.serverFirst
+	return saslResult{serverCreds: &serverFirst}, nil
+}
+
+// c=gs2-header,r=nonce[,extensions],p=proof
+func (e *scramExchange) clientFinal(msg string) (saslResult, error) {
+	malformed := d.NewLdapError(d.InvalidCredentials, nil, "malformed SCRAM client-final message")
+
+	i := strings.LastIndex(msg, ",p=")
+	if i < 0 {
+		return saslResult{}, malformed
+	}
+	withoutProof := msg[:i]
+
+	attrs := strings.Split(withoutProof, ",")
+	if len(attrs) < 2 || attrs[0] != "c="+base64.StdEncoding.EncodeToString([]byte(e.gs2Header)) || attrs[1] != "r="+e.nonce {
+		return saslResult{}, malformed
+	}
+
+	proof, err := base64.StdEncoding.DecodeString(msg[i+3:])
+	if err != nil || len(proof) != sha256.Size {
+		return saslResult{}, malformed
+	}
+
+	if e.entry == nil {
+		return saslResult{}, d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
+	}
+
+	authMessage := e.clientFirstBare + "," + e.serverFirst + "," + withoutProof
+
+	clientKey := scramHmac(e.verifier.storedKey, authMessage)
+	for i := range clientKey {
+		clientKey[i] ^= proof[i]
+	}
+
+	storedKey := sha256.Sum256(clientKey)
+	if subtle.ConstantTimeCompare(storedKey[:], e.verifier.storedKey) != 1 {
+		return saslResult{}, d.NewLdapError(d.InvalidCredentials, nil, "invalid credentials")
+	}
+
+	entry, err := e.b.authorize(e.entry, e.authzId)
+	if err != nil {
+		return saslResult{}, err
+	}
+
+	serverFinal := "v=" + base64.StdEncoding.EncodeToString(scramHmac(e.verifier.serverKey, authMessage))
+	return saslResult{entry: entry, serverCreds: &serverFinal, done: true}, nil
+}
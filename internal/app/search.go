@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+type DerefAliases int
+
+const (
+	NeverDerefAliases   DerefAliases = 0
+	DerefInSearching    DerefAliases = 1
+	DerefFindingBaseObj DerefAliases = 2
+	DerefAlways         DerefAliases = 3
+)
+
+type SearchRequest interface {
+	BaseDn() string
+	SearchScope() d.SearchScope
+	// TODO there are no alias entries in the dit yet so this is ignored
+	Deref() DerefAliases
+	// a max of 0 means there is no limit
+	MaxEntries() int
+	MaxTime() time.Duration
+	AttrsOnly() bool
+	DomainFilter(schema *d.Schema) (d.Filter, error)
+	RequestedAttrs() []string
+}
+
+type SearchEntry struct {
+	Dn    string
+	Attrs map[string][]string
+}
+
+type SearchService struct {
+	schema    *d.Schema
+	scheduler *Scheduler
+	// root DSE values that are only known outside of the app
+	rootDSEAttrs func() map[string][]string
+}
+
+type SearchOption func(*SearchService)
+
+// Adds the values returned by attrs to the root DSE each time it is read, for
+// attributes such as supportedControl that depend on what the server registered
+func WithRootDSE(attrs func() map[string][]string) SearchOption {
+	return func(s *SearchService) {
+		s.rootDSEAttrs = attrs
+	}
+}
+
+func NewSearchService(schema *d.Schema, scheduler *Scheduler, options ...SearchOption) *SearchService {
+	s := &SearchService{schema: schema, scheduler: scheduler}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+type attrSelection struct {
+	all         bool
+	operational bool
+	attrs       map[*d.Attribute]struct{}
+}
+
+// Builds the set of attributes to return as per RFC 4511 4.5.1.8, with + for
+// all operational attributes (RFC 3673)
+func (s *SearchService) attrSelection(requested []string) attrSelection {
+	sel := attrSelection{attrs: map[*d.Attribute]struct{}{}}
+	if len(requested) == 0 {
+		sel.all = true
+		return sel
+	}
+
+	for _, name := range requested {
+		switch name {
+		case "*":
+			sel.all = true
+		case "+":
+			sel.operational = true
+		case "1.1":
+			// no attributes requested
+		default:
+			// unrecognised attributes are ignored rather than treated as an error
+			if attr, ok := s.schema.FindAttribute(name); ok {
+				sel.attrs[attr] = struct{}{}
+			}
+		}
+	}
+
+	return sel
+}
+
+// Operational attributes are only included when asked for by name or with +
+func (a attrSelection) includes(attr *d.Attribute) bool {
+	if _, ok := a.attrs[attr]; ok {
+		return true
+	}
+
+	if attr.Usage() != d.UserApplications {
+		return a.operational
+	}
+	return a.all
+}
+
+func newSearchEntry(e *d.Entry, sel attrSelection, typesOnly bool) SearchEntry {
+	dn := e.Dn()
+	se := SearchEntry{Dn: dn.String(), Attrs: map[string][]string{}}
+
+	for _, attr := range e.Attributes() {
+		if !sel.includes(attr) {
+			continue
+		}
+
+		vals, ok := e.AttrVals(attr)
+		if !ok {
+			continue
+		}
+
+		if typesOnly {
+			vals = []string{}
+		}
+		se.Attrs[attr.Name()] = vals
+	}
+
+	return se
+}
+
+type searchResult struct {
+	entries []SearchEntry
+	err     error
+}
+
+// Search returns the entries that matched the request. If a size or time
+// limit was hit, the entries found so far are returned along with the error.
+// The walk of the DIT stops with ctx's error if ctx is cancelled.
+func (s *SearchService) Search(ctx context.Context, sr SearchRequest) ([]SearchEntry, error) {
+	start := time.Now()
+
+	dn, err := d.NormaliseDN(s.schema, sr.BaseDn())
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := sr.DomainFilter(s.schema)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := s.attrSelection(sr.RequestedAttrs())
+
+	if dn.IsEmpty() && sr.SearchScope() == d.BaseObject {
+		return s.searchRootDSE(filter, sel, sr.AttrsOnly())
+	}
+
+	if isSubschemaDn(s.schema, dn) {
+		return s.searchSubschema(sr.SearchScope(), filter, sel, sr.AttrsOnly())
+	}
+
+	if sr.MaxTime() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(sr.MaxTime()))
+		defer cancel()
+	}
+
+	res, err := ScheduleAwait(s.scheduler, func(dit d.DIT) (searchResult, error) {
+		// the entries matched before the time limit are still returned
+		matched, err := dit.SearchContext(ctx, dn, sr.SearchScope(), filter)
+		if err != nil && !errors.Is(err, d.NewLdapError(d.TimeLimitExceeded, nil, "")) {
+			return searchResult{}, err
+		}
+
+		res := searchResult{entries: []SearchEntry{}, err: err}
+		for _, e := range matched {
+			if sr.MaxEntries() > 0 && len(res.entries) == sr.MaxEntries() {
+				res.err = d.NewLdapError(d.SizeLimitExceeded, nil, "search matched more than %d entries", sr.MaxEntries())
+				break
+			}
+
+			res.entries = append(res.entries, newSearchEntry(e, sel, sr.AttrsOnly()))
+		}
+
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.entries, res.err
+}
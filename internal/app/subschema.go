@@ -0,0 +1,121 @@
+package app
+
+import (
+	"bytes"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+// the subschema subentry holding the schema that governs every entry (RFC 4512 4.2)
+const subschemaDn = "cn=Subschema"
+
+func isSubschemaDn(schema *d.Schema, dn d.DN) bool {
+	subschema, err := d.NormaliseDN(schema, subschemaDn)
+	return err == nil && d.CompareDNs(dn, subschema)
+}
+
+// The subschema subentry has no subordinates, so a search under it can only
+// return the subentry itself
+func (s *SearchService) searchSubschema(scope d.SearchScope, filter d.Filter, sel attrSelection, typesOnly bool) ([]SearchEntry, error) {
+	if scope == d.SingleLevel {
+		return []SearchEntry{}, nil
+	}
+
+	dn, err := d.NormaliseDN(s.schema, subschemaDn)
+	if err != nil {
+		return nil, err
+	}
+
+	return ScheduleAwait(s.scheduler, func(dit d.DIT) ([]SearchEntry, error) {
+		e := s.schema.SubschemaEntry(dn)
+		if filter.Evaluate(e) != d.FilterTrue {
+			return []SearchEntry{}, nil
+		}
+		return []SearchEntry{newSearchEntry(e, sel, typesOnly)}, nil
+	})
+}
+
+type schemaLdif struct {
+	attrs, ocs bytes.Buffer
+}
+
+// Adds attribute types and object classes to the schema or marks them
+// obsolete. Only values can be added or deleted, deleting a value and adding
+// it back with OBSOLETE is how a definition is marked obsolete. Only
+// authenticated clients can modify the schema.
+func (m *ModifyService) modifySubschema(bound *d.Entry, mods []Modification) error {
+	if bound == nil {
+		return d.NewLdapError(d.InsufficientAccessRights, nil, "the subschema can only be modified by an authenticated client")
+	}
+
+	sm := d.SchemaModification{}
+	for _, mod := range mods {
+		attr, ok := m.schema.FindAttribute(mod.Attribute())
+		if !ok {
+			return d.NewLdapError(d.NoSuchAttribute, nil, "could not find attr: %q", mod.Attribute())
+		}
+
+		var add, del *[]string
+		switch attr {
+		case d.AttributeTypesAttribute:
+			add, del = &sm.AddAttrs, &sm.DeleteAttrs
+		case d.ObjectClassesAttribute:
+			add, del = &sm.AddObjClasses, &sm.DeleteObjClasses
+		default:
+			return d.NewLdapError(d.UnwillingToPerform, nil, "only attributeTypes and objectClasses of the subschema can be modified")
+		}
+
+		switch mod.ModOp() {
+		case ModifyAdd:
+			*add = append(*add, mod.Vals()...)
+		case ModifyDelete:
+			if len(mod.Vals()) == 0 {
+				return d.NewLdapError(d.UnwillingToPerform, nil, "every %s value cannot be deleted", attr.Name())
+			}
+			*del = append(*del, mod.Vals()...)
+		case ModifyReplace:
+			return d.NewLdapError(d.UnwillingToPerform, nil, "%s cannot be replaced, delete and add the values that change instead", attr.Name())
+		default:
+			return d.NewLdapError(d.ProtocolError, nil, "unknown modification operation type: %d", mod.ModOp())
+		}
+	}
+
+	m.schemaMu.Lock()
+	defer m.schemaMu.Unlock()
+
+	if m.persistSchema != nil {
+		ldif, err := ScheduleAwait(m.scheduler, func(dit d.DIT) (schemaLdif, error) {
+			change, err := d.NewSchemaChange(m.schema, sm)
+			if err != nil {
+				return schemaLdif{}, err
+			}
+
+			var ldif schemaLdif
+			err = m.schema.WriteLdifWithChange(change, &ldif.attrs, &ldif.ocs)
+			return ldif, err
+		})
+		if err != nil {
+			return err
+		}
+
+		// written outside of the scheduler so that other operations are not
+		// held up by the disk
+		if err := m.persistSchema(ldif.attrs.Bytes(), ldif.ocs.Bytes()); err != nil {
+			return d.NewLdapError(d.OperationsError, nil, "could not persist the schema: %s", err)
+		}
+	}
+
+	// checked again in the scheduler so that no other operation runs between
+	// checking the change and applying it. Only subschema modifications
+	// change the schema and they hold schemaMu, so this is the change that
+	// was persisted.
+	return ScheduleAwaitError(m.scheduler, func(dit d.DIT) error {
+		change, err := d.NewSchemaChange(m.schema, sm)
+		if err != nil {
+			return err
+		}
+
+		m.schema.Apply(change)
+		return nil
+	})
+}
@@ -18,22 +18,88 @@ var (
 		SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.38")), 0).
 		Build()
 	// TODO
-	// creatorsName
-	// createTimestamp
-	// modifiersName
-	// modifyTimestamp
 	// struturalObjectClass
 	// governingStructureRule
 
 	// altServer
-	// namingContexts
-	// supportedControl
-	// supportedExtensions
-	// supportedFeatures
-	// supportedLDAPVersion
-	// supportedSASLMechanism
 )
 
+// root DSE attributes (RFC 4512 5.1)
+var NamingContextsAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.1466.101.120.5").
+	AddNames("namingContexts").
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.12")), 0).
+	SetUsage(DsaOperation).
+	Build()
+
+var SupportedControlAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.1466.101.120.13").
+	AddNames("supportedControl").
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.38")), 0).
+	SetUsage(DsaOperation).
+	Build()
+
+var SupportedExtensionAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.1466.101.120.7").
+	AddNames("supportedExtension").
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.38")), 0).
+	SetUsage(DsaOperation).
+	Build()
+
+var SupportedFeaturesAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.4203.1.3.5").
+	AddNames("supportedFeatures").
+	SetEqRule(util.Unwrap(GetMatchingRule("objectIdentifierMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.38")), 0).
+	SetUsage(DsaOperation).
+	Build()
+
+var SupportedLDAPVersionAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.1466.101.120.15").
+	AddNames("supportedLDAPVersion").
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.27")), 0).
+	SetUsage(DsaOperation).
+	Build()
+
+var SupportedSASLMechanismsAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.1466.101.120.14").
+	AddNames("supportedSASLMechanisms").
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.15")), 0).
+	SetUsage(DsaOperation).
+	Build()
+
+var SubschemaSubentryAttribute = NewAttributeBuilder().
+	SetOid("2.5.18.10").
+	AddNames("subschemaSubentry").
+	SetEqRule(util.Unwrap(GetMatchingRule("distinguishedNameMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.12")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+// attributes the server provides itself, which every schema has
+var operationalAttributes = []*Attribute{
+	NamingContextsAttribute,
+	SupportedControlAttribute,
+	SupportedExtensionAttribute,
+	SupportedFeaturesAttribute,
+	SupportedLDAPVersionAttribute,
+	SupportedSASLMechanismsAttribute,
+	SubschemaSubentryAttribute,
+	AttributeTypesAttribute,
+	ObjectClassesAttribute,
+	LdapSyntaxesAttribute,
+	MatchingRulesAttribute,
+	MatchingRuleUseAttribute,
+	CreatorsNameAttribute,
+	CreateTimestampAttribute,
+	ModifiersNameAttribute,
+	ModifyTimestampAttribute,
+	EntryUUIDAttribute,
+	EntryDNAttribute,
+}
+
 type UsageType int
 
 const (
@@ -67,7 +133,7 @@ func (u UsageType) String() string {
 	case DistributedOperation:
 		return "distributedOperation"
 	case DsaOperation:
-		return "dsaOperation"
+		return "dSAOperation"
 	default:
 		return "unknown usage"
 	}
@@ -225,6 +291,16 @@ func (a *Attribute) HasName(name string) bool {
 	return ok
 }
 
+// Returns true if sup is somewhere in a's chain of sups
+func (a *Attribute) IsSubtypeOf(sup *Attribute) bool {
+	for s := a.sup; s != nil; s = s.sup {
+		if s == sup {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *Attribute) Syntax() (Syntax, int, bool) {
 	var zero Syntax
 	for a != nil {
@@ -249,10 +325,40 @@ func (a *Attribute) EqRule() (MatchingRule, bool) {
 	return zero, false
 }
 
+func (a *Attribute) OrdRule() (MatchingRule, bool) {
+	var zero MatchingRule
+	for a != nil {
+		if !a.ordRule.Eq(zero) {
+			return a.ordRule, true
+		}
+		a = a.sup
+	}
+	return zero, false
+}
+
+func (a *Attribute) SubStrRule() (MatchingRule, bool) {
+	var zero MatchingRule
+	for a != nil {
+		if !a.subStrRule.Eq(zero) {
+			return a.subStrRule, true
+		}
+		a = a.sup
+	}
+	return zero, false
+}
+
 func (a *Attribute) SingleVal() bool {
 	return a.singleVal
 }
 
+func (a *Attribute) NoUserMod() bool {
+	return a.noUserMod
+}
+
+func (a *Attribute) Usage() UsageType {
+	return a.usage
+}
+
 func (a *Attribute) String() string {
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "Numericoid: %q\n", string(a.numericoid))
@@ -381,10 +487,19 @@ func ParseAttributes(r io.Reader) (map[OID]*Attribute, error) {
 type LdifAttrResolver struct {
 	ldif  []*LdifAttribute
 	attrs map[OID]*Attribute
+	// sups that are not being parsed are looked up in schema if not nil
+	schema *Schema
 }
 
 func NewLdifAttrResolver(ldifs []*LdifAttribute) *LdifAttrResolver {
-	return &LdifAttrResolver{ldifs, map[OID]*Attribute{}}
+	return &LdifAttrResolver{ldif: ldifs, attrs: map[OID]*Attribute{}}
+}
+
+// Resolves sups against the attributes already in schema as well as the ones
+// being parsed
+func (r *LdifAttrResolver) WithSchema(schema *Schema) *LdifAttrResolver {
+	r.schema = schema
+	return r
 }
 
 func (r *LdifAttrResolver) Resolve() (map[OID]*Attribute, error) {
@@ -420,6 +535,12 @@ func (r *LdifAttrResolver) GetSup(nameOrOid string) (*Attribute, error) {
 		}
 	}
 
+	if r.schema != nil {
+		if a, ok := r.schema.FindAttribute(nameOrOid); ok {
+			return a, nil
+		}
+	}
+
 	return nil, fmt.Errorf("could not find attr sup %q", nameOrOid)
 }
 
@@ -614,7 +735,7 @@ func (a *LdifAttribute) setDesc(t *Tokeniser) error {
 	if err != nil {
 		return err
 	}
-	a.desc = stripQuotes(desc.val)
+	a.desc = unescapeQdstring(stripQuotes(desc.val))
 	return nil
 }
 
@@ -49,6 +49,11 @@ func NewDIT(root *DITNode) *DIT {
 	return &DIT{root}
 }
 
+// The dn of the entry at the root of the DIT
+func (d *DIT) RootDN() DN {
+	return d.root.entry.Dn()
+}
+
 // TODO is returning a to an entry dangers? (yes?)
 func (d *DIT) GetEntry(dn DN) (*Entry, error) {
 	logger.Printf("getting entry: %s", dn)
@@ -60,7 +65,12 @@ func (d *DIT) GetEntry(dn DN) (*Entry, error) {
 	return node.entry, nil
 }
 
-func (d *DIT) InsertEntry(dn DN, entry *Entry) error {
+// Inserts entry at dn, stamping it as created by s
+func (d *DIT) InsertEntry(dn DN, entry *Entry, s ChangeStamp) error {
+	if len(dn.rdns) == 0 {
+		return NewLdapError(UnwillingToPerform, nil, "cannot add an entry with an empty dn")
+	}
+
 	pDn := dn.GetParentDN()
 	pNode, err := d.getNode(pDn)
 	if err != nil {
@@ -74,13 +84,15 @@ func (d *DIT) InsertEntry(dn DN, entry *Entry) error {
 	}
 
 	entry.dn = dn.Clone()
+	entry.stampCreated(s)
 	pNode.AddChild(entry)
 
 	logger.Printf("added entry: %s", entry)
 	return nil
 }
 
-func (d *DIT) ModifyEntry(dn DN, ops ...ChangeOperation) error {
+// Applies ops to the entry at dn, stamping it as modified by s
+func (d *DIT) ModifyEntry(dn DN, s ChangeStamp, ops ...ChangeOperation) error {
 	node, err := d.getNode(dn)
 	if err != nil {
 		return err
@@ -94,45 +106,83 @@ func (d *DIT) ModifyEntry(dn DN, ops ...ChangeOperation) error {
 		}
 	}
 
+	entry.stampModified(s)
 	node.entry = entry
 	logger.Printf("modified entry: %s", node.entry)
 	return nil
 }
 
-func (d *DIT) ModifyEntryDN(dn DN, rdn RDN, deleteOldRDN bool, newSuperiorDN *DN) error {
+// Renames the entry at dn and moves it under newSuperiorDN if given, stamping
+// it as modified by s. Like ModifyEntry the renamed entry is a clone that
+// replaces the old one, and its subtree moves along with it.
+func (d *DIT) ModifyEntryDN(dn DN, rdn RDN, deleteOldRDN bool, newSuperiorDN *DN, s ChangeStamp) error {
 	curr, err := d.getNode(dn)
 	if err != nil {
 		return err
 	}
 
-	curr.entry.SetRDN(rdn, deleteOldRDN)
-
-	// nothing else todo if not moving the node
-	if newSuperiorDN == nil {
-		return nil
+	newParent := curr.parent
+	if newSuperiorDN != nil {
+		if curr.parent == nil {
+			return NewLdapError(UnwillingToPerform, nil, "cannot move the root entry")
+		}
+		if newParent, err = d.getNode(*newSuperiorDN); err != nil {
+			logger.Printf("could not find parent node at: %s", newSuperiorDN)
+			// TODO do i need to wrap this so i know it's a different notfound/nosuchobject?
+			return err
+		}
 	}
 
-	newParent, err := d.getNode(*newSuperiorDN)
-	if err != nil {
-		logger.Printf("could not find parent node at: %s", newSuperiorDN)
-		// TODO do i need to wrap this so i know it's a different notfound/nosuchobject?
-		return err
+	for n := newParent; n != nil; n = n.parent {
+		if n == curr {
+			return NewLdapError(UnwillingToPerform, nil, "cannot move an entry under itself")
+		}
 	}
 
-	currParent := curr.parent
-	newDn := newSuperiorDN.Clone()
+	newDn := DN{}
+	if newParent != nil {
+		newDn = newParent.entry.dn.Clone()
+	}
 	newDn.AddRDN(rdn)
-	entry := curr.entry
+
+	// checked before anything changes, an entry can be renamed to its own dn
+	// to change how its rdn is written
+	if existing, err := d.getNode(newDn); err == nil && existing != curr {
+		return NewLdapError(EntryAlreadyExists, nil, "an entry already exists at %s", newDn.String())
+	}
+
+	entry := curr.entry.Clone()
+	if err := entry.SetRDN(rdn, deleteOldRDN); err != nil {
+		return err
+	}
 	entry.dn = newDn
+	entry.stampModified(s)
 
-	currParent.DeleteChild(curr)
-	newParent.AddChild(entry)
+	if newParent != curr.parent {
+		curr.parent.DeleteChild(curr)
+		curr.parent = newParent
+		newParent.AddChildNode(curr)
+	}
 
-	logger.Printf("modified entry dn: %s", entry)
+	curr.entry = entry
+	rebaseDescendants(curr)
 
+	logger.Printf("modified entry dn: %s", entry)
 	return nil
 }
 
+// Gives the descendants of n dns under n's dn, each as a clone that replaces
+// the old entry
+func rebaseDescendants(n *DITNode) {
+	for c := range n.children {
+		entry := c.entry.Clone()
+		entry.dn = n.entry.dn.Clone()
+		entry.dn.AddRDN(c.entry.dn.GetRDN().Clone())
+		c.entry = entry
+		rebaseDescendants(c)
+	}
+}
+
 func (d *DIT) DeleteEntry(dn DN) error {
 	node, err := d.getNode(dn)
 	if err != nil {
@@ -143,9 +193,28 @@ func (d *DIT) DeleteEntry(dn DN) error {
 		return ErrNodeNotLeaf
 	}
 
-	p := node.parent
-	delete(p.children, node)
+	if node.parent == nil {
+		return NewLdapError(UnwillingToPerform, nil, "cannot delete the root entry")
+	}
+
+	node.parent.DeleteChild(node)
+	return nil
+}
+
+// Deletes the entry at dn and all of its descendants. The subtree is removed
+// by unlinking it from its parent, so it is all gone at once.
+func (d *DIT) DeleteSubtree(dn DN) error {
+	node, err := d.getNode(dn)
+	if err != nil {
+		return err
+	}
+
+	if node.parent == nil {
+		return NewLdapError(UnwillingToPerform, nil, "cannot delete the root entry")
+	}
 
+	node.parent.DeleteChild(node)
+	logger.Printf("deleted subtree at: %s", dn.String())
 	return nil
 }
 
@@ -159,12 +228,16 @@ func (d *DIT) ContainsAttribute(dn DN, attr *Attribute, val string) (bool, error
 }
 
 func (d *DIT) getNode(dn DN) (*DITNode, error) {
+	if len(dn.rdns) == 0 {
+		return nil, NewLdapError(NoSuchObject, nil, "no object found for the empty dn")
+	}
+
 	node, err := getNodeRecursive(dn.rdns, d.root)
 
 	var nfErr *NodeNotFoundError
 	if errors.As(err, &nfErr) {
 		nfErr.RequestedDN = dn
-		return nil, NewLdapError(NoSuchObject, &nfErr.MatchedDN, "no object found for requested dn %s", nfErr.RequestedDN)
+		return nil, NewLdapError(NoSuchObject, &nfErr.MatchedDN, "no object found for requested dn %s", &nfErr.RequestedDN)
 	} else if err != nil {
 		return nil, err
 	}
@@ -187,8 +260,8 @@ func getNodeRecursive(rdns []RDN, node *DITNode) (*DITNode, error) {
 		return node, nil
 	}
 
-	var finalErr error
-	var nfErr *NodeNotFoundError
+	// a leaf or a node without a matching child has matched up to itself
+	nfErr := &NodeNotFoundError{}
 
 	for c := range node.children {
 		n, err := getNodeRecursive(rdns[1:], c)
@@ -197,16 +270,17 @@ func getNodeRecursive(rdns []RDN, node *DITNode) (*DITNode, error) {
 			return n, nil
 		}
 
-		if !errors.As(err, &nfErr) {
+		var childErr *NodeNotFoundError
+		if !errors.As(err, &childErr) {
 			return nil, err
 		}
 
-		finalErr = err
+		// keep the error from the child that matched the most
+		if len(childErr.MatchedDN.rdns) > len(nfErr.MatchedDN.rdns) {
+			nfErr = childErr
+		}
 	}
 
-	// at this point err can only be not found
-	_ = errors.As(finalErr, &nfErr)
-
 	// prepend this rdn to the matched rdn
 	nfErr.prependMatchedDn(rdns[0])
 	return nil, nfErr
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"errors"
 	"os"
 
@@ -10,6 +11,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/georgib0y/relientldap/internal/util"
 )
@@ -151,7 +153,7 @@ func TestInsertEntryPutsEntryInTreeWithRdnAtt(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = dit.InsertEntry(dn, entry)
+	err = dit.InsertEntry(dn, entry, NewChangeStamp(DN{}))
 	if err != nil {
 		t.Fatalf("Error inserting new entry: %s", err)
 	}
@@ -215,11 +217,27 @@ func TestDeleteEntryFailsOnNonLeafNode(t *testing.T) {
 	}
 }
 
+func TestDeleteSubtreeDeletesDescendants(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+	ouDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").Build()
+	childDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").AddAvaAsRdn(attrs["cn"], "Test2").Build()
+
+	if err := dit.DeleteSubtree(ouDn); err != nil {
+		t.Fatal("Error deleting subtree: ", err)
+	}
+
+	for _, dn := range []DN{ouDn, childDn} {
+		if _, err := dit.GetEntry(dn); !errors.Is(err, NewLdapError(NoSuchObject, nil, "")) {
+			t.Fatalf("Expected ldap nosuchobject error getting deleted entry %s, got: %v", dn.String(), err)
+		}
+	}
+}
+
 func TestModifyAddEntryAddsAttributes(t *testing.T) {
 	dit := GenerateTestDIT(schema)
 	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
 
-	if err := dit.ModifyEntry(dn, AddOperation(attrs["facsimileTelephoneNumber"], "12345")); err != nil {
+	if err := dit.ModifyEntry(dn, NewChangeStamp(DN{}), AddOperation(attrs["facsimileTelephoneNumber"], "12345")); err != nil {
 		t.Fatal("Got error when adding entry: ", err)
 	}
 
@@ -237,7 +255,7 @@ func TestModifyDeleteSingleEntryDeletesAttribute(t *testing.T) {
 	dit := GenerateTestDIT(schema)
 	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
 
-	if err := dit.ModifyEntry(dn, DeleteOperation(attrs["sn"], "One-Two")); err != nil {
+	if err := dit.ModifyEntry(dn, NewChangeStamp(DN{}), DeleteOperation(attrs["sn"], "One-Two")); err != nil {
 		t.Fatal("Got error when deleting entry: ", err)
 	}
 
@@ -264,7 +282,7 @@ func TestModifyDeleteAllEntryDeletesAttributes(t *testing.T) {
 	dit := GenerateTestDIT(schema)
 	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
 
-	if err := dit.ModifyEntry(dn, DeleteOperation(attrs["sn"])); err != nil {
+	if err := dit.ModifyEntry(dn, NewChangeStamp(DN{}), DeleteOperation(attrs["sn"])); err != nil {
 		t.Fatal("Got error when deleting entry: ", err)
 	}
 
@@ -291,7 +309,7 @@ func TestModifyReplaceReplacesAttributes(t *testing.T) {
 	dit := GenerateTestDIT(schema)
 	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
 
-	if err := dit.ModifyEntry(dn, ReplaceOperation(attrs["sn"], "Three", "Three-Four")); err != nil {
+	if err := dit.ModifyEntry(dn, NewChangeStamp(DN{}), ReplaceOperation(attrs["sn"], "Three", "Three-Four")); err != nil {
 		t.Fatal("Got error when replacing entry: ", err)
 	}
 
@@ -332,6 +350,48 @@ func TestModifyReplaceReplacesAttributes(t *testing.T) {
 	}
 }
 
+func TestInsertAndModifyEntryStampOperationalAttributes(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+	creator := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
+	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "New Object").Build()
+
+	entry := util.Unwrap(NewEntry(schema, dn,
+		WithStructural(objClasses["person"]),
+		WithEntryAttr(attrs["sn"], "Object"),
+	))
+
+	created := ChangeStamp{By: creator, At: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	if err := dit.InsertEntry(dn, entry, created); err != nil {
+		t.Fatal("Error inserting new entry: ", err)
+	}
+
+	modified := ChangeStamp{By: DN{}, At: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	if err := dit.ModifyEntry(dn, modified, AddOperation(attrs["sn"], "Other")); err != nil {
+		t.Fatal("Error modifying entry: ", err)
+	}
+
+	entry = util.Unwrap(dit.GetEntry(dn))
+
+	expAttrs := map[*Attribute]string{
+		CreatorsNameAttribute:    "cn=Test1,dc=georgiboy,dc=dev",
+		CreateTimestampAttribute: "20240101120000Z",
+		ModifiersNameAttribute:   "",
+		ModifyTimestampAttribute: "20240601120000Z",
+		EntryDNAttribute:         "cn=New Object,dc=georgiboy,dc=dev",
+	}
+
+	for attr, exp := range expAttrs {
+		if vals, _ := entry.AttrVals(attr); len(vals) != 1 || vals[0] != exp {
+			t.Errorf("Expected %s to be %q, got %v", attr.Name(), exp, vals)
+		}
+	}
+
+	uuid, _ := entry.AttrVals(EntryUUIDAttribute)
+	if len(uuid) != 1 || validateUUID(uuid[0]) != nil {
+		t.Errorf("Expected a single entryUUID, got %v", uuid)
+	}
+}
+
 /*
 Transform this DIT Structue:
 | dc=dev
@@ -366,7 +426,7 @@ func TestModifyDNChangesRDNDeletesOldRDNAndMovesEntry(t *testing.T) {
 	WriteNodeDescendants(&sb1, dit.root)
 	t.Log(sb1.String())
 
-	err := dit.ModifyEntryDN(dn, rdn, true, &newSuperDn)
+	err := dit.ModifyEntryDN(dn, rdn, true, &newSuperDn, NewChangeStamp(DN{}))
 	if err != nil {
 		t.Fatal("Failed to modify dn: ", err)
 	}
@@ -393,6 +453,84 @@ func TestModifyDNChangesRDNDeletesOldRDNAndMovesEntry(t *testing.T) {
 	entry.ContainsAttrVal(attrs["givenName"], "Test1Moved")
 }
 
+func TestModifyDNMovesSubtreeWithoutChangingOldEntries(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+
+	ouDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").Build()
+	childDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").AddAvaAsRdn(attrs["cn"], "Test2").Build()
+	newSuperDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev").Build()
+
+	old := util.Unwrap(dit.GetEntry(ouDn))
+	oldAttrs := len(old.attrs)
+
+	rdn := NewRDN(WithAVA(attrs["ou"], "Moved"))
+	if err := dit.ModifyEntryDN(ouDn, rdn, false, &childDn, NewChangeStamp(DN{})); err == nil {
+		t.Fatal("Expected an error moving an entry under itself")
+	}
+	if err := dit.ModifyEntryDN(ouDn, rdn, true, &newSuperDn, NewChangeStamp(DN{})); err != nil {
+		t.Fatal("Failed to modify dn: ", err)
+	}
+
+	if !CompareDNs(old.dn, ouDn) || len(old.attrs) != oldAttrs {
+		t.Errorf("Expected the entry from before the move not to change, got %s", old)
+	}
+
+	movedChildDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev").AddAvaAsRdn(attrs["ou"], "Moved").AddAvaAsRdn(attrs["cn"], "Test2").Build()
+	child, err := dit.GetEntry(movedChildDn)
+	if err != nil {
+		t.Fatal("Expected the subtree to move with the entry: ", err)
+	}
+	if !CompareDNs(child.dn, movedChildDn) {
+		t.Errorf("Expected the moved child to have dn %s, got %s", movedChildDn, child.dn)
+	}
+
+	if _, err := dit.GetEntry(childDn); err == nil {
+		t.Errorf("Expected nothing at the old dn of the child")
+	}
+}
+
+func TestModifyDNFailsWhenTargetExists(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+
+	test1Dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
+	ouDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").Build()
+	test2Dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").AddAvaAsRdn(attrs["cn"], "Test2").Build()
+	test3Dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["ou"], "TestOu").AddAvaAsRdn(attrs["cn"], "Test3").Build()
+
+	tests := []struct {
+		name        string
+		dn          DN
+		rdn         RDN
+		newSuperior *DN
+	}{
+		{name: "renaming to a sibling", dn: test2Dn, rdn: NewRDN(WithAVA(attrs["cn"], "Test3"))},
+		{name: "moving onto an entry", dn: test1Dn, rdn: NewRDN(WithAVA(attrs["cn"], "Test2")), newSuperior: &ouDn},
+	}
+
+	for _, test := range tests {
+		err := dit.ModifyEntryDN(test.dn, test.rdn, true, test.newSuperior, NewChangeStamp(DN{}))
+		if !errors.Is(err, NewLdapError(EntryAlreadyExists, nil, "")) {
+			t.Errorf("%s: expected entryAlreadyExists, got %v", test.name, err)
+		}
+	}
+
+	// nothing changed
+	for _, dn := range []DN{test1Dn, test2Dn, test3Dn} {
+		entry, err := dit.GetEntry(dn)
+		if err != nil {
+			t.Fatalf("expected %s to be unchanged: %s", dn.String(), err)
+		}
+		if !CompareDNs(entry.dn, dn) {
+			t.Errorf("expected %s to keep its dn, got %s", dn.String(), entry.dn.String())
+		}
+	}
+
+	// an entry can be renamed to its own dn
+	if err := dit.ModifyEntryDN(test2Dn, NewRDN(WithAVA(attrs["cn"], "TEST2")), true, nil, NewChangeStamp(DN{})); err != nil {
+		t.Errorf("could not rename an entry to its own dn: %s", err)
+	}
+}
+
 func TestSearchBaseObject(t *testing.T) {
 	dit := GenerateTestDIT(schema)
 
@@ -507,3 +645,67 @@ func TestSearchWholeSubtree(t *testing.T) {
 		t.Fatalf("expected 3 results, got %d", len(res))
 	}
 }
+
+// matches every entry, taking delay to do so
+type slowFilter struct {
+	delay time.Duration
+}
+
+func (f slowFilter) Evaluate(e *Entry) FilterResult {
+	time.Sleep(f.delay)
+	return FilterTrue
+}
+
+func TestSearchContextStopsAtDeadline(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+	baseDn := NewDnBuilder().
+		AddNamingContext(attrs["dc"], "dev", "georgiboy").
+		Build()
+
+	// the base entry is matched before the deadline, which has passed by the
+	// time the walk reaches the next entry
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res, err := dit.SearchContext(ctx, baseDn, WholeSubtree, slowFilter{100 * time.Millisecond})
+	if !errors.Is(err, NewLdapError(TimeLimitExceeded, nil, "")) {
+		t.Fatalf("expected a time limit error, got %v", err)
+	}
+
+	if len(res) != 1 || !CompareDNs(res[0].Dn(), baseDn) {
+		t.Fatalf("expected only the base entry to be matched, got %v", res)
+	}
+
+	res, err = dit.SearchContext(context.Background(), baseDn, WholeSubtree, slowFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 5 {
+		t.Fatalf("expected 5 results without a deadline, got %d", len(res))
+	}
+}
+
+// cancels the search once it has been evaluated against an entry
+type cancellingFilter struct {
+	cancel context.CancelFunc
+}
+
+func (f cancellingFilter) Evaluate(e *Entry) FilterResult {
+	f.cancel()
+	return FilterTrue
+}
+
+func TestSearchContextStopsWhenCancelled(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+	baseDn := NewDnBuilder().
+		AddNamingContext(attrs["dc"], "dev", "georgiboy").
+		Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res, err := dit.SearchContext(ctx, baseDn, WholeSubtree, cancellingFilter{cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the search to be cancelled, got %v", err)
+	}
+	if len(res) != 0 {
+		t.Fatalf("expected no results from a cancelled search, got %v", res)
+	}
+}
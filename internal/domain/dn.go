@@ -1,8 +1,12 @@
 package domain
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 type (
@@ -74,12 +78,16 @@ func CompareRDNs(r1, r2 *RDN) bool {
 	return true
 }
 
+// Multi-valued rdns are sorted so that the same rdn always prints the same
 func (r RDN) String() string {
 	avas := []string{}
 	for attr, val := range r.avas {
-		ava := attr.Name() + "=" + val
+		ava := attr.Name() + "=" + escapeDnValue(val)
 		avas = append(avas, ava)
 	}
+	slices.SortFunc(avas, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
 
 	return strings.Join(avas, "+")
 }
@@ -173,6 +181,11 @@ func (dn *DN) GetRDN() *RDN {
 	return &dn.rdns[len(dn.rdns)-1]
 }
 
+// The empty dn names the root DSE rather than an entry in the DIT
+func (dn DN) IsEmpty() bool {
+	return len(dn.rdns) == 0
+}
+
 func (dn DN) GetParentDN() DN {
 	return DN{dn.rdns[:len(dn.rdns)-1]}
 }
@@ -189,50 +202,278 @@ func (d *DN) String() string {
 	return strings.Join(rdns, ",")
 }
 
-func attrValFromStr(schema *Schema, s string) (*Attribute, string, error) {
-	spl := strings.Split(s, "=")
-	// TODO could be wrong
-	if len(spl) != 2 {
-		// TODO should technically be providing a matched dn here but to hard
-		return nil, "", NewLdapError(InvalidDnSyntax, nil, "malformed ava: %s", s)
+// Resolves the parsed avas of an rdn against the schema. Values in the
+// #hexstring form are decoded from their BER encoding.
+func normaliseAvas(schema *Schema, avas []dnAva) (RDN, error) {
+	rdn := NewRDN()
+	for _, ava := range avas {
+		attr, ok := schema.FindAttribute(ava.attrType)
+		if !ok {
+			return RDN{}, NewLdapError(UndefinedAttributeType, nil, "unknown attribute %q", ava.attrType)
+		}
+
+		if _, ok := rdn.avas[attr]; ok {
+			return RDN{}, NewLdapError(InvalidDnSyntax, nil, "attribute %q appears more than once in rdn", ava.attrType)
+		}
+
+		val := ava.value
+		if ava.isHex {
+			var err error
+			if val, err = decodeBerValue(val[1:]); err != nil {
+				return RDN{}, err
+			}
+		}
+
+		rdn.avas[attr] = val
+	}
+
+	return rdn, nil
+}
+
+// Decodes the hexstring form of a value, which is the BER encoding of the
+// value. Only primitive types with single byte tags are supported.
+func decodeBerValue(hexstr string) (string, error) {
+	b, err := hex.DecodeString(hexstr)
+	if err != nil || len(b) < 2 {
+		return "", NewLdapError(InvalidDnSyntax, nil, "invalid hexstring value #%s", hexstr)
 	}
 
-	attr, ok := schema.FindAttribute(strings.TrimSpace(spl[0]))
-	if !ok {
-		// return nil, "", fmt.Errorf("unknown attribute %q", strings.TrimSpace(spl[0]))
-		return nil, "", NewLdapError(UndefinedAttributeType, nil, "unknown attribute %q", strings.TrimSpace(spl[0]))
+	if b[0]&0x1f == 0x1f || b[0]&0x20 != 0 {
+		return "", NewLdapError(InvalidDnSyntax, nil, "unsupported BER type in hexstring value #%s", hexstr)
 	}
 
-	return attr, spl[1], nil
+	length, content := int(b[1]), b[2:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || n > len(content) {
+			return "", NewLdapError(InvalidDnSyntax, nil, "invalid BER length in hexstring value #%s", hexstr)
+		}
+
+		length = 0
+		for _, l := range content[:n] {
+			length = length<<8 | int(l)
+		}
+		content = content[n:]
+	}
+
+	if length != len(content) {
+		return "", NewLdapError(InvalidDnSyntax, nil, "invalid BER length in hexstring value #%s", hexstr)
+	}
+
+	return string(content), nil
 }
 
-// TODO this is definitely not a complete DN parser, though probs good enough for now
+// Parses an RDN string such as cn=Test+uid=1 (RFC 4514)
 func NormaliseRDN(schema *Schema, s string) (RDN, error) {
-	rdnOpts := []RDNOption{}
-	avas := strings.Split(s, "+")
-	for _, ava := range avas {
-		a, v, err := attrValFromStr(schema, ava)
-		if err != nil {
-			return RDN{}, err
-		}
-		rdnOpts = append(rdnOpts, WithAVA(a, v))
+	avas, err := parseRdnString(s, s, 0)
+	if err != nil {
+		return RDN{}, err
 	}
 
-	return NewRDN(rdnOpts...), nil
+	return normaliseAvas(schema, avas)
 }
 
+// Parses an RFC 4514 DN string. The rdns are resolved from right to left so
+// if an rdn is invalid the rdns to the right of it are returned as the
+// matched dn.
 func NormaliseDN(schema *Schema, s string) (DN, error) {
 	b := NewDnBuilder()
-	rdns := strings.Split(s, ",")
-	slices.Reverse(rdns)
+	parts := splitDnString(s)
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		avas, err := parseRdnString(s, parts[i].s, parts[i].offset)
+		if err == nil {
+			var rdn RDN
+			if rdn, err = normaliseAvas(schema, avas); err == nil {
+				b.AddRdn(rdn)
+				continue
+			}
+		}
 
-	for _, spl := range rdns {
-		rdn, err := NormaliseRDN(schema, spl)
-		if err != nil {
+		matched := b.Build()
+		var lerr LdapError
+		if !errors.As(err, &lerr) {
 			return DN{}, err
 		}
-		b.AddRdn(rdn)
+		lerr.MatchedDN = &matched
+		return DN{}, lerr
 	}
 
 	return b.Build(), nil
 }
+
+// An attribute type and value as it appears in a DN string, before the type
+// has been resolved against a schema
+type dnAva struct {
+	attrType string
+	value    string
+	isHex    bool
+}
+
+func isDnSpecial(c byte) bool {
+	return strings.IndexByte(` "#+,;<=>\`, c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+type dnPart struct {
+	s      string
+	offset int
+}
+
+// Splits a DN string on the unescaped rdn separators without checking the
+// rdns, so that a DN can be split even if some of its rdns are invalid
+func splitDnString(s string) []dnPart {
+	parts := []dnPart{}
+	if strings.TrimSpace(s) == "" {
+		return parts
+	}
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',', ';':
+			parts = append(parts, dnPart{s[start:i], start})
+			start = i + 1
+		}
+	}
+
+	return append(parts, dnPart{s[start:], start})
+}
+
+// Splits an RFC 4514 DN string into its rdns, from left to right, unescaping
+// the values. Values in the #hexstring form are kept as they are.
+func parseDnString(s string) ([][]dnAva, error) {
+	rdns := [][]dnAva{}
+	for _, part := range splitDnString(s) {
+		rdn, err := parseRdnString(s, part.s, part.offset)
+		if err != nil {
+			return nil, err
+		}
+		rdns = append(rdns, rdn)
+	}
+
+	return rdns, nil
+}
+
+// Parses a single rdn that starts at offset in the dn string, the dn and
+// offset are only used for error messages
+func parseRdnString(dn, s string, offset int) ([]dnAva, error) {
+	invalid := func(pos int, msg string) error {
+		return NewLdapError(InvalidDnSyntax, nil, "invalid dn %q at position %d: %s", dn, offset+pos, msg)
+	}
+
+	rdn := []dnAva{}
+	i := 0
+	for {
+		// attribute type, surrounding spaces are tolerated
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' {
+			if !isDescChar(s[i]) || s[i] == ';' {
+				return nil, invalid(i, "unexpected character in attribute type")
+			}
+			i++
+		}
+		attrType := s[start:i]
+		if attrType == "" {
+			return nil, invalid(i, "missing attribute type")
+		}
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i == len(s) || s[i] != '=' {
+			return nil, invalid(i, "expected '='")
+		}
+		i++
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+
+		var sb strings.Builder
+		// the end of the value ignoring unescaped trailing spaces
+		end := 0
+		isHex := i < len(s) && s[i] == '#'
+		if isHex {
+			sb.WriteByte('#')
+			i++
+			for i < len(s) && isHexDigit(s[i]) {
+				sb.WriteByte(s[i])
+				i++
+			}
+			if sb.Len() == 1 || sb.Len()%2 == 0 {
+				return nil, invalid(i, "invalid hexstring value")
+			}
+			end = sb.Len()
+		}
+
+	value:
+		for i < len(s) {
+			switch c := s[i]; {
+			case c == '+':
+				break value
+			case c == '\\' && !isHex:
+				if i+1 < len(s) && isDnSpecial(s[i+1]) {
+					sb.WriteByte(s[i+1])
+					i += 2
+				} else if i+2 < len(s) && isHexDigit(s[i+1]) && isHexDigit(s[i+2]) {
+					b, _ := hex.DecodeString(s[i+1 : i+3])
+					sb.Write(b)
+					i += 3
+				} else {
+					return nil, invalid(i, "invalid escape")
+				}
+				end = sb.Len()
+			case c == '"' || c == '<' || c == '>' || c == '\\' || c == ',' || c == ';' || c == 0:
+				return nil, invalid(i, "character must be escaped")
+			case c == ' ':
+				sb.WriteByte(c)
+				i++
+			default:
+				if isHex {
+					return nil, invalid(i, "invalid hexstring value")
+				}
+				sb.WriteByte(c)
+				i++
+				end = sb.Len()
+			}
+		}
+
+		rdn = append(rdn, dnAva{attrType: attrType, value: sb.String()[:end], isHex: isHex})
+
+		if i == len(s) {
+			return rdn, nil
+		}
+		i++
+	}
+}
+
+// Escapes a value for use in a DN string (RFC 4514 2.4). Bytes that are not
+// valid UTF-8 are hex escaped.
+func escapeDnValue(v string) string {
+	var sb strings.Builder
+	for i := 0; i < len(v); {
+		r, size := utf8.DecodeRuneInString(v[i:])
+		c := v[i]
+		switch {
+		case r == utf8.RuneError && size == 1, c == 0:
+			fmt.Fprintf(&sb, "\\%02X", c)
+		case strings.IndexByte(`"+,;<>\`, c) >= 0,
+			i == 0 && (c == ' ' || c == '#'),
+			i == len(v)-1 && c == ' ':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteString(v[i : i+size])
+		}
+		i += size
+	}
+	return sb.String()
+}
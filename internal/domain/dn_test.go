@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormaliseDNRoundTrip(t *testing.T) {
+	tests := []struct {
+		dn, canonical string
+	}{
+		{"cn=Test1,dc=georgiboy,dc=dev", ""},
+		{"", ""},
+		{"CN = Test1 , DC=georgiboy;dc=dev", "cn=Test1,dc=georgiboy,dc=dev"},
+		{"2.5.4.3=Test1,dc=dev", "cn=Test1,dc=dev"},
+		{"cn=Smith\\, John,dc=example", ""},
+		{"cn=a=b,dc=example", ""},
+		{"cn=\\\"quoted\\\" \\<x\\>,dc=example", ""},
+		{"cn=\\#hash,dc=example", ""},
+		{"cn=\\ spaces\\ ,dc=example", ""},
+		{"cn=back\\\\slash\\+plus\\;semi,dc=example", ""},
+		{"cn=\\41\\42C,dc=example", "cn=ABC,dc=example"},
+		{"cn=\\C3\\A9,dc=example", "cn=\u00e9,dc=example"},
+		{"cn=#04024869,dc=example", "cn=Hi,dc=example"},
+		{"cn=#0C024869,dc=example", "cn=Hi,dc=example"},
+		{"uid=1+cn=Test,dc=example", "cn=Test+uid=1,dc=example"},
+		{"cn=Test+uid=1,dc=example", ""},
+		{"cn=\\00,dc=example", "cn=\\00,dc=example"},
+	}
+
+	for _, test := range tests {
+		dn, err := NormaliseDN(schema, test.dn)
+		if err != nil {
+			t.Errorf("could not normalise %q: %s", test.dn, err)
+			continue
+		}
+
+		exp := test.canonical
+		if exp == "" {
+			exp = test.dn
+		}
+
+		if s := dn.String(); s != exp {
+			t.Errorf("normalised %q to %q, expected %q", test.dn, s, exp)
+		}
+
+		again, err := NormaliseDN(schema, dn.String())
+		if err != nil {
+			t.Errorf("could not reparse %q: %s", dn.String(), err)
+			continue
+		}
+		if !CompareDNs(dn, again) {
+			t.Errorf("reparsing %q gave a different dn %q", dn.String(), again.String())
+		}
+	}
+}
+
+func TestNormaliseDNErrors(t *testing.T) {
+	tests := []struct {
+		dn      string
+		code    ResultCode
+		matched string
+	}{
+		{"cn", InvalidDnSyntax, ""},
+		{"cn=test,", InvalidDnSyntax, ""},
+		{",dc=dev", InvalidDnSyntax, "dc=dev"},
+		{"cn=Test,ou=a\\zz,dc=georgiboy,dc=dev", InvalidDnSyntax, "dc=georgiboy,dc=dev"},
+		{"cn=Test,ou=a\"b,dc=dev", InvalidDnSyntax, "dc=dev"},
+		{"cn=#0402486,dc=dev", InvalidDnSyntax, "dc=dev"},
+		{"cn=#04034869,dc=dev", InvalidDnSyntax, "dc=dev"},
+		{"cn=#0402486x,dc=dev", InvalidDnSyntax, "dc=dev"},
+		{"cn=a+cn=b,dc=dev", InvalidDnSyntax, "dc=dev"},
+		{"cn=a,unknownAttr=b,dc=dev", UndefinedAttributeType, "dc=dev"},
+	}
+
+	for _, test := range tests {
+		_, err := NormaliseDN(schema, test.dn)
+
+		var lerr LdapError
+		if !errors.As(err, &lerr) {
+			t.Errorf("expected an ldap error for %q, got: %v", test.dn, err)
+			continue
+		}
+
+		if lerr.ResultCode != test.code {
+			t.Errorf("expected %s for %q, got %s: %s", test.code, test.dn, lerr.ResultCode, lerr.DiagnosticMessage)
+		}
+
+		if matched := lerr.MatchedDN.String(); matched != test.matched {
+			t.Errorf("expected matched dn %q for %q, got %q", test.matched, test.dn, matched)
+		}
+	}
+}
+
+func TestNormaliseRDN(t *testing.T) {
+	rdn, err := NormaliseRDN(schema, "uid=1+cn=Smith\\, John")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := rdn.String(); s != "cn=Smith\\, John+uid=1" {
+		t.Errorf("unexpected rdn %q", s)
+	}
+
+	if _, err := NormaliseRDN(schema, "cn=a,dc=b"); err == nil {
+		t.Errorf("expected an error for an rdn with a separator")
+	}
+}
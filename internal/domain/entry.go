@@ -13,6 +13,9 @@ type Entry struct {
 	structural *ObjectClass
 	auxiliary  map[*ObjectClass]struct{}
 	attrs      map[*Attribute]map[string]struct{}
+	// the schema the entry was built against, used by the matching rules that
+	// resolve names. Nil for entries built directly in tests.
+	schema *Schema
 }
 
 type EntryOption func(*Entry)
@@ -39,10 +42,15 @@ func WithEntryAttr(attr *Attribute, val ...string) EntryOption {
 }
 
 func NewEntry(schema *Schema, dn DN, options ...EntryOption) (*Entry, error) {
+	if len(dn.rdns) == 0 {
+		return nil, NewLdapError(UnwillingToPerform, nil, "an entry cannot have an empty dn")
+	}
+
 	e := &Entry{
 		dn:        dn,
 		auxiliary: map[*ObjectClass]struct{}{},
 		attrs:     map[*Attribute]map[string]struct{}{},
+		schema:    schema,
 	}
 
 	for _, o := range options {
@@ -61,6 +69,28 @@ func NewEntry(schema *Schema, dn DN, options ...EntryOption) (*Entry, error) {
 	return e, nil
 }
 
+// Builds the root DSE (RFC 4512 5.1), which is not part of the DIT and so has
+// an empty dn and is not validated against the schema
+func NewRootDSE(schema *Schema, attrs map[*Attribute][]string) *Entry {
+	return newUncheckedEntry(schema, DN{}, attrs)
+}
+
+// Builds an entry the server provides itself, without validating it
+func newUncheckedEntry(schema *Schema, dn DN, attrs map[*Attribute][]string) *Entry {
+	e := &Entry{
+		dn:         dn,
+		structural: TopObjectClass,
+		auxiliary:  map[*ObjectClass]struct{}{},
+		attrs:      map[*Attribute]map[string]struct{}{},
+		schema:     schema,
+	}
+
+	for attr, vals := range attrs {
+		e.AddAttrUnsafe(attr, vals...)
+	}
+	return e
+}
+
 func (e *Entry) Dn() DN {
 	return e.dn
 }
@@ -71,6 +101,7 @@ func (e *Entry) Clone() *Entry {
 		structural: e.structural,
 		auxiliary:  util.CloneMap(e.auxiliary),
 		attrs:      util.CloneMapNested(e.attrs),
+		schema:     e.schema,
 	}
 }
 
@@ -110,10 +141,103 @@ func (e *Entry) ConatinsObjectClass(objClass *ObjectClass) bool {
 	return ok
 }
 
-// ContainsAttrVal will attempt to match based on the provided eq rule.
+// Returns the entry's object classes along with all of their sups
+func (e *Entry) ObjectClasses() map[*ObjectClass]struct{} {
+	ocs := map[*ObjectClass]struct{}{}
+
+	var addWithSups func(*ObjectClass)
+	addWithSups = func(oc *ObjectClass) {
+		if _, ok := ocs[oc]; ok {
+			return
+		}
+		ocs[oc] = struct{}{}
+		for _, sup := range oc.sups {
+			addWithSups(sup)
+		}
+	}
+
+	if e.structural != nil {
+		addWithSups(e.structural)
+	}
+	for oc := range e.auxiliary {
+		addWithSups(oc)
+	}
+
+	return ocs
+}
+
+// objectClass and entryDN are not stored with the rest of the attributes, so
+// their values are built from the entry's object classes and dn
+func (e *Entry) attrVals(attr *Attribute) (map[string]struct{}, bool) {
+	if attr == EntryDNAttribute {
+		if len(e.dn.rdns) == 0 {
+			return nil, false
+		}
+		return map[string]struct{}{e.dn.String(): {}}, true
+	}
+
+	if attr != ObjectClassAttribute {
+		vals, ok := e.attrs[attr]
+		return vals, ok
+	}
+
+	vals := map[string]struct{}{}
+	for oc := range e.ObjectClasses() {
+		vals[oc.Name()] = struct{}{}
+	}
+	return vals, len(vals) > 0
+}
+
+// Returns the values held for attr and all of attr's subtypes
+func (e *Entry) valsIncludingSubtypes(attr *Attribute) []string {
+	vals := []string{}
+	if attr == ObjectClassAttribute || attr == EntryDNAttribute {
+		vals, _ = e.AttrVals(attr)
+		return vals
+	}
+
+	for a, aVals := range e.attrs {
+		if a != attr && !a.IsSubtypeOf(attr) {
+			continue
+		}
+		for v := range aVals {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// Returns a copy of the values held for attr, or false if the entry does not have attr
+func (e *Entry) AttrVals(attr *Attribute) ([]string, bool) {
+	a, ok := e.attrVals(attr)
+	if !ok {
+		return nil, false
+	}
+
+	vals := []string{}
+	for v := range a {
+		vals = append(vals, v)
+	}
+	return vals, true
+}
+
+// Returns all the attributes held by the entry, including objectClass and entryDN
+func (e *Entry) Attributes() []*Attribute {
+	attrs := []*Attribute{ObjectClassAttribute}
+	if len(e.dn.rdns) > 0 {
+		attrs = append(attrs, EntryDNAttribute)
+	}
+	for attr := range e.attrs {
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// ContainsAttrVal will attempt to match val as an asserted value against the
+// attribute values, based on the provided eq rule.
 // If the attribute does not have an eq rule, then val will be compared exactly.
 func (e *Entry) ContainsAttrVal(attr *Attribute, val string) (bool, error) {
-	a, ok := e.attrs[attr]
+	a, ok := e.attrVals(attr)
 	if !ok {
 		return false, nil
 	}
@@ -123,12 +247,14 @@ func (e *Entry) ContainsAttrVal(attr *Attribute, val string) (bool, error) {
 	matched := false
 	var undefined error
 	for v := range a {
-		if !hasEqRule && v == val {
-			matched = true
+		if !hasEqRule {
+			if v == val {
+				matched = true
+			}
 			continue
 		}
 
-		m, err := eq.Match(val, v)
+		m, err := eq.MatchIn(e.schema, v, val)
 		if err != nil {
 			if errors.Is(err, UndefinedMatch) {
 				undefined = err
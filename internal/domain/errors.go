@@ -13,30 +13,56 @@ var (
 type ResultCode int
 
 const (
-	Success                ResultCode = iota
-	ProtocolError                     = 2
-	AuthMethodNotSupported            = 7
-	NoSuchAttribute                   = 16
-	UndefinedAttributeType            = 17
-	InappropriateMatching             = 18
-	ConstraintViolation               = 19
-	InvalidAttributeSyntax            = 21
-	NoSuchObject                      = 32
-	InvalidDnSyntax                   = 34
-	InvalidCredentials                = 49
-	UnwillingToPerform                = 53
-	ObjectClassViolation              = 65
-	Other                             = 80
+	Success                      ResultCode = iota
+	OperationsError                         = 1
+	ProtocolError                           = 2
+	TimeLimitExceeded                       = 3
+	SizeLimitExceeded                       = 4
+	CompareFalse                            = 5
+	CompareTrue                             = 6
+	AuthMethodNotSupported                  = 7
+	UnavailableCriticalExtension            = 12
+	SaslBindInProgress                      = 14
+	NoSuchAttribute                         = 16
+	UndefinedAttributeType                  = 17
+	InappropriateMatching                   = 18
+	ConstraintViolation                     = 19
+	AttributeOrValueExists                  = 20
+	InvalidAttributeSyntax                  = 21
+	NoSuchObject                            = 32
+	InvalidDnSyntax                         = 34
+	InappropriateAuthentication             = 48
+	InvalidCredentials                      = 49
+	InsufficientAccessRights                = 50
+	UnwillingToPerform                      = 53
+	ObjectClassViolation                    = 65
+	NotAllowedOnNonLeaf                     = 66
+	EntryAlreadyExists                      = 68
+	Other                                   = 80
 )
 
 func (rc ResultCode) String() string {
 	switch rc {
 	case Success:
 		return "Success"
+	case OperationsError:
+		return "OperationsError"
 	case ProtocolError:
 		return "ProtocolError"
+	case TimeLimitExceeded:
+		return "TimeLimitExceeded"
+	case SizeLimitExceeded:
+		return "SizeLimitExceeded"
+	case CompareFalse:
+		return "CompareFalse"
+	case CompareTrue:
+		return "CompareTrue"
 	case AuthMethodNotSupported:
 		return "AuthMethodNotSupported"
+	case UnavailableCriticalExtension:
+		return "UnavailableCriticalExtension"
+	case SaslBindInProgress:
+		return "SaslBindInProgress"
 	case NoSuchAttribute:
 		return "NoSuchAttribute"
 	case UndefinedAttributeType:
@@ -45,18 +71,28 @@ func (rc ResultCode) String() string {
 		return "InappropriateMatching"
 	case ConstraintViolation:
 		return "ConstraintViolation"
+	case AttributeOrValueExists:
+		return "AttributeOrValueExists"
 	case InvalidAttributeSyntax:
 		return "InvalidAttributeSyntax"
 	case NoSuchObject:
 		return "NoSuchObject"
 	case InvalidDnSyntax:
 		return "InvalidDnSyntax"
+	case InappropriateAuthentication:
+		return "InappropriateAuthentication"
 	case InvalidCredentials:
 		return "InvalidCredentials"
+	case InsufficientAccessRights:
+		return "InsufficientAccessRights"
 	case UnwillingToPerform:
 		return "UnwillingToPerform"
 	case ObjectClassViolation:
 		return "ObjectClassViolation"
+	case NotAllowedOnNonLeaf:
+		return "NotAllowedOnNonLeaf"
+	case EntryAlreadyExists:
+		return "EntryAlreadyExists"
 	case Other:
 		return "Other"
 	default:
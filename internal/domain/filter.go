@@ -0,0 +1,331 @@
+package domain
+
+// The three valued result of evaluating a filter against an entry (RFC 4511 4.5.1.7)
+type FilterResult int
+
+const (
+	FilterFalse FilterResult = iota
+	FilterTrue
+	FilterUndefined
+)
+
+func (r FilterResult) String() string {
+	switch r {
+	case FilterFalse:
+		return "FALSE"
+	case FilterTrue:
+		return "TRUE"
+	case FilterUndefined:
+		return "Undefined"
+	default:
+		return "unknown filter result"
+	}
+}
+
+func andResults(r1, r2 FilterResult) FilterResult {
+	switch {
+	case r1 == FilterFalse || r2 == FilterFalse:
+		return FilterFalse
+	case r1 == FilterUndefined || r2 == FilterUndefined:
+		return FilterUndefined
+	default:
+		return FilterTrue
+	}
+}
+
+func orResults(r1, r2 FilterResult) FilterResult {
+	switch {
+	case r1 == FilterTrue || r2 == FilterTrue:
+		return FilterTrue
+	case r1 == FilterUndefined || r2 == FilterUndefined:
+		return FilterUndefined
+	default:
+		return FilterFalse
+	}
+}
+
+type Filter interface {
+	Evaluate(e *Entry) FilterResult
+}
+
+/*
+Filter items that refer to an attribute keep the attribute description as it
+was given alongside the schema attribute it resolved to. If the description
+could not be resolved Attr is nil and the item always evaluates to Undefined.
+*/
+
+type AndFilter struct {
+	Filters []Filter
+}
+
+type OrFilter struct {
+	Filters []Filter
+}
+
+type NotFilter struct {
+	Filter Filter
+}
+
+type EqualityFilter struct {
+	Desc  string
+	Attr  *Attribute
+	Value string
+}
+
+type SubstringsFilter struct {
+	Desc    string
+	Attr    *Attribute
+	Initial string
+	Any     []string
+	Final   string
+}
+
+type GreaterOrEqualFilter struct {
+	Desc  string
+	Attr  *Attribute
+	Value string
+}
+
+type LessOrEqualFilter struct {
+	Desc  string
+	Attr  *Attribute
+	Value string
+}
+
+type PresentFilter struct {
+	Desc string
+	Attr *Attribute
+}
+
+type ApproxFilter struct {
+	Desc  string
+	Attr  *Attribute
+	Value string
+}
+
+// Both the rule and the attribute are optional, but at least one of them must be given
+type ExtensibleFilter struct {
+	RuleId       string
+	Rule         *MatchingRule
+	Desc         string
+	Attr         *Attribute
+	Value        string
+	DnAttributes bool
+}
+
+func FilterAnd(filters ...Filter) Filter {
+	return &AndFilter{filters}
+}
+
+func FilterOr(filters ...Filter) Filter {
+	return &OrFilter{filters}
+}
+
+func FilterNot(f Filter) Filter {
+	return &NotFilter{f}
+}
+
+func NewPresenceFilter(target *Attribute) Filter {
+	return &PresentFilter{Desc: target.Name(), Attr: target}
+}
+
+func NewEqualityFilter(target *Attribute, matchVal string) Filter {
+	return &EqualityFilter{Desc: target.Name(), Attr: target, Value: matchVal}
+}
+
+// an empty and is always true (RFC 4526)
+func (f *AndFilter) Evaluate(e *Entry) FilterResult {
+	res := FilterTrue
+	for _, filter := range f.Filters {
+		res = andResults(res, filter.Evaluate(e))
+		if res == FilterFalse {
+			return res
+		}
+	}
+	return res
+}
+
+// an empty or is always false (RFC 4526)
+func (f *OrFilter) Evaluate(e *Entry) FilterResult {
+	res := FilterFalse
+	for _, filter := range f.Filters {
+		res = orResults(res, filter.Evaluate(e))
+		if res == FilterTrue {
+			return res
+		}
+	}
+	return res
+}
+
+func (f *NotFilter) Evaluate(e *Entry) FilterResult {
+	switch f.Filter.Evaluate(e) {
+	case FilterTrue:
+		return FilterFalse
+	case FilterFalse:
+		return FilterTrue
+	default:
+		return FilterUndefined
+	}
+}
+
+// evaluates match against every value the entry holds for attr or any of its subtypes
+func evaluateVals(e *Entry, attr *Attribute, match func(val string) (bool, error)) FilterResult {
+	res := FilterFalse
+	for _, v := range e.valsIncludingSubtypes(attr) {
+		ok, err := match(v)
+		if err != nil {
+			res = FilterUndefined
+			continue
+		}
+
+		if ok {
+			return FilterTrue
+		}
+	}
+
+	return res
+}
+
+func evaluateEquality(e *Entry, attr *Attribute, value string) FilterResult {
+	if attr == nil {
+		return FilterUndefined
+	}
+
+	eq, ok := attr.EqRule()
+	if !ok {
+		return FilterUndefined
+	}
+
+	return evaluateVals(e, attr, func(val string) (bool, error) {
+		return eq.MatchIn(e.schema, val, value)
+	})
+}
+
+func (f *EqualityFilter) Evaluate(e *Entry) FilterResult {
+	return evaluateEquality(e, f.Attr, f.Value)
+}
+
+func (f *SubstringsFilter) Evaluate(e *Entry) FilterResult {
+	if f.Attr == nil {
+		return FilterUndefined
+	}
+
+	sub, ok := f.Attr.SubStrRule()
+	if !ok {
+		return FilterUndefined
+	}
+
+	assertion := substringAssertion{initial: f.Initial, any: f.Any, final: f.Final}.String()
+	return evaluateVals(e, f.Attr, func(val string) (bool, error) {
+		return sub.Match(val, assertion)
+	})
+}
+
+// evaluates to true if any value compared to the asserted value satisfies cmp
+func evaluateOrdering(e *Entry, attr *Attribute, value string, cmp func(int) bool) FilterResult {
+	if attr == nil {
+		return FilterUndefined
+	}
+
+	ord, ok := attr.OrdRule()
+	if !ok {
+		return FilterUndefined
+	}
+
+	return evaluateVals(e, attr, func(val string) (bool, error) {
+		c, err := ord.Compare(val, value)
+		if err != nil {
+			return false, err
+		}
+		return cmp(c), nil
+	})
+}
+
+func (f *GreaterOrEqualFilter) Evaluate(e *Entry) FilterResult {
+	return evaluateOrdering(e, f.Attr, f.Value, func(c int) bool { return c >= 0 })
+}
+
+func (f *LessOrEqualFilter) Evaluate(e *Entry) FilterResult {
+	return evaluateOrdering(e, f.Attr, f.Value, func(c int) bool { return c <= 0 })
+}
+
+func (f *PresentFilter) Evaluate(e *Entry) FilterResult {
+	if f.Attr == nil {
+		return FilterUndefined
+	}
+
+	if len(e.valsIncludingSubtypes(f.Attr)) == 0 {
+		return FilterFalse
+	}
+	return FilterTrue
+}
+
+// there is no approximate matching so it is treated as equality (RFC 4511 4.5.1.7.6)
+func (f *ApproxFilter) Evaluate(e *Entry) FilterResult {
+	return evaluateEquality(e, f.Attr, f.Value)
+}
+
+func (f *ExtensibleFilter) Evaluate(e *Entry) FilterResult {
+	if (f.RuleId != "" && f.Rule == nil) || (f.Desc != "" && f.Attr == nil) {
+		return FilterUndefined
+	}
+
+	rule := f.Rule
+	if rule == nil {
+		if f.Attr == nil {
+			return FilterUndefined
+		}
+
+		eq, ok := f.Attr.EqRule()
+		if !ok {
+			return FilterUndefined
+		}
+		rule = &eq
+	}
+
+	// without a type the rule applies to every attribute of the rule's syntax
+	applies := func(attr *Attribute) bool {
+		if f.Attr != nil {
+			return attr == f.Attr || attr.IsSubtypeOf(f.Attr)
+		}
+		syntax, _, ok := attr.Syntax()
+		return ok && syntax.numericoid == rule.syntax
+	}
+
+	match := func(val string) (bool, error) {
+		return rule.MatchIn(e.schema, val, f.Value)
+	}
+
+	res := FilterFalse
+	if f.Attr != nil {
+		res = evaluateVals(e, f.Attr, match)
+	} else {
+		for _, attr := range e.Attributes() {
+			if applies(attr) {
+				res = orResults(res, evaluateVals(e, attr, match))
+			}
+		}
+	}
+
+	if res == FilterTrue || !f.DnAttributes {
+		return res
+	}
+
+	for _, rdn := range e.dn.rdns {
+		for attr, val := range rdn.avas {
+			if !applies(attr) {
+				continue
+			}
+
+			ok, err := match(val)
+			switch {
+			case err != nil:
+				res = orResults(res, FilterUndefined)
+			case ok:
+				return FilterTrue
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,405 @@
+package domain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Returned when a string filter cannot be parsed, Pos is the byte offset
+// into the filter string where parsing failed
+type FilterSyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e FilterSyntaxError) Error() string {
+	return fmt.Sprintf("filter syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+type filterParser struct {
+	schema *Schema
+	s      string
+	pos    int
+}
+
+// Parses an RFC 4515 string filter such as (&(objectClass=person)(cn=Test*)).
+// Attribute descriptions and matching rules that are not in the schema are
+// not an error, the resulting filter items evaluate to undefined.
+func ParseFilter(schema *Schema, s string) (Filter, error) {
+	p := &filterParser{schema: schema, s: s}
+
+	f, err := p.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.s) {
+		return nil, p.errorf("unexpected %q after filter", p.s[p.pos:])
+	}
+
+	return f, nil
+}
+
+func (p *filterParser) errorf(format string, a ...any) error {
+	return FilterSyntaxError{Pos: p.pos, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (p *filterParser) peek() (byte, bool) {
+	if p.pos >= len(p.s) {
+		return 0, false
+	}
+	return p.s[p.pos], true
+}
+
+func (p *filterParser) expect(c byte) error {
+	next, ok := p.peek()
+	if !ok {
+		return p.errorf("expected %q but reached end of filter", c)
+	}
+	if next != c {
+		return p.errorf("expected %q but got %q", c, next)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) filter() (Filter, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+
+	c, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("unexpected end of filter")
+	}
+
+	var (
+		f   Filter
+		err error
+	)
+	switch c {
+	case '&':
+		p.pos++
+		var filters []Filter
+		filters, err = p.filterList()
+		f = FilterAnd(filters...)
+	case '|':
+		p.pos++
+		var filters []Filter
+		filters, err = p.filterList()
+		f = FilterOr(filters...)
+	case '!':
+		p.pos++
+		var not Filter
+		not, err = p.filter()
+		f = FilterNot(not)
+	default:
+		f, err = p.item()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// an empty filter list is allowed for the absolute true and false filters (RFC 4526)
+func (p *filterParser) filterList() ([]Filter, error) {
+	filters := []Filter{}
+	for {
+		if c, ok := p.peek(); !ok || c != '(' {
+			return filters, nil
+		}
+
+		f, err := p.filter()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+}
+
+func isDescChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == ';'
+}
+
+// reads an attribute description, matching rule oid or the dn keyword
+func (p *filterParser) descr() string {
+	start := p.pos
+	for p.pos < len(p.s) && isDescChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *filterParser) item() (Filter, error) {
+	start := p.pos
+	desc := p.descr()
+
+	c, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("unexpected end of filter")
+	}
+
+	if c == ':' {
+		return p.extensible(start, desc)
+	}
+
+	if desc == "" {
+		return nil, FilterSyntaxError{Pos: start, Msg: "missing attribute description"}
+	}
+
+	attr, _ := p.schema.FindAttributeDesc(desc)
+
+	switch c {
+	case '~', '>', '<':
+		p.pos++
+		if err := p.expect('='); err != nil {
+			return nil, err
+		}
+
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch c {
+		case '~':
+			return &ApproxFilter{Desc: desc, Attr: attr, Value: val}, nil
+		case '>':
+			return &GreaterOrEqualFilter{Desc: desc, Attr: attr, Value: val}, nil
+		default:
+			return &LessOrEqualFilter{Desc: desc, Attr: attr, Value: val}, nil
+		}
+
+	case '=':
+		p.pos++
+		return p.equalityOrSubstrings(desc, attr)
+
+	default:
+		return nil, p.errorf("unexpected %q in attribute description", c)
+	}
+}
+
+func (p *filterParser) equalityOrSubstrings(desc string, attr *Attribute) (Filter, error) {
+	start := p.pos
+	subs, err := p.substrings()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(subs) == 1 {
+		return &EqualityFilter{Desc: desc, Attr: attr, Value: subs[0]}, nil
+	}
+
+	if len(subs) == 2 && subs[0] == "" && subs[1] == "" {
+		return &PresentFilter{Desc: desc, Attr: attr}, nil
+	}
+
+	f := &SubstringsFilter{
+		Desc:    desc,
+		Attr:    attr,
+		Initial: subs[0],
+		Any:     []string{},
+		Final:   subs[len(subs)-1],
+	}
+
+	for _, sub := range subs[1 : len(subs)-1] {
+		if sub == "" {
+			return nil, FilterSyntaxError{Pos: start, Msg: "substrings filter contains an empty any substring"}
+		}
+		f.Any = append(f.Any, sub)
+	}
+
+	return f, nil
+}
+
+// [dn] [:rule] := value, where desc has already been read from start
+func (p *filterParser) extensible(start int, desc string) (Filter, error) {
+	f := &ExtensibleFilter{Desc: desc}
+	if desc != "" {
+		f.Attr, _ = p.schema.FindAttributeDesc(desc)
+	}
+
+	for {
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+
+		if c, ok := p.peek(); ok && c == '=' {
+			p.pos++
+			break
+		}
+
+		partStart := p.pos
+		part := p.descr()
+		switch {
+		case part == "":
+			return nil, p.errorf("expected dn or matching rule in extensible match")
+		case strings.EqualFold(part, "dn") && !f.DnAttributes && f.RuleId == "":
+			f.DnAttributes = true
+		case f.RuleId == "":
+			f.RuleId = part
+			if rule, err := GetMatchingRule(part); err == nil {
+				f.Rule = &rule
+			}
+		default:
+			return nil, FilterSyntaxError{Pos: partStart, Msg: fmt.Sprintf("unexpected %q in extensible match", part)}
+		}
+	}
+
+	if f.Desc == "" && f.RuleId == "" {
+		return nil, FilterSyntaxError{Pos: start, Msg: "extensible match needs an attribute description or a matching rule"}
+	}
+
+	val, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	f.Value = val
+
+	return f, nil
+}
+
+// reads an assertion value where * is not special
+func (p *filterParser) value() (string, error) {
+	subs, err := p.values(false)
+	if err != nil {
+		return "", err
+	}
+	return subs[0], nil
+}
+
+// reads an assertion value split on unescaped *
+func (p *filterParser) substrings() ([]string, error) {
+	return p.values(true)
+}
+
+func (p *filterParser) values(splitStars bool) ([]string, error) {
+	subs := []string{}
+	var sb strings.Builder
+
+	for {
+		c, ok := p.peek()
+		if !ok {
+			return nil, p.errorf("unexpected end of filter in assertion value")
+		}
+
+		switch c {
+		case ')':
+			return append(subs, sb.String()), nil
+		case '(', 0:
+			return nil, p.errorf("%q must be escaped in assertion values", c)
+		case '*':
+			if !splitStars {
+				return nil, p.errorf("'*' must be escaped in assertion values")
+			}
+			subs = append(subs, sb.String())
+			sb.Reset()
+			p.pos++
+		case '\\':
+			if p.pos+3 > len(p.s) {
+				return nil, p.errorf("incomplete escape in assertion value")
+			}
+			b, err := hex.DecodeString(p.s[p.pos+1 : p.pos+3])
+			if err != nil {
+				return nil, p.errorf("invalid escape %q in assertion value", p.s[p.pos:p.pos+3])
+			}
+			sb.Write(b)
+			p.pos += 3
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// Escapes the characters that cannot appear unescaped in an assertion value
+func EscapeFilterValue(v string) string {
+	var sb strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&sb, "\\%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// resolved attributes are printed by their primary name with any options kept
+func filterDescString(desc string, attr *Attribute) string {
+	if attr == nil {
+		return desc
+	}
+
+	if _, options, ok := strings.Cut(desc, ";"); ok {
+		return attr.Name() + ";" + options
+	}
+	return attr.Name()
+}
+
+func filterListString(op byte, filters []Filter) string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	sb.WriteByte(op)
+	for _, f := range filters {
+		sb.WriteString(FilterString(f))
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// Prints a filter in its canonical RFC 4515 string form
+func FilterString(f Filter) string {
+	switch f := f.(type) {
+	case *AndFilter:
+		return filterListString('&', f.Filters)
+	case *OrFilter:
+		return filterListString('|', f.Filters)
+	case *NotFilter:
+		return "(!" + FilterString(f.Filter) + ")"
+	case *EqualityFilter:
+		return "(" + filterDescString(f.Desc, f.Attr) + "=" + EscapeFilterValue(f.Value) + ")"
+	case *SubstringsFilter:
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "(%s=%s*", filterDescString(f.Desc, f.Attr), EscapeFilterValue(f.Initial))
+		for _, sub := range f.Any {
+			sb.WriteString(EscapeFilterValue(sub))
+			sb.WriteByte('*')
+		}
+		fmt.Fprintf(&sb, "%s)", EscapeFilterValue(f.Final))
+		return sb.String()
+	case *GreaterOrEqualFilter:
+		return "(" + filterDescString(f.Desc, f.Attr) + ">=" + EscapeFilterValue(f.Value) + ")"
+	case *LessOrEqualFilter:
+		return "(" + filterDescString(f.Desc, f.Attr) + "<=" + EscapeFilterValue(f.Value) + ")"
+	case *PresentFilter:
+		return "(" + filterDescString(f.Desc, f.Attr) + "=*)"
+	case *ApproxFilter:
+		return "(" + filterDescString(f.Desc, f.Attr) + "~=" + EscapeFilterValue(f.Value) + ")"
+	case *ExtensibleFilter:
+		var sb strings.Builder
+		sb.WriteByte('(')
+		sb.WriteString(filterDescString(f.Desc, f.Attr))
+		if f.DnAttributes {
+			sb.WriteString(":dn")
+		}
+		switch {
+		case f.Rule != nil:
+			fmt.Fprintf(&sb, ":%s", f.Rule.Name())
+		case f.RuleId != "":
+			fmt.Fprintf(&sb, ":%s", f.RuleId)
+		}
+		fmt.Fprintf(&sb, ":=%s)", EscapeFilterValue(f.Value))
+		return sb.String()
+	default:
+		return fmt.Sprintf("(unknown filter %T)", f)
+	}
+}
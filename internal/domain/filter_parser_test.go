@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFilterRoundTrip(t *testing.T) {
+	tests := []struct {
+		filter, canonical string
+	}{
+		{"(&(objectClass=person)(|(cn=Test*)(sn=Tester)))", ""},
+		{"(!(cn=Test1))", ""},
+		{"(&)", ""},
+		{"(|)", ""},
+		{"(cn=*)", ""},
+		{"(cn=*one*two*)", ""},
+		{"(cn=a*)", ""},
+		{"(cn=*z)", ""},
+		{"(sn>=a)", ""},
+		{"(sn<=z)", ""},
+		{"(sn~=tester)", ""},
+		{"(cn:caseExactMatch:=Test1)", ""},
+		{"(cn:dn:caseIgnoreMatch:=test1)", ""},
+		{"(:dn:2.5.13.2:=dev)", "(:dn:caseIgnoreMatch:=dev)"},
+		{"(:1.2.3.4:=x)", ""},
+		{"(cn=a\\2ab\\28\\29\\5c)", ""},
+		{"(cn=\\41)", "(cn=A)"},
+		{"(CN=test)", "(cn=test)"},
+		{"(2.5.4.3=test)", "(cn=test)"},
+		{"(cn;lang-en=test)", ""},
+		{"(unknownAttr=x)", ""},
+	}
+
+	for _, test := range tests {
+		f, err := ParseFilter(schema, test.filter)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %s", test.filter, err)
+		}
+
+		expected := test.canonical
+		if expected == "" {
+			expected = test.filter
+		}
+
+		if s := FilterString(f); s != expected {
+			t.Fatalf("parsed %q but printed %q, expected %q", test.filter, s, expected)
+		}
+	}
+}
+
+func TestParseFilterAst(t *testing.T) {
+	f, err := ParseFilter(schema, "(&(cn=Test*)(!(sn=One))(unknownAttr=*))")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	and, ok := f.(*AndFilter)
+	if !ok || len(and.Filters) != 3 {
+		t.Fatalf("expected and filter with 3 filters, got %#v", f)
+	}
+
+	sub, ok := and.Filters[0].(*SubstringsFilter)
+	if !ok || sub.Attr != attrs["cn"] || sub.Initial != "Test" || len(sub.Any) != 0 || sub.Final != "" {
+		t.Fatalf("unexpected substrings filter %#v", and.Filters[0])
+	}
+
+	not, ok := and.Filters[1].(*NotFilter)
+	if !ok {
+		t.Fatalf("expected not filter, got %#v", and.Filters[1])
+	}
+	if eq, ok := not.Filter.(*EqualityFilter); !ok || eq.Attr != attrs["sn"] || eq.Value != "One" {
+		t.Fatalf("unexpected equality filter %#v", not.Filter)
+	}
+
+	if present, ok := and.Filters[2].(*PresentFilter); !ok || present.Attr != nil || present.Desc != "unknownAttr" {
+		t.Fatalf("unexpected present filter %#v", and.Filters[2])
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter string
+		pos    int
+	}{
+		{"", 0},
+		{"cn=test", 0},
+		{"(cn=test", 8},
+		{"(cn=test))", 9},
+		{"(=test)", 1},
+		{"(cn>test)", 4},
+		{"(cn=a(b)", 5},
+		{"(cn=a**b)", 4},
+		{"(sn>=a*)", 6},
+		{"(cn=\\zz)", 4},
+		{"(cn=\\4)", 4},
+		{"(:=x)", 1},
+		{"(cn:dn:rule:extra:=x)", 12},
+		{"(&(cn=a)(sn=b)", 14},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFilter(schema, test.filter)
+		var syntaxErr FilterSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("expected syntax error parsing %q, got %v", test.filter, err)
+		}
+
+		if syntaxErr.Pos != test.pos {
+			t.Fatalf("expected error parsing %q at %d, got %s", test.filter, test.pos, syntaxErr)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+func TestFilterEvaluate(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Test1").Build()
+	entry := util.Unwrap(dit.GetEntry(dn))
+
+	name := util.UnwrapOk(schema.FindAttribute("name"))
+	caseIgnore := util.Unwrap(GetMatchingRule("caseIgnoreMatch"))
+	unknown := &EqualityFilter{Desc: "unknownAttr", Value: "x"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		res    FilterResult
+	}{
+		{"equality", NewEqualityFilter(attrs["cn"], "test1"), FilterTrue},
+		{"equality no match", NewEqualityFilter(attrs["cn"], "Test2"), FilterFalse},
+		{"equality on supertype", NewEqualityFilter(name, "Tester"), FilterTrue},
+		{"unknown attribute", unknown, FilterUndefined},
+		{"not unknown attribute", FilterNot(unknown), FilterUndefined},
+		{"present", NewPresenceFilter(attrs["sn"]), FilterTrue},
+		{"not present", NewPresenceFilter(attrs["givenName"]), FilterFalse},
+		{"objectClass", NewEqualityFilter(ObjectClassAttribute, "top"), FilterTrue},
+		{"objectClass by oid", NewEqualityFilter(ObjectClassAttribute, "2.5.6.6"), FilterTrue},
+		{"objectClass ignores case", NewEqualityFilter(ObjectClassAttribute, "PERSON"), FilterTrue},
+		{"entryDN by attribute oid", NewEqualityFilter(EntryDNAttribute, "2.5.4.3=test1, DC=Georgiboy,dc=dev"), FilterTrue},
+		{"entryDN of another entry", NewEqualityFilter(EntryDNAttribute, "cn=test2,dc=georgiboy,dc=dev"), FilterFalse},
+		{"empty and", FilterAnd(), FilterTrue},
+		{"empty or", FilterOr(), FilterFalse},
+		{"and with false", FilterAnd(unknown, NewEqualityFilter(attrs["cn"], "Test2")), FilterFalse},
+		{"and with undefined", FilterAnd(unknown, NewEqualityFilter(attrs["cn"], "Test1")), FilterUndefined},
+		{"or with true", FilterOr(unknown, NewEqualityFilter(attrs["cn"], "Test1")), FilterTrue},
+		{"or with undefined", FilterOr(unknown, NewEqualityFilter(attrs["cn"], "Test2")), FilterUndefined},
+		{"substrings initial", &SubstringsFilter{Desc: "cn", Attr: attrs["cn"], Initial: "tes"}, FilterTrue},
+		{"substrings any", &SubstringsFilter{Desc: "sn", Attr: attrs["sn"], Any: []string{"est"}}, FilterTrue},
+		{"substrings final", &SubstringsFilter{Desc: "cn", Attr: attrs["cn"], Final: "2"}, FilterFalse},
+		{"substrings on supertype", &SubstringsFilter{Desc: "name", Attr: name, Initial: "On", Final: "e"}, FilterTrue},
+		{"approx", &ApproxFilter{Desc: "sn", Attr: attrs["sn"], Value: "one"}, FilterTrue},
+		{"extensible rule and type", &ExtensibleFilter{RuleId: "caseIgnoreMatch", Rule: &caseIgnore, Desc: "sn", Attr: attrs["sn"], Value: "ONE"}, FilterTrue},
+		{"extensible rule only", &ExtensibleFilter{RuleId: "caseIgnoreMatch", Rule: &caseIgnore, Value: "tester"}, FilterTrue},
+		{"extensible unknown rule", &ExtensibleFilter{RuleId: "1.2.3.4", Desc: "sn", Attr: attrs["sn"], Value: "One"}, FilterUndefined},
+		{"extensible dn attributes", &ExtensibleFilter{Desc: "dc", Attr: attrs["dc"], Value: "georgiboy", DnAttributes: true}, FilterTrue},
+		{"extensible without dn attributes", &ExtensibleFilter{Desc: "dc", Attr: attrs["dc"], Value: "georgiboy"}, FilterFalse},
+	}
+
+	for _, test := range tests {
+		if res := test.filter.Evaluate(entry); res != test.res {
+			t.Errorf("%s: filter evaluated to %s but expected %s", test.name, res, test.res)
+		}
+	}
+}
+
+func TestSearchExcludesUndefined(t *testing.T) {
+	dit := GenerateTestDIT(schema)
+	baseDn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").Build()
+
+	// not of an undefined filter is still undefined so nothing is returned
+	filter := FilterNot(&EqualityFilter{Desc: "unknownAttr", Value: "x"})
+	res, err := dit.Search(baseDn, WholeSubtree, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 {
+		t.Fatalf("expected no results, got %d", len(res))
+	}
+}
+
+func TestOrderingFilters(t *testing.T) {
+	uidNumber := NewAttributeBuilder().
+		SetOid("1.3.6.1.1.1.1.0").
+		AddNames("uidNumber").
+		SetOrdRule(util.Unwrap(GetMatchingRule("integerOrderingMatch"))).
+		Build()
+	expiry := NewAttributeBuilder().
+		SetOid("1.2.3.4").
+		AddNames("expiry").
+		SetOrdRule(util.Unwrap(GetMatchingRule("generalizedTimeOrderingMatch"))).
+		Build()
+
+	entry := &Entry{attrs: map[*Attribute]map[string]struct{}{
+		uidNumber:   {"1000": {}},
+		expiry:      {"20240601000000Z": {}},
+		attrs["sn"]: {"Tester": {}},
+	}}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		res    FilterResult
+	}{
+		{"integer greater", &GreaterOrEqualFilter{Attr: uidNumber, Value: "999"}, FilterTrue},
+		{"integer equal", &GreaterOrEqualFilter{Attr: uidNumber, Value: "1000"}, FilterTrue},
+		{"integer less", &GreaterOrEqualFilter{Attr: uidNumber, Value: "1001"}, FilterFalse},
+		{"integer less or equal", &LessOrEqualFilter{Attr: uidNumber, Value: "1000"}, FilterTrue},
+		{"integer not less", &LessOrEqualFilter{Attr: uidNumber, Value: "20"}, FilterFalse},
+		{"invalid integer", &LessOrEqualFilter{Attr: uidNumber, Value: "twenty"}, FilterUndefined},
+		{"time before", &LessOrEqualFilter{Attr: expiry, Value: "20240701000000Z"}, FilterTrue},
+		{"time after", &GreaterOrEqualFilter{Attr: expiry, Value: "20240701000000Z"}, FilterFalse},
+		{"no ordering rule", &GreaterOrEqualFilter{Attr: attrs["sn"], Value: "a"}, FilterUndefined},
+	}
+
+	for _, test := range tests {
+		if res := test.filter.Evaluate(entry); res != test.res {
+			t.Errorf("%s: filter evaluated to %s but expected %s", test.name, res, test.res)
+		}
+	}
+}
+
+// Compare and RDN matching use ContainsAttrVal, which has to pass the values
+// to the rule in the same order as the equality filter
+func TestContainsAttrValAgreesWithEqualityFilter(t *testing.T) {
+	tests := []struct {
+		rule, val, assertion string
+		exp                  bool
+	}{
+		{"wordMatch", "red green blue", "green", true},
+		{"keywordMatch", "red green blue", "GREEN", true},
+		{"keywordMatch", "green", "red green blue", false},
+		{"objectIdentifierFirstComponentMatch", "( 2.5.4.3 NAME 'cn' SUP name )", "2.5.4.3", true},
+		{"integerFirstComponentMatch", "( 1 NAME 'rule' FORM form )", "1", true},
+		{"directoryStringFirstComponentMatch", "( 'Test' 'other' )", "test", true},
+		{"uniqueMemberMatch", "cn=Test1,dc=dev#'0101'B", "cn=test1,dc=dev", true},
+	}
+
+	for i, test := range tests {
+		attr := NewAttributeBuilder().
+			SetOid(OID(fmt.Sprintf("1.2.3.%d", i))).
+			AddNames("testAttr").
+			SetEqRule(util.Unwrap(GetMatchingRule(test.rule))).
+			Build()
+		entry := &Entry{attrs: map[*Attribute]map[string]struct{}{attr: {test.val: {}}}}
+
+		contains, err := entry.ContainsAttrVal(attr, test.assertion)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.rule, err)
+		}
+		filtered := (&EqualityFilter{Attr: attr, Value: test.assertion}).Evaluate(entry) == FilterTrue
+
+		if contains != test.exp || filtered != test.exp {
+			t.Errorf("%s: %q against %q expected %t, got compare %t and filter %t", test.rule, test.assertion, test.val, test.exp, contains, filtered)
+		}
+	}
+}
@@ -181,6 +181,81 @@ func TestParsesAttributes(t *testing.T) {
 	t.Logf("passed parsed ldif: %s", manyAttrDefs)
 }
 
+// attributes from the OpenLDAP core, cosine and inetorgperson schemas
+const openLdapAttrDefs = `
+      ( 2.5.4.2 NAME 'knowledgeInformation'
+         EQUALITY caseIgnoreMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{32768} )
+
+      ( 2.16.840.1.113730.3.1.3 NAME 'employeeNumber'
+         EQUALITY caseIgnoreMatch
+         SUBSTR caseIgnoreSubstringsMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.15
+         SINGLE-VALUE )
+
+      ( 0.9.2342.19200300.100.1.3 NAME ( 'mail' 'rfc822Mailbox' )
+         EQUALITY caseIgnoreIA5Match
+         SUBSTR caseIgnoreIA5SubstringsMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.26{256} )
+
+      ( 2.5.4.45 NAME 'x500UniqueIdentifier'
+         EQUALITY bitStringMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.6 )
+
+      ( 1.3.6.1.1.1.1.0 NAME 'uidNumber'
+         EQUALITY integerMatch
+         ORDERING integerOrderingMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.27
+         SINGLE-VALUE )
+
+      ( 0.9.2342.19200300.100.1.1 NAME ( 'uid' 'userid' )
+         EQUALITY caseIgnoreMatch
+         SUBSTR caseIgnoreSubstringsMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.15{256} )
+
+      ( 2.5.4.50 NAME 'uniqueMember'
+         EQUALITY uniqueMemberMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.34 )
+
+      ( 1.3.6.1.4.1.1466.101.120.16 NAME 'ldapSyntaxes'
+         EQUALITY objectIdentifierFirstComponentMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.54
+         USAGE directoryOperation )
+
+      ( 2.5.18.1 NAME 'createTimestamp'
+         EQUALITY generalizedTimeMatch
+         ORDERING generalizedTimeOrderingMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.24
+         SINGLE-VALUE NO-USER-MODIFICATION
+         USAGE directoryOperation )
+
+      ( 2.5.4.20 NAME 'telephoneNumber'
+         EQUALITY telephoneNumberMatch
+         SUBSTR telephoneNumberSubstringsMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.50{32} )
+
+      ( 1.3.6.1.4.1.4203.1.3.5 NAME 'supportedFeatures'
+         EQUALITY objectIdentifierMatch
+         SYNTAX 1.3.6.1.4.1.1466.115.121.1.38
+         USAGE dSAOperation )
+`
+
+func TestParsesOpenLdapAttributes(t *testing.T) {
+	parsed, err := ParseAttributes(strings.NewReader(openLdapAttrDefs))
+	if err != nil {
+		t.Fatalf("Failed to parse ldif:\n%s\nErr is: %s", openLdapAttrDefs, err)
+	}
+
+	if len(parsed) != 11 {
+		t.Fatalf("parsed wrong number attributes, got %d expected 11", len(parsed))
+	}
+
+	uidNumber := parsed["1.3.6.1.1.1.1.0"]
+	if ord, ok := uidNumber.OrdRule(); !ok || ord.Name() != "integerOrderingMatch" {
+		t.Fatalf("uidNumber has the wrong ordering rule %q", ord.Name())
+	}
+}
+
 const manyOcDefs = `
 	( 2.5.6.11 NAME 'applicationProcess'
          SUP top
@@ -59,7 +59,7 @@ var (
 	noidlen_re    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)+({[1-9][0-9]*})?$`)
 	descr_re      = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
 	qdescr_re     = regexp.MustCompile(`^\'[a-zA-Z][a-zA-Z0-9-]*\'$`)
-	qdstring_re   = regexp.MustCompile(`^\'[^\\\']+\'$`)
+	qdstring_re   = regexp.MustCompile(`^\'([^\\\']|\\27|\\5[cC])+\'$`)
 )
 
 func determineTokenType(val string) (TokenType, error) {
@@ -419,3 +419,16 @@ func stripQuotes(s string) string {
 	}
 	return s
 }
+
+// the escapes a qdstring needs for ' and \ (RFC 4512 4.1)
+var qdstringEscaper = strings.NewReplacer(`\`, `\5C`, `'`, `\27`)
+
+var qdstringUnescaper = strings.NewReplacer(`\27`, `'`, `\5C`, `\`, `\5c`, `\`)
+
+func quoteQdstring(s string) string {
+	return "'" + qdstringEscaper.Replace(s) + "'"
+}
+
+func unescapeQdstring(s string) string {
+	return qdstringUnescaper.Replace(s)
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -17,6 +18,8 @@ type MatchingRule struct {
 	name       string
 	syntax     OID
 	match      func(string, string) (bool, error)
+	// only set for ordering rules, returns <0, 0 or >0 like strings.Compare
+	compare func(string, string) (int, error)
 }
 
 func (m MatchingRule) Oid() OID {
@@ -31,6 +34,9 @@ func (m MatchingRule) Syntax() OID {
 	return m.syntax
 }
 
+// Matches an attribute value v1 against an asserted value v2, the order
+// matters for rules such as wordMatch and the first component rules where
+// only part of the attribute value is compared
 func (m MatchingRule) Match(v1, v2 string) (bool, error) {
 	if m.match == nil {
 		return false, NewLdapError(UnwillingToPerform, nil, "Matching rule %s has no implementation", m.name)
@@ -38,74 +44,190 @@ func (m MatchingRule) Match(v1, v2 string) (bool, error) {
 	return m.match(v1, v2)
 }
 
+// Like Match, but rules such as distinguishedNameMatch and objectIdentifierMatch
+// resolve the names in the values through the schema. Without a schema they fall
+// back to comparing the names.
+func (m MatchingRule) MatchIn(schema *Schema, v1, v2 string) (bool, error) {
+	if match, ok := schemaMatches[m.numericoid]; ok && schema != nil {
+		return match(schema, v1, v2)
+	}
+	return m.Match(v1, v2)
+}
+
+// Compares an attribute value v1 with an asserted value v2 using an ordering rule
+func (m MatchingRule) Compare(v1, v2 string) (int, error) {
+	if m.compare == nil {
+		return 0, NewLdapError(UnwillingToPerform, nil, "Matching rule %s is not an ordering rule", m.name)
+	}
+	return m.compare(v1, v2)
+}
+
 func (m MatchingRule) Eq(o MatchingRule) bool {
 	return m.numericoid == o.numericoid && m.name == o.name && m.syntax == o.syntax
 }
 
+// Every matching rule defined in RFC 4517 4.2, along with the UUID rules (RFC 4530 3)
 var matchingRules = map[string]MatchingRule{
-	"objectIdentifierMatch": MatchingRule{
-		numericoid: "2.5.13.0",
-		name:       "objectIdentifierMatch",
-		syntax:     "1.3.6.1.4.1.1466.115.121.1.38",
-		match:      basicStringEquality,
-	},
 	"bitStringMatch": MatchingRule{
 		numericoid: "2.5.13.16",
 		name:       "bitStringMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.6",
 		match:      bitStringMatch,
 	},
+	"booleanMatch": MatchingRule{
+		numericoid: "2.5.13.13",
+		name:       "booleanMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.7",
+		match:      booleanMatch,
+	},
+	"caseExactIA5Match": MatchingRule{
+		numericoid: "1.3.6.1.4.1.1466.109.114.1",
+		name:       "caseExactIA5Match",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.26",
+		match:      stringMatch(caseExactPrep),
+	},
+	"caseExactMatch": MatchingRule{
+		numericoid: "2.5.13.5",
+		name:       "caseExactMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
+		match:      stringMatch(caseExactPrep),
+	},
+	"caseExactOrderingMatch": MatchingRule{
+		numericoid: "2.5.13.6",
+		name:       "caseExactOrderingMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
+		match:      orderingMatch(prepOrdering(caseExactPrep)),
+		compare:    prepOrdering(caseExactPrep),
+	},
+	"caseExactSubstringsMatch": MatchingRule{
+		numericoid: "2.5.13.7",
+		name:       "caseExactSubstringsMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.58",
+		match:      substringsMatch(caseExactPrep),
+	},
 	"caseIgnoreIA5Match": MatchingRule{
 		numericoid: "1.3.6.1.4.1.1466.109.114.2",
 		name:       "caseIgnoreIA5Match",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.26",
-		match:      caseIgnoreMatch,
+		match:      stringMatch(caseIgnorePrep),
 	},
 	"caseIgnoreIA5SubstringsMatch": MatchingRule{
 		numericoid: "1.3.6.1.4.1.1466.109.114.3",
 		name:       "caseIgnoreIA5SubstringsMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.58",
+		match:      substringsMatch(caseIgnorePrep),
 	},
 	"caseIgnoreListMatch": MatchingRule{
 		numericoid: "2.5.13.11",
 		name:       "caseIgnoreListMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.41",
+		match:      caseIgnoreListMatch,
 	},
 	"caseIgnoreListSubstringsMatch": MatchingRule{
 		numericoid: "2.5.13.12",
 		name:       "caseIgnoreListSubstringsMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.58",
+		match:      caseIgnoreListSubstringsMatch,
 	},
 	"caseIgnoreMatch": MatchingRule{
 		numericoid: "2.5.13.2",
 		name:       "caseIgnoreMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
-		match:      caseIgnoreMatch,
+		match:      stringMatch(caseIgnorePrep),
+	},
+	"caseIgnoreOrderingMatch": MatchingRule{
+		numericoid: "2.5.13.3",
+		name:       "caseIgnoreOrderingMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
+		match:      orderingMatch(prepOrdering(caseIgnorePrep)),
+		compare:    prepOrdering(caseIgnorePrep),
 	},
 	"caseIgnoreSubstringsMatch": MatchingRule{
 		numericoid: "2.5.13.4",
 		name:       "caseIgnoreSubstringsMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.58",
+		match:      substringsMatch(caseIgnorePrep),
 	},
-	"caseIgnoreOrderingMatch": MatchingRule{
-		numericoid: "2.5.13.3",
-		name:       "caseIgnoreOrderingMatch",
+	"directoryStringFirstComponentMatch": MatchingRule{
+		numericoid: "2.5.13.31",
+		name:       "directoryStringFirstComponentMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
+		match:      firstComponentMatch(stringMatch(caseIgnorePrep)),
 	},
 	"distinguishedNameMatch": MatchingRule{
 		numericoid: "2.5.13.1",
 		name:       "distinguishedNameMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.12",
+		match:      distinguishedNameMatch,
+	},
+	"generalizedTimeMatch": MatchingRule{
+		numericoid: "2.5.13.27",
+		name:       "generalizedTimeMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.24",
+		match:      generalizedTimeMatch,
+	},
+	"generalizedTimeOrderingMatch": MatchingRule{
+		numericoid: "2.5.13.28",
+		name:       "generalizedTimeOrderingMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.24",
+		match:      orderingMatch(generalizedTimeOrdering),
+		compare:    generalizedTimeOrdering,
+	},
+	"integerFirstComponentMatch": MatchingRule{
+		numericoid: "2.5.13.29",
+		name:       "integerFirstComponentMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.27",
+		match:      firstComponentMatch(integerMatch),
+	},
+	"integerMatch": MatchingRule{
+		numericoid: "2.5.13.14",
+		name:       "integerMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.27",
+		match:      integerMatch,
+	},
+	"integerOrderingMatch": MatchingRule{
+		numericoid: "2.5.13.15",
+		name:       "integerOrderingMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.27",
+		match:      orderingMatch(integerOrdering),
+		compare:    integerOrdering,
+	},
+	"keywordMatch": MatchingRule{
+		numericoid: "2.5.13.33",
+		name:       "keywordMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
+		match:      wordMatch,
 	},
 	"numericStringMatch": MatchingRule{
 		numericoid: "2.5.13.8",
 		name:       "numericStringMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.36",
+		match:      stringMatch(numericStringPrep),
+	},
+	"numericStringOrderingMatch": MatchingRule{
+		numericoid: "2.5.13.9",
+		name:       "numericStringOrderingMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.36",
+		match:      orderingMatch(prepOrdering(numericStringPrep)),
+		compare:    prepOrdering(numericStringPrep),
 	},
 	"numericStringSubstringsMatch": MatchingRule{
 		numericoid: "2.5.13.10",
 		name:       "numericStringSubstringsMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.58",
+		match:      substringsMatch(numericStringPrep),
+	},
+	"objectIdentifierFirstComponentMatch": MatchingRule{
+		numericoid: "2.5.13.30",
+		name:       "objectIdentifierFirstComponentMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.38",
+		match:      firstComponentMatch(objectIdentifierMatch),
+	},
+	"objectIdentifierMatch": MatchingRule{
+		numericoid: "2.5.13.0",
+		name:       "objectIdentifierMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.38",
+		match:      objectIdentifierMatch,
 	},
 	"octetStringMatch": MatchingRule{
 		numericoid: "2.5.13.17",
@@ -113,20 +235,67 @@ var matchingRules = map[string]MatchingRule{
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.40",
 		match:      basicStringEquality,
 	},
+	"octetStringOrderingMatch": MatchingRule{
+		numericoid: "2.5.13.18",
+		name:       "octetStringOrderingMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.40",
+		match:      orderingMatch(octetStringOrdering),
+		compare:    octetStringOrdering,
+	},
 	"telephoneNumberMatch": MatchingRule{
 		numericoid: "2.5.13.20",
 		name:       "telephoneNumberMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.50",
+		match:      stringMatch(telephoneNumberPrep),
 	},
 	"telephoneNumberSubstringsMatch": MatchingRule{
 		numericoid: "2.5.13.21",
 		name:       "telephoneNumberSubstringsMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.58",
+		match:      substringsMatch(telephoneNumberPrep),
 	},
 	"uniqueMemberMatch": MatchingRule{
 		numericoid: "2.5.13.23",
 		name:       "uniqueMemberMatch",
 		syntax:     "1.3.6.1.4.1.1466.115.121.1.34",
+		match:      uniqueMemberMatch(distinguishedNameMatch),
+	},
+	"uuidMatch": MatchingRule{
+		numericoid: "1.3.6.1.1.16.2",
+		name:       "uuidMatch",
+		syntax:     "1.3.6.1.1.16.1",
+		match:      uuidMatch,
+	},
+	"uuidOrderingMatch": MatchingRule{
+		numericoid: "1.3.6.1.1.16.3",
+		name:       "uuidOrderingMatch",
+		syntax:     "1.3.6.1.1.16.1",
+		match:      orderingMatch(uuidOrdering),
+		compare:    uuidOrdering,
+	},
+	"wordMatch": MatchingRule{
+		numericoid: "2.5.13.32",
+		name:       "wordMatch",
+		syntax:     "1.3.6.1.4.1.1466.115.121.1.15",
+		match:      wordMatch,
+	},
+}
+
+// The rules that resolve attribute types or descriptors, which they can only do
+// with the schema. Kept apart from matchingRules as resolving names refers back
+// to the attributes built from matchingRules.
+var schemaMatches = map[OID]func(*Schema, string, string) (bool, error){
+	// distinguishedNameMatch
+	"2.5.13.1": distinguishedNameSchemaMatch,
+	// objectIdentifierFirstComponentMatch
+	"2.5.13.30": func(schema *Schema, v1, v2 string) (bool, error) {
+		return firstComponentMatch(withSchema(schema, objectIdentifierSchemaMatch))(v1, v2)
+	},
+	// objectIdentifierMatch
+	"2.5.13.0": objectIdentifierSchemaMatch,
+	// uniqueMemberMatch
+	"2.5.13.23": func(schema *Schema, v1, v2 string) (bool, error) {
+		return uniqueMemberMatch(withSchema(schema, distinguishedNameSchemaMatch))(v1, v2)
 	},
 }
 
@@ -135,8 +304,9 @@ func GetMatchingRule(nameOrOid string) (MatchingRule, error) {
 		return mr, nil
 	}
 
+	// names are case insensitive
 	for _, mr := range matchingRules {
-		if mr.numericoid == OID(nameOrOid) {
+		if mr.numericoid == OID(nameOrOid) || strings.EqualFold(mr.name, nameOrOid) {
 			return mr, nil
 		}
 	}
@@ -166,9 +336,260 @@ func bitStringMatch(s1, s2 string) (bool, error) {
 	return s1 == s2, nil
 }
 
-// TODO insignificant space handling
-func caseIgnoreMatch(s1, s2 string) (bool, error) {
-	return strings.ToLower(s1) == strings.ToLower(s2), nil
+// Binds a rule that needs the schema to schema
+func withSchema(schema *Schema, match func(*Schema, string, string) (bool, error)) func(string, string) (bool, error) {
+	return func(s1, s2 string) (bool, error) {
+		return match(schema, s1, s2)
+	}
+}
+
+// descriptors are case insensitive, without the schema a descriptor and its
+// numericoid do not match
+func objectIdentifierMatch(s1, s2 string) (bool, error) {
+	return strings.EqualFold(strings.TrimSpace(s1), strings.TrimSpace(s2)), nil
+}
+
+// Descriptors are resolved to the numericoid they name so that cn matches
+// 2.5.4.3, a descriptor the schema does not know is compared by name
+func objectIdentifierSchemaMatch(schema *Schema, s1, s2 string) (bool, error) {
+	oid1, ok1 := schema.resolveOid(strings.TrimSpace(s1))
+	oid2, ok2 := schema.resolveOid(strings.TrimSpace(s2))
+	if ok1 && ok2 {
+		return oid1 == oid2, nil
+	}
+	return objectIdentifierMatch(s1, s2)
+}
+
+func booleanMatch(s1, s2 string) (bool, error) {
+	if validateBoolean(s1) != nil || validateBoolean(s2) != nil {
+		return false, UndefinedMatch
+	}
+	return s1 == s2, nil
+}
+
+func integerMatch(s1, s2 string) (bool, error) {
+	c, err := integerOrdering(s1, s2)
+	if err != nil {
+		return false, err
+	}
+	return c == 0, nil
+}
+
+func generalizedTimeMatch(s1, s2 string) (bool, error) {
+	c, err := generalizedTimeOrdering(s1, s2)
+	if err != nil {
+		return false, err
+	}
+	return c == 0, nil
+}
+
+func uuidMatch(s1, s2 string) (bool, error) {
+	c, err := uuidOrdering(s1, s2)
+	if err != nil {
+		return false, err
+	}
+	return c == 0, nil
+}
+
+// wordMatch and keywordMatch are true if the assertion is any of the space
+// separated words in the value
+func wordMatch(val, assertion string) (bool, error) {
+	v, err := caseIgnorePrep.prepare(val, prepValue)
+	if err != nil {
+		return false, err
+	}
+	a, err := caseIgnorePrep.prepare(assertion, prepValue)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(strings.Fields(v), strings.TrimSpace(a)), nil
+}
+
+// Postal addresses are lists of lines that must all match (RFC 4517 4.2.5)
+func caseIgnoreListMatch(val, assertion string) (bool, error) {
+	l1, err := parsePostalAddress(val)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+	l2, err := parsePostalAddress(assertion)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+
+	if len(l1) != len(l2) {
+		return false, nil
+	}
+
+	for i := range l1 {
+		ok, err := stringMatch(caseIgnorePrep)(l1[i], l2[i])
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// The substrings are matched against the lines concatenated together but
+// cannot span more than one line (RFC 4517 4.2.6)
+func caseIgnoreListSubstringsMatch(val, assertion string) (bool, error) {
+	lines, err := parsePostalAddress(val)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+
+	sa, err := parseSubstringAssertion(assertion)
+	if err != nil {
+		return false, err
+	}
+
+	// prepared strings cannot contain control characters, so NUL is used to
+	// stop substrings matching across lines
+	for i, line := range lines {
+		if lines[i], err = caseIgnorePrep.prepare(line, prepValue); err != nil {
+			return false, err
+		}
+	}
+
+	if sa.initial != "" {
+		if sa.initial, err = caseIgnorePrep.prepare(sa.initial, prepInitial); err != nil {
+			return false, err
+		}
+	}
+	for i := range sa.any {
+		if sa.any[i], err = caseIgnorePrep.prepare(sa.any[i], prepAny); err != nil {
+			return false, err
+		}
+	}
+	if sa.final != "" {
+		if sa.final, err = caseIgnorePrep.prepare(sa.final, prepFinal); err != nil {
+			return false, err
+		}
+	}
+
+	return matchSubstr(sa, strings.Join(lines, "\x00")), nil
+}
+
+// Returns the first component of a value such as an attribute type
+// description, ie the 2.5.4.3 of ( 2.5.4.3 NAME 'cn' ... )
+func firstComponent(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		return "", UndefinedMatch
+	}
+	s = strings.TrimLeft(s[1:], " ")
+
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", UndefinedMatch
+		}
+		return s[1 : end+1], nil
+	}
+
+	end := strings.IndexAny(s, " )")
+	if end <= 0 {
+		return "", UndefinedMatch
+	}
+	return s[:end], nil
+}
+
+// Matches the assertion against the first component of the value with match
+func firstComponentMatch(match func(string, string) (bool, error)) func(string, string) (bool, error) {
+	return func(val, assertion string) (bool, error) {
+		first, err := firstComponent(val)
+		if err != nil {
+			return false, err
+		}
+		return match(first, assertion)
+	}
+}
+
+// Both dns are resolved through the schema, so attribute types match by oid
+// and each value is compared with the equality rule of its attribute
+func distinguishedNameSchemaMatch(schema *Schema, s1, s2 string) (bool, error) {
+	dn1, err := NormaliseDN(schema, s1)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+	dn2, err := NormaliseDN(schema, s2)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+	return CompareDNs(dn1, dn2), nil
+}
+
+// Without the schema attribute types are compared by name and every value is
+// compared with caseIgnoreMatch
+func distinguishedNameMatch(s1, s2 string) (bool, error) {
+	dn1, err := parseDnString(s1)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+	dn2, err := parseDnString(s2)
+	if err != nil {
+		return false, UndefinedMatch
+	}
+
+	if len(dn1) != len(dn2) {
+		return false, nil
+	}
+
+	for i := range dn1 {
+		if len(dn1[i]) != len(dn2[i]) {
+			return false, nil
+		}
+
+		for _, ava1 := range dn1[i] {
+			found := false
+			for _, ava2 := range dn2[i] {
+				if !strings.EqualFold(ava1.attrType, ava2.attrType) {
+					continue
+				}
+				ok, err := stringMatch(caseIgnorePrep)(ava1.value, ava2.value)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// Splits a Name and Optional UID into the dn and the optional bit string uid
+func splitNameAndOptionalUid(s string) (string, string) {
+	i := strings.LastIndex(s, "#'")
+	if i < 0 || !strings.HasSuffix(s, "'B") {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+// Compares the dns with dnMatch, the uids are only compared if both values
+// have one (RFC 4517 4.2.31)
+func uniqueMemberMatch(dnMatch func(string, string) (bool, error)) func(string, string) (bool, error) {
+	return func(val, assertion string) (bool, error) {
+		dn1, uid1 := splitNameAndOptionalUid(val)
+		dn2, uid2 := splitNameAndOptionalUid(assertion)
+
+		ok, err := dnMatch(dn1, dn2)
+		if err != nil || !ok {
+			return false, err
+		}
+
+		if uid1 == "" || uid2 == "" {
+			return true, nil
+		}
+		return bitStringMatch(uid1, uid2)
+	}
 }
 
 type substringAssertion struct {
@@ -177,11 +598,197 @@ type substringAssertion struct {
 	final   string
 }
 
-// TODO asterisk and backslash escaping
-// func parseSubstringAssertion(s string) (substringAssertion, error) {
+// Parses the substring assertion syntax (RFC 4517 3.3.30), where * separates
+// the substrings and a literal * or \ is escaped as \2A or \5C
+func parseSubstringAssertion(s string) (substringAssertion, error) {
+	subs := []string{}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*':
+			subs = append(subs, sb.String())
+			sb.Reset()
+		case '\\':
+			if i+2 >= len(s) {
+				return substringAssertion{}, UndefinedMatch
+			}
+			switch strings.ToUpper(s[i+1 : i+3]) {
+			case "2A":
+				sb.WriteByte('*')
+			case "5C":
+				sb.WriteByte('\\')
+			default:
+				return substringAssertion{}, UndefinedMatch
+			}
+			i += 2
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	subs = append(subs, sb.String())
+
+	// there must be at least one * and the any substrings cannot be empty
+	if len(subs) < 2 || (len(subs) == 2 && subs[0] == "" && subs[1] == "") {
+		return substringAssertion{}, UndefinedMatch
+	}
+
+	sa := substringAssertion{initial: subs[0], any: []string{}, final: subs[len(subs)-1]}
+	for _, sub := range subs[1 : len(subs)-1] {
+		if sub == "" {
+			return substringAssertion{}, UndefinedMatch
+		}
+		sa.any = append(sa.any, sub)
+	}
+
+	return sa, nil
+}
+
+func escapeSubstring(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\5C")
+	return strings.ReplaceAll(s, "*", "\\2A")
+}
+
+func (sa substringAssertion) String() string {
+	var sb strings.Builder
+	sb.WriteString(escapeSubstring(sa.initial))
+	sb.WriteByte('*')
+	for _, sub := range sa.any {
+		sb.WriteString(escapeSubstring(sub))
+		sb.WriteByte('*')
+	}
+	sb.WriteString(escapeSubstring(sa.final))
+	return sb.String()
+}
+
+// Checks that initial starts val, each of any appear in order after that and
+// that final ends whatever is left, none of the substrings can overlap
+func matchSubstr(sa substringAssertion, val string) bool {
+	if !strings.HasPrefix(val, sa.initial) {
+		return false
+	}
+	val = val[len(sa.initial):]
+
+	for _, sub := range sa.any {
+		i := strings.Index(val, sub)
+		if i < 0 {
+			return false
+		}
+		val = val[i+len(sub):]
+	}
+
+	return strings.HasSuffix(val, sa.final)
+}
+
+// Builds a substring matching rule where both the value and every substring
+// are first prepared with prep
+func substringsMatch(prep stringPrep) func(string, string) (bool, error) {
+	return func(val, assertion string) (bool, error) {
+		sa, err := parseSubstringAssertion(assertion)
+		if err != nil {
+			return false, err
+		}
+
+		// an empty initial or final means it was not given so it is left empty
+		if sa.initial != "" {
+			if sa.initial, err = prep.prepare(sa.initial, prepInitial); err != nil {
+				return false, err
+			}
+		}
+		for i := range sa.any {
+			if sa.any[i], err = prep.prepare(sa.any[i], prepAny); err != nil {
+				return false, err
+			}
+		}
+		if sa.final != "" {
+			if sa.final, err = prep.prepare(sa.final, prepFinal); err != nil {
+				return false, err
+			}
+		}
+
+		val, err = prep.prepare(val, prepValue)
+		if err != nil {
+			return false, err
+		}
+
+		return matchSubstr(sa, val), nil
+	}
+}
+
+// Builds an equality matching rule that compares the prepared strings
+func stringMatch(prep stringPrep) func(string, string) (bool, error) {
+	return func(s1, s2 string) (bool, error) {
+		p1, err := prep.prepare(s1, prepValue)
+		if err != nil {
+			return false, err
+		}
+		p2, err := prep.prepare(s2, prepValue)
+		if err != nil {
+			return false, err
+		}
+		return p1 == p2, nil
+	}
+}
 
-// }
+// An ordering rule used as a match evaluates to true when the attribute value
+// is less than the asserted value (RFC 4517 4.1)
+func orderingMatch(compare func(string, string) (int, error)) func(string, string) (bool, error) {
+	return func(v1, v2 string) (bool, error) {
+		c, err := compare(v1, v2)
+		if err != nil {
+			return false, err
+		}
+		return c < 0, nil
+	}
+}
 
-// func matchSubstr(subAssert, val string) (bool, error) {
+// Compares the prepared strings by code point
+func prepOrdering(prep stringPrep) func(string, string) (int, error) {
+	return func(s1, s2 string) (int, error) {
+		p1, err := prep.prepare(s1, prepValue)
+		if err != nil {
+			return 0, err
+		}
+		p2, err := prep.prepare(s2, prepValue)
+		if err != nil {
+			return 0, err
+		}
+		return strings.Compare(p1, p2), nil
+	}
+}
 
-// }
+func octetStringOrdering(s1, s2 string) (int, error) {
+	return strings.Compare(s1, s2), nil
+}
+
+func integerOrdering(s1, s2 string) (int, error) {
+	i1, err := parseInteger(s1)
+	if err != nil {
+		return 0, UndefinedMatch
+	}
+	i2, err := parseInteger(s2)
+	if err != nil {
+		return 0, UndefinedMatch
+	}
+	return i1.Cmp(i2), nil
+}
+
+func generalizedTimeOrdering(s1, s2 string) (int, error) {
+	t1, err := parseGeneralizedTime(s1)
+	if err != nil {
+		return 0, UndefinedMatch
+	}
+	t2, err := parseGeneralizedTime(s2)
+	if err != nil {
+		return 0, UndefinedMatch
+	}
+	return t1.Compare(t2), nil
+}
+
+// UUIDs are ordered by their octets, which is the order of their hex digits
+// ignoring case (RFC 4530 3.2)
+func uuidOrdering(s1, s2 string) (int, error) {
+	if validateUUID(s1) != nil || validateUUID(s2) != nil {
+		return 0, UndefinedMatch
+	}
+	return strings.Compare(strings.ToLower(s1), strings.ToLower(s2)), nil
+}
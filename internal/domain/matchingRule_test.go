@@ -43,3 +43,311 @@ func TestCaseIgnoreMatch(t *testing.T) {
 	caseIgnore := util.Unwrap(GetMatchingRule("caseIgnoreMatch"))
 	testMatchingRules(tests, caseIgnore, t)
 }
+
+func TestCaseIgnoreSubstringsMatch(t *testing.T) {
+	tests := []matchingRuleTest{
+		{v1: "Dexter", v2: "dex*", exp: true, expErr: nil},
+		{v1: "Dexter", v2: "*TER", exp: true, expErr: nil},
+		{v1: "Dexter", v2: "d*x*e*r", exp: true, expErr: nil},
+		{v1: "Dexter", v2: "de*xt*er", exp: true, expErr: nil},
+		{v1: "Dexter", v2: "dex*xter", exp: false, expErr: nil},
+		{v1: "Dexter", v2: "*ter*dex*", exp: false, expErr: nil},
+		{v1: "a*b", v2: "a\\2A*", exp: true, expErr: nil},
+		{v1: "a\\b", v2: "*\\5cb", exp: true, expErr: nil},
+		{v1: "Dexter", v2: "dexter", exp: false, expErr: UndefinedMatch},
+		{v1: "Dexter", v2: "d**r", exp: false, expErr: UndefinedMatch},
+		{v1: "Dexter", v2: "d\\zz*", exp: false, expErr: UndefinedMatch},
+	}
+	caseIgnoreSubstrings := util.Unwrap(GetMatchingRule("caseIgnoreSubstringsMatch"))
+	testMatchingRules(tests, caseIgnoreSubstrings, t)
+}
+
+func TestNumericStringSubstringsMatch(t *testing.T) {
+	tests := []matchingRuleTest{
+		{v1: "123 456 789", v2: "123*789", exp: true, expErr: nil},
+		{v1: "123 456 789", v2: "*34 5*", exp: true, expErr: nil},
+		{v1: "123 456 789", v2: "*98*", exp: false, expErr: nil},
+	}
+	numericSubstrings := util.Unwrap(GetMatchingRule("numericStringSubstringsMatch"))
+	testMatchingRules(tests, numericSubstrings, t)
+}
+
+func TestTelephoneNumberSubstringsMatch(t *testing.T) {
+	tests := []matchingRuleTest{
+		{v1: "+61 2-9999-1234", v2: "+612*1234", exp: true, expErr: nil},
+		{v1: "+61 2-9999-1234", v2: "*99 99*", exp: true, expErr: nil},
+		{v1: "+61 2-9999-1234", v2: "+1*", exp: false, expErr: nil},
+	}
+	telephoneSubstrings := util.Unwrap(GetMatchingRule("telephoneNumberSubstringsMatch"))
+	testMatchingRules(tests, telephoneSubstrings, t)
+}
+
+type orderingRuleTest struct {
+	v1, v2 string
+	exp    int
+	expErr error
+}
+
+func testOrderingRules(ors []orderingRuleTest, mr MatchingRule, t *testing.T) {
+	for _, o := range ors {
+		res, err := mr.Compare(o.v1, o.v2)
+		if err != o.expErr {
+			t.Errorf("in comparing %q and %q\texpected err: %s, got error: %s", o.v1, o.v2, o.expErr, err)
+		}
+
+		if res != o.exp {
+			t.Errorf("in comparing %q and %q\texpected res: %d, got: %d", o.v1, o.v2, o.exp, res)
+		}
+	}
+}
+
+func TestCaseIgnoreOrderingMatch(t *testing.T) {
+	tests := []orderingRuleTest{
+		{v1: "abc", v2: "ABD", exp: -1, expErr: nil},
+		{v1: "ABC", v2: "abc", exp: 0, expErr: nil},
+		{v1: "b", v2: "Abc", exp: 1, expErr: nil},
+	}
+	caseIgnoreOrdering := util.Unwrap(GetMatchingRule("caseIgnoreOrderingMatch"))
+	testOrderingRules(tests, caseIgnoreOrdering, t)
+
+	// as a match the ordering rule is true if the value is less than the assertion
+	testMatchingRules([]matchingRuleTest{
+		{v1: "abc", v2: "abd", exp: true, expErr: nil},
+		{v1: "abd", v2: "abc", exp: false, expErr: nil},
+	}, caseIgnoreOrdering, t)
+}
+
+func TestIntegerOrderingMatch(t *testing.T) {
+	tests := []orderingRuleTest{
+		{v1: "9", v2: "10", exp: -1, expErr: nil},
+		{v1: "-10", v2: "-9", exp: -1, expErr: nil},
+		{v1: "123456789012345678901234567890", v2: "123456789012345678901234567890", exp: 0, expErr: nil},
+		{v1: "100", v2: "-100", exp: 1, expErr: nil},
+		{v1: "010", v2: "10", exp: 0, expErr: UndefinedMatch},
+		{v1: "ten", v2: "10", exp: 0, expErr: UndefinedMatch},
+	}
+	integerOrdering := util.Unwrap(GetMatchingRule("integerOrderingMatch"))
+	testOrderingRules(tests, integerOrdering, t)
+}
+
+func TestGeneralizedTimeOrderingMatch(t *testing.T) {
+	tests := []orderingRuleTest{
+		{v1: "20240101000000Z", v2: "20240101000001Z", exp: -1, expErr: nil},
+		{v1: "2024010112Z", v2: "202401011200Z", exp: 0, expErr: nil},
+		{v1: "20240101120000+1000", v2: "20240101020000Z", exp: 0, expErr: nil},
+		{v1: "20240101120000.5Z", v2: "20240101120000Z", exp: 1, expErr: nil},
+		{v1: "2024010112.5Z", v2: "202401011230Z", exp: 0, expErr: nil},
+		{v1: "20240101120000", v2: "20240101120000Z", exp: 0, expErr: UndefinedMatch},
+		{v1: "20241301120000Z", v2: "20240101120000Z", exp: 0, expErr: UndefinedMatch},
+	}
+	generalizedTimeOrdering := util.Unwrap(GetMatchingRule("generalizedTimeOrderingMatch"))
+	testOrderingRules(tests, generalizedTimeOrdering, t)
+}
+
+func TestUUIDOrderingMatch(t *testing.T) {
+	tests := []orderingRuleTest{
+		{v1: "597ae2f6-16a6-1027-98f4-d28b5365dc14", v2: "597ae2f6-16a6-1027-98f4-d28b5365dc15", exp: -1, expErr: nil},
+		{v1: "A97ae2f6-16a6-1027-98f4-d28b5365dc14", v2: "597ae2f6-16a6-1027-98f4-d28b5365dc14", exp: 1, expErr: nil},
+		{v1: "597AE2F6-16A6-1027-98F4-D28B5365DC14", v2: "597ae2f6-16a6-1027-98f4-d28b5365dc14", exp: 0, expErr: nil},
+		{v1: "not a uuid", v2: "597ae2f6-16a6-1027-98f4-d28b5365dc14", exp: 0, expErr: UndefinedMatch},
+	}
+	uuidOrdering := util.Unwrap(GetMatchingRule("uuidOrderingMatch"))
+	testOrderingRules(tests, uuidOrdering, t)
+}
+
+func TestCaseIgnoreMatchPreparesStrings(t *testing.T) {
+	tests := []matchingRuleTest{
+		{v1: "  Zoë   Smith ", v2: "zoë smith", exp: true, expErr: nil},
+		{v1: "Zoë Smith", v2: "ZOË SMITH", exp: true, expErr: nil},
+		{v1: "Straße", v2: "STRASSE", exp: true, expErr: nil},
+		{v1: "Zoë Smith", v2: "Zoe Smith", exp: false, expErr: nil},
+		{v1: "Zoë\uE000", v2: "Zoë", exp: false, expErr: UndefinedMatch},
+	}
+	caseIgnore := util.Unwrap(GetMatchingRule("caseIgnoreMatch"))
+	testMatchingRules(tests, caseIgnore, t)
+
+	substrTests := []matchingRuleTest{
+		{v1: "  Zoë   Smith ", v2: "zoë s*", exp: true, expErr: nil},
+		{v1: "  Zoë   Smith ", v2: "*ë s*", exp: true, expErr: nil},
+		{v1: "  Zoë   Smith ", v2: "*SMITH", exp: true, expErr: nil},
+		{v1: "  Zoë   Smith ", v2: "*ë*", exp: true, expErr: nil},
+		{v1: "  Zoë   Smith ", v2: "oë*", exp: false, expErr: nil},
+	}
+	caseIgnoreSubstrings := util.Unwrap(GetMatchingRule("caseIgnoreSubstringsMatch"))
+	testMatchingRules(substrTests, caseIgnoreSubstrings, t)
+}
+
+func TestNumericAndTelephoneMatch(t *testing.T) {
+	numericString := util.Unwrap(GetMatchingRule("numericStringMatch"))
+	testMatchingRules([]matchingRuleTest{
+		{v1: "123 456", v2: "123456", exp: true, expErr: nil},
+		{v1: "123 456", v2: "123457", exp: false, expErr: nil},
+	}, numericString, t)
+
+	telephoneNumber := util.Unwrap(GetMatchingRule("telephoneNumberMatch"))
+	testMatchingRules([]matchingRuleTest{
+		{v1: "+61 2 9999-1234", v2: "+61299991234", exp: true, expErr: nil},
+		{v1: "+61 2 9999-1234", v2: "+61299991235", exp: false, expErr: nil},
+	}, telephoneNumber, t)
+}
+
+func TestRfc4517EqualityRules(t *testing.T) {
+	tests := map[string][]matchingRuleTest{
+		"booleanMatch": {
+			{v1: "TRUE", v2: "TRUE", exp: true, expErr: nil},
+			{v1: "TRUE", v2: "FALSE", exp: false, expErr: nil},
+			{v1: "TRUE", v2: "true", exp: false, expErr: UndefinedMatch},
+		},
+		"caseExactMatch": {
+			{v1: " Test  One", v2: "Test One", exp: true, expErr: nil},
+			{v1: "Test One", v2: "test one", exp: false, expErr: nil},
+		},
+		"caseExactIA5Match": {
+			{v1: "Test", v2: "Test", exp: true, expErr: nil},
+			{v1: "Test", v2: "TEST", exp: false, expErr: nil},
+		},
+		"caseExactSubstringsMatch": {
+			{v1: "Test One", v2: "Test*", exp: true, expErr: nil},
+			{v1: "Test One", v2: "test*", exp: false, expErr: nil},
+		},
+		"caseIgnoreListMatch": {
+			{v1: "1 Main St$Sydney", v2: "1 MAIN ST$sydney", exp: true, expErr: nil},
+			{v1: "1 Main St$Sydney", v2: "1 Main St", exp: false, expErr: nil},
+			{v1: "Cost \\24100$Sydney", v2: "cost $100$sydney", exp: false, expErr: nil},
+		},
+		"caseIgnoreListSubstringsMatch": {
+			{v1: "1 Main St$Sydney", v2: "*main*syd*", exp: true, expErr: nil},
+			{v1: "1 Main St$Sydney", v2: "*stsyd*", exp: false, expErr: nil},
+		},
+		"directoryStringFirstComponentMatch": {
+			{v1: "( 'Test' 'other' )", v2: "test", exp: true, expErr: nil},
+			{v1: "( 'Test' 'other' )", v2: "other", exp: false, expErr: nil},
+		},
+		"distinguishedNameMatch": {
+			{v1: "cn=Test1,dc=georgiboy,dc=dev", v2: "CN=test1, DC=Georgiboy, DC=dev", exp: true, expErr: nil},
+			{v1: "cn=Test1+sn=One,dc=dev", v2: "sn=one+cn=test1,dc=dev", exp: true, expErr: nil},
+			{v1: "cn=Test\\2c1,dc=dev", v2: "cn=Test\\,1,dc=dev", exp: true, expErr: nil},
+			{v1: "cn=Test1,dc=dev", v2: "cn=Test1", exp: false, expErr: nil},
+			{v1: "cn=Test1,dc=dev", v2: "cn=Test1,,", exp: false, expErr: UndefinedMatch},
+		},
+		"generalizedTimeMatch": {
+			{v1: "20240101120000Z", v2: "20240101220000+1000", exp: true, expErr: nil},
+			{v1: "20240101120000Z", v2: "20240101120001Z", exp: false, expErr: nil},
+		},
+		"integerFirstComponentMatch": {
+			{v1: "( 1 NAME 'rule' FORM form )", v2: "1", exp: true, expErr: nil},
+			{v1: "( 1 NAME 'rule' FORM form )", v2: "2", exp: false, expErr: nil},
+		},
+		"integerMatch": {
+			{v1: "-42", v2: "-42", exp: true, expErr: nil},
+			{v1: "42", v2: "042", exp: false, expErr: UndefinedMatch},
+		},
+		"keywordMatch": {
+			{v1: "red green blue", v2: "GREEN", exp: true, expErr: nil},
+			{v1: "red green blue", v2: "gre", exp: false, expErr: nil},
+		},
+		"objectIdentifierFirstComponentMatch": {
+			{v1: "( 2.5.4.3 NAME 'cn' SUP name )", v2: "2.5.4.3", exp: true, expErr: nil},
+			{v1: "( 2.5.4.3 NAME 'cn' SUP name )", v2: "2.5.4.4", exp: false, expErr: nil},
+		},
+		"objectIdentifierMatch": {
+			{v1: "person", v2: "Person", exp: true, expErr: nil},
+			{v1: "2.5.6.6", v2: "2.5.6.6", exp: true, expErr: nil},
+			{v1: "2.5.6.6", v2: "2.5.6.7", exp: false, expErr: nil},
+		},
+		"uuidMatch": {
+			{v1: "597ae2f6-16a6-1027-98f4-d28b5365dc14", v2: "597AE2F6-16A6-1027-98F4-D28B5365DC14", exp: true, expErr: nil},
+			{v1: "597ae2f6-16a6-1027-98f4-d28b5365dc14", v2: "597ae2f6-16a6-1027-98f4-d28b5365dc15", exp: false, expErr: nil},
+			{v1: "597ae2f6-16a6-1027-98f4-d28b5365dc14", v2: "597ae2f6", exp: false, expErr: UndefinedMatch},
+		},
+		"uniqueMemberMatch": {
+			{v1: "cn=Test1,dc=dev#'0101'B", v2: "cn=test1,dc=dev", exp: true, expErr: nil},
+			{v1: "cn=Test1,dc=dev#'0101'B", v2: "cn=test1,dc=dev#'0101'B", exp: true, expErr: nil},
+			{v1: "cn=Test1,dc=dev#'0101'B", v2: "cn=test1,dc=dev#'0111'B", exp: false, expErr: nil},
+		},
+	}
+
+	for name, ruleTests := range tests {
+		rule, err := GetMatchingRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testMatchingRules(ruleTests, rule, t)
+	}
+}
+
+func TestSchemaRulesResolveNames(t *testing.T) {
+	tests := map[string][]matchingRuleTest{
+		"distinguishedNameMatch": {
+			{v1: "cn=Test1,dc=georgiboy,dc=dev", v2: "2.5.4.3=test1,0.9.2342.19200300.100.1.25=Georgiboy,DC=dev", exp: true, expErr: nil},
+			{v1: "cn=Test  One,dc=dev", v2: "CN=test one,dc=dev", exp: true, expErr: nil},
+			{v1: "cn=Test1,dc=dev", v2: "sn=Test1,dc=dev", exp: false, expErr: nil},
+			{v1: "cn=Test1,dc=dev", v2: "unknownAttr=Test1,dc=dev", exp: false, expErr: UndefinedMatch},
+		},
+		"objectIdentifierMatch": {
+			{v1: "person", v2: "2.5.6.6", exp: true, expErr: nil},
+			{v1: "2.5.4.3", v2: "CN", exp: true, expErr: nil},
+			{v1: "top", v2: "2.5.6.0", exp: true, expErr: nil},
+			{v1: "caseIgnoreMatch", v2: "2.5.13.2", exp: true, expErr: nil},
+			{v1: "person", v2: "2.5.6.7", exp: false, expErr: nil},
+			{v1: "unknownThing", v2: "UNKNOWNTHING", exp: true, expErr: nil},
+		},
+		"objectIdentifierFirstComponentMatch": {
+			{v1: "( 2.5.4.3 NAME 'cn' SUP name )", v2: "cn", exp: true, expErr: nil},
+			{v1: "( 2.5.4.3 NAME 'cn' SUP name )", v2: "sn", exp: false, expErr: nil},
+		},
+		"uniqueMemberMatch": {
+			{v1: "cn=Test1,dc=dev#'0101'B", v2: "2.5.4.3=test1,dc=dev", exp: true, expErr: nil},
+		},
+	}
+
+	for name, ruleTests := range tests {
+		rule := util.Unwrap(GetMatchingRule(name))
+		for _, m := range ruleTests {
+			res, err := rule.MatchIn(schema, m.v1, m.v2)
+			if err != m.expErr {
+				t.Errorf("%s: in matching %q and %q\texpected err: %s, got error: %s", name, m.v1, m.v2, m.expErr, err)
+			}
+			if res != m.exp {
+				t.Errorf("%s: in matching %q and %q\texpected res: %t, got: %t", name, m.v1, m.v2, m.exp, res)
+			}
+		}
+	}
+}
+
+func TestRfc4517OrderingRules(t *testing.T) {
+	tests := map[string][]orderingRuleTest{
+		"caseExactOrderingMatch": {
+			{v1: "B", v2: "a", exp: -1, expErr: nil},
+			{v1: "a", v2: "a", exp: 0, expErr: nil},
+		},
+		"numericStringOrderingMatch": {
+			{v1: "1 23", v2: "124", exp: -1, expErr: nil},
+			{v1: "1 24", v2: "124", exp: 0, expErr: nil},
+		},
+		"octetStringOrderingMatch": {
+			{v1: "ab", v2: "abc", exp: -1, expErr: nil},
+			{v1: "b", v2: "abc", exp: 1, expErr: nil},
+		},
+	}
+
+	for name, ruleTests := range tests {
+		rule, err := GetMatchingRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testOrderingRules(ruleTests, rule, t)
+	}
+}
+
+func TestGetMatchingRuleIgnoresCase(t *testing.T) {
+	for _, name := range []string{"caseExactMatch", "CASEEXACTMATCH", "2.5.13.5"} {
+		rule, err := GetMatchingRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rule.Name() != "caseExactMatch" {
+			t.Fatalf("%q returned %q", name, rule.Name())
+		}
+	}
+}
@@ -285,6 +285,8 @@ type LdifOcResolver struct {
 	ldif  []*LdifObjectClass
 	attrs map[OID]*Attribute
 	ocs   map[OID]*ObjectClass
+	// sups that are not being parsed are looked up in schema if not nil
+	schema *Schema
 }
 
 func NewLdifOcResolver(ldifs []*LdifObjectClass, attrs map[OID]*Attribute) *LdifOcResolver {
@@ -295,6 +297,13 @@ func NewLdifOcResolver(ldifs []*LdifObjectClass, attrs map[OID]*Attribute) *Ldif
 	}
 }
 
+// Resolves sups against the object classes already in schema as well as the
+// ones being parsed
+func (r *LdifOcResolver) WithSchema(schema *Schema) *LdifOcResolver {
+	r.schema = schema
+	return r
+}
+
 func (r *LdifOcResolver) Resolve() (map[OID]*ObjectClass, error) {
 	for _, l := range r.ldif {
 		if err := l.Build(r); err != nil {
@@ -310,7 +319,8 @@ func (r *LdifOcResolver) supByOid(o OID) *ObjectClass {
 		return sup
 	}
 
-	sup = new(ObjectClass)
+	// the oid is set now as subtypes key their sups by it before it is built
+	sup = &ObjectClass{numericoid: o}
 	r.ocs[o] = sup
 	return sup
 }
@@ -327,6 +337,12 @@ func (r *LdifOcResolver) GetSup(nameOrOid string) (*ObjectClass, error) {
 		}
 	}
 
+	if r.schema != nil {
+		if o, ok := r.schema.FindObjectClass(nameOrOid); ok {
+			return o, nil
+		}
+	}
+
 	return nil, fmt.Errorf("could not find objclass sup %q", nameOrOid)
 }
 
@@ -507,7 +523,7 @@ func (o *LdifObjectClass) setDesc(t *Tokeniser) error {
 	if err != nil {
 		return err
 	}
-	o.desc = stripQuotes(desc.val)
+	o.desc = unescapeQdstring(stripQuotes(desc.val))
 	return nil
 }
 
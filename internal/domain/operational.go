@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+// attributes the server keeps on every entry it creates or modifies (RFC 4512 3.4)
+var CreatorsNameAttribute = NewAttributeBuilder().
+	SetOid("2.5.18.3").
+	AddNames("creatorsName").
+	SetEqRule(util.Unwrap(GetMatchingRule("distinguishedNameMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.12")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var CreateTimestampAttribute = NewAttributeBuilder().
+	SetOid("2.5.18.1").
+	AddNames("createTimestamp").
+	SetEqRule(util.Unwrap(GetMatchingRule("generalizedTimeMatch"))).
+	SetOrdRule(util.Unwrap(GetMatchingRule("generalizedTimeOrderingMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.24")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var ModifiersNameAttribute = NewAttributeBuilder().
+	SetOid("2.5.18.4").
+	AddNames("modifiersName").
+	SetEqRule(util.Unwrap(GetMatchingRule("distinguishedNameMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.12")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var ModifyTimestampAttribute = NewAttributeBuilder().
+	SetOid("2.5.18.2").
+	AddNames("modifyTimestamp").
+	SetEqRule(util.Unwrap(GetMatchingRule("generalizedTimeMatch"))).
+	SetOrdRule(util.Unwrap(GetMatchingRule("generalizedTimeOrderingMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.24")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+// RFC 4530 2.2
+var EntryUUIDAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.1.16.4").
+	AddNames("entryUUID").
+	SetEqRule(util.Unwrap(GetMatchingRule("uuidMatch"))).
+	SetOrdRule(util.Unwrap(GetMatchingRule("uuidOrderingMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.1.16.1")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+// RFC 5020 2, the values are built from the entry's dn rather than stored
+var EntryDNAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.1.20").
+	AddNames("entryDN").
+	SetEqRule(util.Unwrap(GetMatchingRule("distinguishedNameMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.12")), 0).
+	SetSingleVal(true).
+	SetNoUserMod(true).
+	SetUsage(DirectoryOperations).
+	Build()
+
+// Who made a change to the DIT and when, kept in the operational attributes
+// of the entries it changes. Anonymous changes are made by the empty dn.
+type ChangeStamp struct {
+	By DN
+	At time.Time
+}
+
+func NewChangeStamp(by DN) ChangeStamp {
+	return ChangeStamp{By: by, At: time.Now()}
+}
+
+// GeneralizedTime in UTC (RFC 4517 3.3.13)
+func (s ChangeStamp) timestamp() string {
+	return s.At.UTC().Format("20060102150405Z")
+}
+
+// A random (version 4) UUID (RFC 4122 4.4)
+func newUUID() string {
+	b := make([]byte, 16)
+	// never returns an error, it crashes the program instead
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (e *Entry) setOperational(attr *Attribute, val string) {
+	e.attrs[attr] = map[string]struct{}{val: {}}
+}
+
+// Records the creation of a new entry, which also counts as its last modification
+func (e *Entry) stampCreated(s ChangeStamp) {
+	e.setOperational(CreatorsNameAttribute, s.By.String())
+	e.setOperational(CreateTimestampAttribute, s.timestamp())
+	e.setOperational(EntryUUIDAttribute, newUUID())
+	e.stampModified(s)
+}
+
+func (e *Entry) stampModified(s ChangeStamp) {
+	e.setOperational(ModifiersNameAttribute, s.By.String())
+	e.setOperational(ModifyTimestampAttribute, s.timestamp())
+}
@@ -3,6 +3,8 @@ package domain
 import (
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 )
 
 type OID string
@@ -12,6 +14,8 @@ type SchemaObject interface {
 }
 
 type Schema struct {
+	// guards the maps, which change when the schema is modified online
+	mu         sync.RWMutex
 	attributes map[OID]*Attribute
 	objClasses map[OID]*ObjectClass
 }
@@ -23,23 +27,63 @@ func NewSchema(attrs map[OID]*Attribute, objClasses map[OID]*ObjectClass) *Schem
 	}
 }
 
+// Finds an attribute by one of its names or its numericoid, names are case
+// insensitive (RFC 4512 2.5)
 func (s *Schema) FindAttribute(name string) (*Attribute, bool) {
-	if name == "objectClass" {
+	if strings.EqualFold(name, "objectClass") || name == string(ObjectClassAttribute.numericoid) {
 		return ObjectClassAttribute, true
 	}
 
+	for _, a := range operationalAttributes {
+		if string(a.numericoid) == name || strings.EqualFold(a.Name(), name) {
+			return a, true
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if a, ok := s.attributes[OID(name)]; ok {
+		return a, true
+	}
+
 	for _, a := range s.attributes {
 		if _, ok := a.names[name]; ok {
 			return a, true
 		}
 	}
 
+	for _, a := range s.attributes {
+		for n := range a.names {
+			if strings.EqualFold(n, name) {
+				return a, true
+			}
+		}
+	}
+
 	return nil, false
 }
 
+// Finds the attribute for an attribute description, ignoring any options
+// such as ;binary or ;lang-en (RFC 4512 2.5)
+func (s *Schema) FindAttributeDesc(desc string) (*Attribute, bool) {
+	name, _, _ := strings.Cut(desc, ";")
+	return s.FindAttribute(name)
+}
+
+// Finds an object class by one of its names or its numericoid
 func (s *Schema) FindObjectClass(name string) (*ObjectClass, bool) {
-	if name == "top" {
-		return TopObjectClass, true
+	for _, o := range operationalObjectClasses {
+		if _, ok := o.names[name]; ok || string(o.numericoid) == name {
+			return o, true
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if o, ok := s.objClasses[OID(name)]; ok {
+		return o, true
 	}
 
 	for _, o := range s.objClasses {
@@ -51,6 +95,33 @@ func (s *Schema) FindObjectClass(name string) (*ObjectClass, bool) {
 	return nil, false
 }
 
+// Resolves a descriptor to the numericoid of the attribute type, object class
+// or matching rule it names, numericoids are returned as they are
+func (s *Schema) resolveOid(oid string) (OID, bool) {
+	if numericoid_re.MatchString(oid) {
+		return OID(oid), true
+	}
+
+	if a, ok := s.FindAttribute(oid); ok {
+		return a.numericoid, true
+	}
+
+	// unlike FindObjectClass, names are compared case insensitively
+	for _, o := range s.ObjectClasses() {
+		for n := range o.names {
+			if strings.EqualFold(n, oid) {
+				return o.numericoid, true
+			}
+		}
+	}
+
+	if mr, err := GetMatchingRule(oid); err == nil {
+		return mr.numericoid, true
+	}
+
+	return "", false
+}
+
 func (s *Schema) ValidateAttributeVals(attr *Attribute, vals map[string]struct{}) error {
 	if len(vals) == 0 {
 		return NewLdapError(ConstraintViolation, nil, "Attribute %q exists for entry but has no given values", attr.Name())
@@ -80,6 +151,8 @@ func (s *Schema) ValidateAttributeVals(attr *Attribute, vals map[string]struct{}
 	return nil
 }
 
+// Checks e against its object classes. An entry has one structural class and
+// any number of auxiliary classes, but no abstract ones (RFC 4512 2.4)
 func (s *Schema) ValidateEntry(e *Entry) error {
 	if e.structural == nil {
 		return NewLdapError(ConstraintViolation, nil,
@@ -93,9 +166,11 @@ func (s *Schema) ValidateEntry(e *Entry) error {
 			)
 		}
 
-		return NewLdapError(ConstraintViolation, nil,
-			"An entry cannot have multiple structural object classes",
-		)
+		if oc.kind == Structural {
+			return NewLdapError(ConstraintViolation, nil,
+				"An entry cannot have multiple structural object classes",
+			)
+		}
 	}
 
 	allMust := AllObjectClassMusts(e)
@@ -0,0 +1,428 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The attribute type and object class descriptions that a modify of the
+// subschema subentry adds and deletes (RFC 4512 4.2). Existing descriptions
+// cannot be removed, but deleting one and adding it back with OBSOLETE marks
+// it obsolete.
+type SchemaModification struct {
+	AddAttrs, DeleteAttrs           []string
+	AddObjClasses, DeleteObjClasses []string
+}
+
+// A schema modification that has been checked against the schema, ready to apply
+type SchemaChange struct {
+	attrs      []*Attribute
+	objClasses []*ObjectClass
+	// existing definitions and whether they will be obsolete
+	obsoleteAttrs map[*Attribute]bool
+	obsoleteOcs   map[*ObjectClass]bool
+}
+
+// Parses a description value with parse, a value holds exactly one description
+func parseDescription[T any](desc string, parse func(*Tokeniser) (T, error)) (T, error) {
+	var zero T
+	t, err := NewTokeniser(strings.NewReader(desc))
+	if err != nil {
+		return zero, err
+	}
+	if !t.HasNext() {
+		return zero, errors.New("empty description")
+	}
+
+	sub, err := t.ParenSubTokeniser()
+	if err != nil {
+		return zero, err
+	}
+	if t.HasNext() {
+		return zero, errors.New("expected a single description")
+	}
+
+	v, err := parse(sub)
+	if err != nil {
+		return zero, err
+	}
+	if next, ok := sub.Peek(); ok {
+		return zero, fmt.Errorf("unexpected %q", next.val)
+	}
+	return v, nil
+}
+
+// Checks m against the schema, new definitions are resolved against the
+// schema as well as each other
+func NewSchemaChange(s *Schema, m SchemaModification) (*SchemaChange, error) {
+	c := &SchemaChange{
+		obsoleteAttrs: map[*Attribute]bool{},
+		obsoleteOcs:   map[*ObjectClass]bool{},
+	}
+
+	if err := s.changeAttrs(c, m.AddAttrs, m.DeleteAttrs); err != nil {
+		return nil, err
+	}
+
+	if err := s.changeObjClasses(c, m.AddObjClasses, m.DeleteObjClasses); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *Schema) ownAttribute(oid OID) (*Attribute, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.attributes[oid]
+	return a, ok
+}
+
+func (s *Schema) ownObjClass(oid OID) (*ObjectClass, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.objClasses[oid]
+	return o, ok
+}
+
+func (s *Schema) changeAttrs(c *SchemaChange, add, del []string) error {
+	replaced := map[OID]*Attribute{}
+	for _, desc := range del {
+		oid, err := firstComponent(desc)
+		if err != nil {
+			return NewLdapError(InvalidAttributeSyntax, nil, "invalid attribute type description %q", desc)
+		}
+
+		a, ok := s.ownAttribute(OID(oid))
+		if !ok {
+			if _, builtin := s.FindAttribute(oid); builtin {
+				return NewLdapError(UnwillingToPerform, nil, "attribute type %s is provided by the server and cannot be changed", oid)
+			}
+			return NewLdapError(NoSuchAttribute, nil, "attribute type %s is not in the schema", oid)
+		}
+		replaced[a.numericoid] = a
+	}
+
+	ldifs := []*LdifAttribute{}
+	// the lower cased names and oids being added
+	defined := map[string]struct{}{}
+	for _, desc := range add {
+		l, err := parseDescription(desc, NewLdifAttribute)
+		if err != nil {
+			return NewLdapError(InvalidAttributeSyntax, nil, "invalid attribute type description %q: %s", desc, err)
+		}
+
+		if existing, ok := replaced[OID(l.numericoid)]; ok {
+			if err := s.redefineAttr(c, existing, l); err != nil {
+				return err
+			}
+			delete(replaced, existing.numericoid)
+			continue
+		}
+
+		for _, name := range append([]string{l.numericoid}, l.names...) {
+			_, inSchema := s.FindAttribute(name)
+			_, adding := defined[strings.ToLower(name)]
+			if inSchema || adding {
+				return NewLdapError(AttributeOrValueExists, nil, "attribute type %s is already defined", name)
+			}
+			defined[strings.ToLower(name)] = struct{}{}
+		}
+		ldifs = append(ldifs, l)
+	}
+
+	if len(replaced) > 0 {
+		return NewLdapError(UnwillingToPerform, nil, "attribute types cannot be removed from the schema, only marked obsolete")
+	}
+
+	attrs, err := NewLdifAttrResolver(ldifs).WithSchema(s).Resolve()
+	if err != nil {
+		return NewLdapError(InvalidAttributeSyntax, nil, "could not resolve attribute types: %s", err)
+	}
+
+	for _, a := range attrs {
+		if err := validateNewAttr(a); err != nil {
+			return err
+		}
+		c.attrs = append(c.attrs, a)
+	}
+	return nil
+}
+
+// Only whether an attribute type is obsolete can change, anything else could
+// change the meaning of values that are already held
+func (s *Schema) redefineAttr(c *SchemaChange, existing *Attribute, l *LdifAttribute) error {
+	attrs, err := NewLdifAttrResolver([]*LdifAttribute{l}).WithSchema(s).Resolve()
+	if err != nil {
+		return NewLdapError(InvalidAttributeSyntax, nil, "could not resolve attribute type %s: %s", l.numericoid, err)
+	}
+
+	redefined := *attrs[existing.numericoid]
+	obsolete := redefined.obsolete
+	redefined.obsolete = existing.obsolete
+	if err := AttributesAreEqual(existing, &redefined); err != nil {
+		return NewLdapError(UnwillingToPerform, nil, "only whether attribute type %s is obsolete can change: %s", existing.Name(), err)
+	}
+
+	c.obsoleteAttrs[existing] = obsolete
+	return nil
+}
+
+// Checks the rules of RFC 4512 2.5 and 4.1.2 that parsing does not
+func validateNewAttr(a *Attribute) error {
+	seen := map[*Attribute]struct{}{}
+	for sup := a; sup != nil; sup = sup.sup {
+		if _, ok := seen[sup]; ok {
+			return NewLdapError(InvalidAttributeSyntax, nil, "attribute type %s is its own supertype", a.Name())
+		}
+		seen[sup] = struct{}{}
+	}
+
+	_, _, hasSyntax := a.Syntax()
+	switch {
+	case !hasSyntax:
+		return NewLdapError(InvalidAttributeSyntax, nil, "attribute type %s has no syntax", a.Name())
+	case a.sup != nil && a.sup.usage != a.usage:
+		return NewLdapError(InvalidAttributeSyntax, nil, "attribute type %s must have the same usage as its supertype", a.Name())
+	case a.collective && a.usage != UserApplications:
+		return NewLdapError(InvalidAttributeSyntax, nil, "collective attribute type %s must have userApplications usage", a.Name())
+	case a.noUserMod && a.usage == UserApplications:
+		return NewLdapError(InvalidAttributeSyntax, nil, "attribute type %s must be operational to be NO-USER-MODIFICATION", a.Name())
+	}
+	return nil
+}
+
+func (s *Schema) changeObjClasses(c *SchemaChange, add, del []string) error {
+	replaced := map[OID]*ObjectClass{}
+	for _, desc := range del {
+		oid, err := firstComponent(desc)
+		if err != nil {
+			return NewLdapError(InvalidAttributeSyntax, nil, "invalid object class description %q", desc)
+		}
+
+		o, ok := s.ownObjClass(OID(oid))
+		if !ok {
+			if _, builtin := s.FindObjectClass(oid); builtin {
+				return NewLdapError(UnwillingToPerform, nil, "object class %s is provided by the server and cannot be changed", oid)
+			}
+			return NewLdapError(NoSuchAttribute, nil, "object class %s is not in the schema", oid)
+		}
+		replaced[o.numericoid] = o
+	}
+
+	// musts and mays can be any attribute, including ones added by this change
+	attrs := map[OID]*Attribute{}
+	for _, a := range append(s.Attributes(), c.attrs...) {
+		attrs[a.numericoid] = a
+	}
+
+	ldifs := []*LdifObjectClass{}
+	defined := map[string]struct{}{}
+	for _, desc := range add {
+		l, err := parseDescription(desc, NewLdifObjectClass)
+		if err != nil {
+			return NewLdapError(InvalidAttributeSyntax, nil, "invalid object class description %q: %s", desc, err)
+		}
+
+		if existing, ok := replaced[OID(l.numericoid)]; ok {
+			if err := s.redefineObjClass(c, existing, l, attrs); err != nil {
+				return err
+			}
+			delete(replaced, existing.numericoid)
+			continue
+		}
+
+		for _, name := range append([]string{l.numericoid}, l.names...) {
+			_, inSchema := s.findObjClassFold(name)
+			_, adding := defined[strings.ToLower(name)]
+			if inSchema || adding {
+				return NewLdapError(AttributeOrValueExists, nil, "object class %s is already defined", name)
+			}
+			defined[strings.ToLower(name)] = struct{}{}
+		}
+		ldifs = append(ldifs, l)
+	}
+
+	if len(replaced) > 0 {
+		return NewLdapError(UnwillingToPerform, nil, "object classes cannot be removed from the schema, only marked obsolete")
+	}
+
+	ocs, err := NewLdifOcResolver(ldifs, attrs).WithSchema(s).Resolve()
+	if err != nil {
+		return NewLdapError(InvalidAttributeSyntax, nil, "could not resolve object classes: %s", err)
+	}
+
+	for _, o := range ocs {
+		if err := validateNewObjClass(o); err != nil {
+			return err
+		}
+		c.objClasses = append(c.objClasses, o)
+	}
+	return nil
+}
+
+// Object class names are compared case insensitively so that a new one cannot
+// differ from an existing one only by case
+func (s *Schema) findObjClassFold(name string) (*ObjectClass, bool) {
+	for _, o := range s.ObjectClasses() {
+		if strings.EqualFold(string(o.numericoid), name) {
+			return o, true
+		}
+		for n := range o.names {
+			if strings.EqualFold(n, name) {
+				return o, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (s *Schema) redefineObjClass(c *SchemaChange, existing *ObjectClass, l *LdifObjectClass, attrs map[OID]*Attribute) error {
+	ocs, err := NewLdifOcResolver([]*LdifObjectClass{l}, attrs).WithSchema(s).Resolve()
+	if err != nil {
+		return NewLdapError(InvalidAttributeSyntax, nil, "could not resolve object class %s: %s", l.numericoid, err)
+	}
+
+	redefined := *ocs[existing.numericoid]
+	obsolete := redefined.obsolete
+	redefined.obsolete = existing.obsolete
+	if err := ObjectClassesAreEqual(existing, &redefined); err != nil {
+		return NewLdapError(UnwillingToPerform, nil, "only whether object class %s is obsolete can change: %s", existing.Name(), err)
+	}
+
+	c.obsoleteOcs[existing] = obsolete
+	return nil
+}
+
+// Checks the superclass rules of RFC 4512 2.4
+func validateNewObjClass(o *ObjectClass) error {
+	if objClassInheritsFrom(o, o, map[*ObjectClass]struct{}{}) {
+		return NewLdapError(InvalidAttributeSyntax, nil, "object class %s is its own superclass", o.Name())
+	}
+
+	for _, sup := range o.sups {
+		if sup.kind != Abstract && sup.kind != o.kind {
+			return NewLdapError(InvalidAttributeSyntax, nil, "%s object class %s cannot have %s superclass %s", o.kind, o.Name(), sup.kind, sup.Name())
+		}
+	}
+	return nil
+}
+
+// Returns true if target is one of o's superclasses
+func objClassInheritsFrom(o, target *ObjectClass, seen map[*ObjectClass]struct{}) bool {
+	for _, sup := range o.sups {
+		if sup == target {
+			return true
+		}
+		if _, ok := seen[sup]; ok {
+			continue
+		}
+		seen[sup] = struct{}{}
+		if objClassInheritsFrom(sup, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Applies a change made by NewSchemaChange, returning a func that undoes it
+func (s *Schema) Apply(c *SchemaChange) (undo func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range c.attrs {
+		s.attributes[a.numericoid] = a
+	}
+	for _, o := range c.objClasses {
+		s.objClasses[o.numericoid] = o
+	}
+
+	wasObsoleteAttrs := map[*Attribute]bool{}
+	for a, obsolete := range c.obsoleteAttrs {
+		wasObsoleteAttrs[a], a.obsolete = a.obsolete, obsolete
+	}
+	wasObsoleteOcs := map[*ObjectClass]bool{}
+	for o, obsolete := range c.obsoleteOcs {
+		wasObsoleteOcs[o], o.obsolete = o.obsolete, obsolete
+	}
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for _, a := range c.attrs {
+			delete(s.attributes, a.numericoid)
+		}
+		for _, o := range c.objClasses {
+			delete(s.objClasses, o.numericoid)
+		}
+		for a, obsolete := range wasObsoleteAttrs {
+			a.obsolete = obsolete
+		}
+		for o, obsolete := range wasObsoleteOcs {
+			o.obsolete = obsolete
+		}
+	}
+}
+
+// Writes the attribute types and object classes of the schema, other than the
+// ones the server provides, in the form that LoadSchemaFromReaders reads
+func (s *Schema) WriteLdif(aWriter, ocWriter io.Writer) error {
+	return s.WriteLdifWithChange(&SchemaChange{}, aWriter, ocWriter)
+}
+
+// Like WriteLdif, but writes the schema as it will be once c is applied
+// without applying it, so a change can be persisted before it takes effect
+func (s *Schema) WriteLdifWithChange(c *SchemaChange, aWriter, ocWriter io.Writer) error {
+	s.mu.RLock()
+	attrsByOid := maps.Clone(s.attributes)
+	ocsByOid := maps.Clone(s.objClasses)
+	s.mu.RUnlock()
+
+	for _, a := range c.attrs {
+		attrsByOid[a.numericoid] = a
+	}
+	for _, o := range c.objClasses {
+		ocsByOid[o.numericoid] = o
+	}
+
+	// obsolete is set on the existing definitions when the change is applied,
+	// so copies are written instead
+	for a, obsolete := range c.obsoleteAttrs {
+		redefined := *a
+		redefined.obsolete = obsolete
+		attrsByOid[a.numericoid] = &redefined
+	}
+	for o, obsolete := range c.obsoleteOcs {
+		redefined := *o
+		redefined.obsolete = obsolete
+		ocsByOid[o.numericoid] = &redefined
+	}
+
+	attrs := slices.Collect(maps.Values(attrsByOid))
+	ocs := slices.Collect(maps.Values(ocsByOid))
+
+	slices.SortFunc(attrs, func(a1, a2 *Attribute) int {
+		return strings.Compare(string(a1.numericoid), string(a2.numericoid))
+	})
+	slices.SortFunc(ocs, func(o1, o2 *ObjectClass) int {
+		return strings.Compare(string(o1.numericoid), string(o2.numericoid))
+	})
+
+	for _, a := range attrs {
+		if _, err := fmt.Fprintf(aWriter, "%s\n\n", a.Description()); err != nil {
+			return err
+		}
+	}
+
+	for _, o := range ocs {
+		if _, err := fmt.Fprintf(ocWriter, "%s\n\n", o.Description()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+func schemaDescriptions(s *Schema) []string {
+	descs := []string{}
+	for _, a := range s.Attributes() {
+		descs = append(descs, a.Description())
+	}
+	for _, o := range s.ObjectClasses() {
+		descs = append(descs, o.Description())
+	}
+	return descs
+}
+
+func TestSchemaChange(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  SchemaModification
+		rc   ResultCode
+	}{
+		{
+			name: "adds attribute types and object classes that refer to each other",
+			mod: SchemaModification{
+				AddAttrs: []string{
+					"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' EQUALITY caseIgnoreMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )",
+					"( 1.3.6.1.4.1.99999.1.2 NAME 'appAlias' SUP appId )",
+				},
+				AddObjClasses: []string{
+					"( 1.3.6.1.4.1.99999.2.1 NAME 'appThing' SUP top STRUCTURAL MUST ( appId $ cn ) MAY appAlias )",
+					"( 1.3.6.1.4.1.99999.2.2 NAME 'appSubThing' SUP appThing STRUCTURAL MAY description )",
+				},
+			},
+			rc: Success,
+		},
+		{
+			name: "marks existing definitions obsolete",
+			mod: SchemaModification{
+				DeleteAttrs:      []string{"( 2.5.4.20 )"},
+				AddAttrs:         []string{"( 2.5.4.20 NAME 'telephoneNumber' OBSOLETE EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )"},
+				DeleteObjClasses: []string{"( 2.5.6.6 NAME 'person' )"},
+				AddObjClasses:    []string{"( 2.5.6.6 NAME 'person' OBSOLETE SUP top STRUCTURAL MUST ( cn $ sn ) MAY ( description $ seeAlso $ telephoneNumber $ userPassword ) )"},
+			},
+			rc: Success,
+		},
+		{
+			name: "cannot change more than whether a definition is obsolete",
+			mod: SchemaModification{
+				DeleteAttrs: []string{"( 2.5.4.20 )"},
+				AddAttrs:    []string{"( 2.5.4.20 NAME 'telephoneNumber' OBSOLETE SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )"},
+			},
+			rc: UnwillingToPerform,
+		},
+		{
+			name: "cannot remove a definition",
+			mod:  SchemaModification{DeleteObjClasses: []string{"( 2.5.6.6 )"}},
+			rc:   UnwillingToPerform,
+		},
+		{
+			name: "cannot change what the server provides",
+			mod:  SchemaModification{DeleteAttrs: []string{"( 2.5.18.10 )"}},
+			rc:   UnwillingToPerform,
+		},
+		{
+			name: "cannot delete what is not there",
+			mod:  SchemaModification{DeleteAttrs: []string{"( 1.2.3.4 )"}},
+			rc:   NoSuchAttribute,
+		},
+		{
+			name: "cannot reuse an oid",
+			mod:  SchemaModification{AddAttrs: []string{"( 2.5.4.3 NAME 'notCn' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )"}},
+			rc:   AttributeOrValueExists,
+		},
+		{
+			name: "cannot reuse a name in another case",
+			mod:  SchemaModification{AddObjClasses: []string{"( 1.3.6.1.4.1.99999.2.1 NAME 'Person' SUP top STRUCTURAL )"}},
+			rc:   AttributeOrValueExists,
+		},
+		{
+			name: "cannot add the same name twice",
+			mod: SchemaModification{AddAttrs: []string{
+				"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )",
+				"( 1.3.6.1.4.1.99999.1.2 NAME 'APPID' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 )",
+			}},
+			rc: AttributeOrValueExists,
+		},
+		{
+			name: "rejects malformed descriptions",
+			mod:  SchemaModification{AddAttrs: []string{"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 ) ( 1.3.6.1.4.1.99999.1.2 )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects keywords out of order",
+			mod:  SchemaModification{AddAttrs: []string{"( 1.3.6.1.4.1.99999.1.1 SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 NAME 'appId' )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects unknown sups",
+			mod:  SchemaModification{AddAttrs: []string{"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' SUP notAnAttribute )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects attribute types without a syntax",
+			mod:  SchemaModification{AddAttrs: []string{"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects user attribute types that cannot be modified",
+			mod:  SchemaModification{AddAttrs: []string{"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 NO-USER-MODIFICATION )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects supertype cycles",
+			mod: SchemaModification{AddAttrs: []string{
+				"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' SUP appAlias )",
+				"( 1.3.6.1.4.1.99999.1.2 NAME 'appAlias' SUP appId )",
+			}},
+			rc: InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects auxiliary classes with structural superclasses",
+			mod:  SchemaModification{AddObjClasses: []string{"( 1.3.6.1.4.1.99999.2.1 NAME 'appAux' SUP person AUXILIARY )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+		{
+			name: "rejects unknown attributes",
+			mod:  SchemaModification{AddObjClasses: []string{"( 1.3.6.1.4.1.99999.2.1 NAME 'appThing' SUP top STRUCTURAL MUST appId )"}},
+			rc:   InvalidAttributeSyntax,
+		},
+	}
+
+	for _, test := range tests {
+		s := util.Unwrap(LoadSchemaFromReaders(attrLdifFile(), ocsLdifFile()))
+
+		c, err := NewSchemaChange(s, test.mod)
+		if test.rc != Success {
+			if !errors.Is(err, NewLdapError(test.rc, nil, "")) {
+				t.Errorf("%s: expected %s, got %v", test.name, test.rc, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+
+		before := schemaDescriptions(s)
+		undo := s.Apply(c)
+
+		for _, desc := range append(test.mod.AddAttrs, test.mod.AddObjClasses...) {
+			oid := OID(util.Unwrap(firstComponent(desc)))
+			var got string
+			if a, ok := s.FindAttribute(string(oid)); ok {
+				got = a.Description()
+			} else if o, ok := s.FindObjectClass(string(oid)); ok {
+				got = o.Description()
+			}
+
+			if got != desc {
+				t.Errorf("%s: expected %s\ngot      %s", test.name, desc, got)
+			}
+		}
+
+		undo()
+		if !slices.Equal(before, schemaDescriptions(s)) {
+			t.Errorf("%s: undo did not restore the schema", test.name)
+		}
+	}
+}
+
+func TestWriteLdif(t *testing.T) {
+	var attrs, ocs bytes.Buffer
+	if err := schema.WriteLdif(&attrs, &ocs); err != nil {
+		t.Fatalf("could not write schema: %s", err)
+	}
+
+	written, err := LoadSchemaFromReaders(&attrs, &ocs)
+	if err != nil {
+		t.Fatalf("could not load written schema: %s", err)
+	}
+
+	if len(written.attributes) != len(schema.attributes) || len(written.objClasses) != len(schema.objClasses) {
+		t.Fatalf("expected the written schema to be the same size")
+	}
+
+	for oid, a := range schema.attributes {
+		if err := AttributesAreEqual(a, written.attributes[oid]); err != nil {
+			t.Errorf("%s was not written: %s", a.Name(), err)
+		}
+	}
+
+	for oid, o := range schema.objClasses {
+		if err := ObjectClassesAreEqual(o, written.objClasses[oid]); err != nil {
+			t.Errorf("%s was not written: %s", o.Name(), err)
+		}
+	}
+}
+
+func TestWriteLdifWithChange(t *testing.T) {
+	change, err := NewSchemaChange(schema, SchemaModification{
+		AddAttrs: []string{
+			"( 1.3.6.1.4.1.99999.1.1 NAME 'appId' EQUALITY caseIgnoreMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.15 SINGLE-VALUE )",
+			"( 2.5.4.20 NAME 'telephoneNumber' OBSOLETE EQUALITY telephoneNumberMatch SUBSTR telephoneNumberSubstringsMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.50 )",
+		},
+		DeleteAttrs: []string{"( 2.5.4.20 )"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attrs, ocs bytes.Buffer
+	if err := schema.WriteLdifWithChange(change, &attrs, &ocs); err != nil {
+		t.Fatalf("could not write schema: %s", err)
+	}
+
+	written, err := LoadSchemaFromReaders(&attrs, &ocs)
+	if err != nil {
+		t.Fatalf("could not load written schema: %s", err)
+	}
+
+	if _, ok := written.FindAttribute("appId"); !ok {
+		t.Errorf("expected the added attribute type to be written")
+	}
+	if a, ok := written.FindAttribute("telephoneNumber"); !ok || !a.obsolete {
+		t.Errorf("expected telephoneNumber to be written as obsolete")
+	}
+
+	// the change is only written, not applied
+	if _, ok := schema.FindAttribute("appId"); ok {
+		t.Errorf("expected appId not to be added to the schema")
+	}
+	if a, _ := schema.FindAttribute("telephoneNumber"); a.obsolete {
+		t.Errorf("expected telephoneNumber not to be made obsolete in the schema")
+	}
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+func TestValidateEntryObjectClasses(t *testing.T) {
+	dn := NewDnBuilder().AddNamingContext(attrs["dc"], "dev", "georgiboy").AddAvaAsRdn(attrs["cn"], "Aux").Build()
+	uid := util.UnwrapOk(schema.FindAttribute("uid"))
+
+	tests := []struct {
+		name string
+		aux  string
+		// only the entry with uidObject can hold a uid
+		opts []EntryOption
+		rc   ResultCode
+	}{
+		{
+			name: "an auxiliary class alongside the structural class",
+			aux:  "uidObject",
+			opts: []EntryOption{WithEntryAttr(uid, "aux")},
+			rc:   Success,
+		},
+		{name: "a second structural class", aux: "device", rc: ConstraintViolation},
+		{name: "an abstract class", aux: "top", rc: ConstraintViolation},
+	}
+
+	for _, test := range tests {
+		opts := append([]EntryOption{
+			WithStructural(objClasses["person"]),
+			WithAuxiliary(util.UnwrapOk(schema.FindObjectClass(test.aux))),
+			WithEntryAttr(attrs["sn"], "Aux"),
+		}, test.opts...)
+
+		_, err := NewEntry(schema, dn, opts...)
+
+		if test.rc == Success {
+			if err != nil {
+				t.Errorf("%s: expected the entry to be valid, got %s", test.name, err)
+			}
+			continue
+		}
+
+		if !errors.Is(err, NewLdapError(test.rc, nil, "")) {
+			t.Errorf("%s: expected %s, got %v", test.name, test.rc, err)
+		}
+	}
+}
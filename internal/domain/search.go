@@ -1,5 +1,10 @@
 package domain
 
+import (
+	"context"
+	"errors"
+)
+
 type SearchScope int
 
 const (
@@ -9,115 +14,85 @@ const (
 	SubordinateSubtree
 )
 
-// TODO Greater/Less or equal, Substring, Approx match, extensible match
-type Filter func(*Entry) bool
-
-func FilterAnd(f1, f2 Filter) Filter {
-	return func(e *Entry) bool {
-		return f1(e) && f2(e)
-	}
-}
-
-func FilterOr(f1, f2 Filter) Filter {
-	return func(e *Entry) bool {
-		return f1(e) || f2(e)
-	}
-}
-
-func FilterNot(f Filter) Filter {
-	return func(e *Entry) bool {
-		return !f(e)
-	}
-}
-
-func NewPresenceFilter(target *Attribute) Filter {
-	return func(e *Entry) bool {
-		_, ok := e.attrs[target]
-		return ok
-	}
-}
-
-func NewEqualityFilter(target *Attribute, matchVal string) Filter {
-	return func(e *Entry) bool {
-		vals, ok := e.attrs[target]
-		if !ok {
-			return false
-		}
-
-		for val := range vals {
-			eq, ok := target.EqRule()
-			if !ok {
-				continue
-			}
-			// TODO handling undefined?
-			if ok, err := eq.Match(val, matchVal); ok && err == nil {
-				return true
-			}
-		}
-
-		return false
-	}
+// TODO alias deref, size limits, types only, requested attrs
+func (d DIT) Search(baseDn DN, scope SearchScope, filter Filter) ([]*Entry, error) {
+	return d.SearchContext(context.Background(), baseDn, scope, filter)
 }
 
-// TODO alias deref, size and time limits, types only, requested attrs
-func (d DIT) Search(baseDn DN, scope SearchScope, filter Filter) ([]*Entry, error) {
+// Like Search, but stops walking the DIT once ctx is done. If ctx's deadline
+// passed the entries matched so far are returned along with a
+// TimeLimitExceeded error, otherwise the context's error is returned.
+func (d DIT) SearchContext(ctx context.Context, baseDn DN, scope SearchScope, filter Filter) ([]*Entry, error) {
 	node, err := d.getNode(baseDn)
 	if err != nil {
 		return nil, err
 	}
 
+	s := &searcher{ctx: ctx, filter: filter, matched: []*Entry{}}
+
 	switch scope {
 	case BaseObject:
-		res := []*Entry{}
-		if e, ok := searchBaseObject(node.entry, filter); ok {
-			res = append(res, e)
-		}
-		return res, nil
+		s.visit(node.entry)
 	case SingleLevel:
-		return searchSingleLevel(node, filter), nil
+		for c := range node.children {
+			if !s.visit(c.entry) {
+				break
+			}
+		}
 	case WholeSubtree:
-		return searchWholeSubtree(node, filter), nil
+		s.walk(node)
 	case SubordinateSubtree:
-		return searchSubordiateSubtree(node, filter), nil
+		for c := range node.children {
+			if !s.walk(c) {
+				break
+			}
+		}
+	default:
+		return nil, ErrUnknownScope
 	}
 
-	return nil, ErrUnknownScope
+	if !s.stopped {
+		return s.matched, nil
+	} else if errors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+		return s.matched, NewLdapError(TimeLimitExceeded, nil, "search exceeded its time limit")
+	}
+	return nil, s.ctx.Err()
 }
 
-func searchBaseObject(e *Entry, filter Filter) (*Entry, bool) {
-	return e, filter(e)
+// only entries that evaluate to true are returned, false and undefined are both discarded
+func matches(filter Filter, e *Entry) bool {
+	return filter.Evaluate(e) == FilterTrue
 }
 
-func searchSingleLevel(base *DITNode, filter Filter) []*Entry {
-	matched := []*Entry{}
-	for c := range base.children {
-		if filter(c.entry) {
-			matched = append(matched, c.entry)
-		}
-	}
-	return matched
+type searcher struct {
+	ctx     context.Context
+	filter  Filter
+	stopped bool
+	matched []*Entry
 }
 
-func searchWholeSubtree(base *DITNode, filter Filter) []*Entry {
-	matched := []*Entry{}
-	WalkTree(base, func(e *Entry) {
-		if filter(e) {
-			matched = append(matched, e)
-		}
-	})
+// Adds e if it matches the filter, returns false once the context is done
+func (s *searcher) visit(e *Entry) bool {
+	if s.ctx.Err() != nil {
+		s.stopped = true
+		return false
+	}
 
-	return matched
+	if matches(s.filter, e) {
+		s.matched = append(s.matched, e)
+	}
+	return true
 }
 
-func searchSubordiateSubtree(base *DITNode, filter Filter) []*Entry {
-	matched := []*Entry{}
-	for c := range base.children {
-		WalkTree(c, func(e *Entry) {
-			if filter(e) {
-				matched = append(matched, e)
-			}
-		})
+func (s *searcher) walk(n *DITNode) bool {
+	if !s.visit(n.entry) {
+		return false
 	}
 
-	return matched
+	for c := range n.children {
+		if !s.walk(c) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Which part of an assertion a string is, insignificant space handling is
+// different for each part of a substring assertion (RFC 4518 2.6.1)
+type prepKind int
+
+const (
+	prepValue prepKind = iota
+	prepInitial
+	prepAny
+	prepFinal
+)
+
+type insignificantHandling int
+
+const (
+	insignificantSpace insignificantHandling = iota
+	insignificantNumeric
+	insignificantTelephone
+)
+
+// The LDAP string preparation algorithm (RFC 4518) used by string matching rules
+type stringPrep struct {
+	caseFold      bool
+	insignificant insignificantHandling
+}
+
+var (
+	caseIgnorePrep      = stringPrep{caseFold: true, insignificant: insignificantSpace}
+	caseExactPrep       = stringPrep{caseFold: false, insignificant: insignificantSpace}
+	numericStringPrep   = stringPrep{caseFold: true, insignificant: insignificantNumeric}
+	telephoneNumberPrep = stringPrep{caseFold: true, insignificant: insignificantTelephone}
+)
+
+var caseFolder = cases.Fold()
+
+// Prepares s for matching, returns UndefinedMatch if s contains a prohibited
+// character or is not valid UTF-8
+func (p stringPrep) prepare(s string, kind prepKind) (string, error) {
+	// transcode, values are already unicode so just check they are valid
+	if !utf8.ValidString(s) {
+		return "", UndefinedMatch
+	}
+
+	s = mapChars(s)
+	if p.caseFold {
+		s = caseFolder.String(s)
+	}
+
+	s = norm.NFKC.String(s)
+
+	if strings.IndexFunc(s, isProhibited) >= 0 {
+		return "", UndefinedMatch
+	}
+
+	switch p.insignificant {
+	case insignificantNumeric:
+		return strings.ReplaceAll(s, " ", ""), nil
+	case insignificantTelephone:
+		return strings.Map(func(r rune) rune {
+			if r == ' ' || isHyphen(r) {
+				return -1
+			}
+			return r
+		}, s), nil
+	default:
+		return insignificantSpaces(s, kind), nil
+	}
+}
+
+// RFC 4518 2.2
+func mapChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u00AD' || r == '\u1806' || r == '\u034F' || r == '\u200B' || r == '\uFFFC':
+			return -1
+		case r >= '\u180B' && r <= '\u180D', r >= '\uFE00' && r <= '\uFE0F':
+			// variation selectors
+			return -1
+		case r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r' || r == '\u0085':
+			return ' '
+		case unicode.In(r, unicode.Cc, unicode.Cf):
+			return -1
+		case unicode.In(r, unicode.Zs, unicode.Zl, unicode.Zp):
+			return ' '
+		default:
+			return r
+		}
+	}, s)
+}
+
+// RFC 4518 2.4
+func isProhibited(r rune) bool {
+	switch {
+	case r == utf8.RuneError:
+		return true
+	case unicode.In(r, unicode.Co, unicode.Cs):
+		return true
+	case r >= '\uFDD0' && r <= '\uFDEF', r&0xFFFE == 0xFFFE:
+		// noncharacters
+		return true
+	case r == '\u0340' || r == '\u0341' || r == '\u200E' || r == '\u200F':
+		return true
+	case r >= '\u202A' && r <= '\u202E', r >= '\u206A' && r <= '\u206F':
+		return true
+	}
+
+	// unassigned code points
+	return !unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
+
+func isHyphen(r rune) bool {
+	switch r {
+	case '-', '\u058A', '\u2010', '\u2011', '\u2212', '\uFE63', '\uFF0D':
+		return true
+	default:
+		return false
+	}
+}
+
+// RFC 4518 2.6.1, a space followed by a combining mark is not a space
+func insignificantSpaces(s string, kind prepKind) string {
+	runes := []rune(s)
+	isSpace := func(i int) bool {
+		return runes[i] == ' ' && (i+1 == len(runes) || !unicode.In(runes[i+1], unicode.M))
+	}
+
+	words := []string{}
+	var word strings.Builder
+	leading, trailing := false, false
+	for i := range runes {
+		if !isSpace(i) {
+			word.WriteRune(runes[i])
+			continue
+		}
+
+		if i == 0 {
+			leading = true
+		}
+		if i == len(runes)-1 {
+			trailing = true
+		}
+
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+
+	if len(words) == 0 {
+		if kind == prepValue {
+			return "  "
+		}
+		return " "
+	}
+
+	body := strings.Join(words, "  ")
+	if kind == prepValue || kind == prepInitial || (kind == prepAny || kind == prepFinal) && leading {
+		body = " " + body
+	}
+	if kind == prepValue || kind == prepFinal || (kind == prepInitial || kind == prepAny) && trailing {
+		body = body + " "
+	}
+
+	return body
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+)
+
+func TestStringPrep(t *testing.T) {
+	tests := []struct {
+		prep stringPrep
+		kind prepKind
+		in   string
+		out  string
+		err  error
+	}{
+		{caseIgnorePrep, prepValue, "foo bar  ", " foo  bar ", nil},
+		{caseIgnorePrep, prepValue, "  Foo\tBAR", " foo  bar ", nil},
+		{caseIgnorePrep, prepValue, "   ", "  ", nil},
+		{caseIgnorePrep, prepValue, "", "  ", nil},
+		{caseIgnorePrep, prepValue, "Straße", " strasse ", nil},
+		{caseIgnorePrep, prepValue, "Ｆｏｏ", " foo ", nil},
+		{caseIgnorePrep, prepValue, "José", " josé ", nil},
+		{caseIgnorePrep, prepValue, "Jose\u0301", " jos\u00E9 ", nil},
+		{caseIgnorePrep, prepValue, "so\u00ADft\u200Bhyphen", " softhyphen ", nil},
+		{caseIgnorePrep, prepValue, "non\u00A0breaking", " non  breaking ", nil},
+		{caseIgnorePrep, prepValue, "private\uE000", "", UndefinedMatch},
+		{caseIgnorePrep, prepValue, "bad\xffutf8", "", UndefinedMatch},
+		{caseIgnorePrep, prepInitial, "foo ", " foo ", nil},
+		{caseIgnorePrep, prepInitial, "foo", " foo", nil},
+		{caseIgnorePrep, prepAny, " foo", " foo", nil},
+		{caseIgnorePrep, prepAny, "foo", "foo", nil},
+		{caseIgnorePrep, prepFinal, "foo", "foo ", nil},
+		{caseIgnorePrep, prepFinal, "   ", " ", nil},
+		{numericStringPrep, prepValue, " 123 456 ", "123456", nil},
+		{telephoneNumberPrep, prepValue, "+61 2-9999\u20101234", "+61299991234", nil},
+	}
+
+	for _, test := range tests {
+		out, err := test.prep.prepare(test.in, test.kind)
+		if err != test.err {
+			t.Errorf("preparing %q expected err: %v, got: %v", test.in, test.err, err)
+		}
+
+		if out != test.out {
+			t.Errorf("preparing %q expected %q, got %q", test.in, test.out, out)
+		}
+	}
+}
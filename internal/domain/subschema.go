@@ -0,0 +1,292 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+// subschema attributes (RFC 4512 4.2)
+var AttributeTypesAttribute = NewAttributeBuilder().
+	SetOid("2.5.21.5").
+	AddNames("attributeTypes").
+	SetEqRule(util.Unwrap(GetMatchingRule("objectIdentifierFirstComponentMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.3")), 0).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var ObjectClassesAttribute = NewAttributeBuilder().
+	SetOid("2.5.21.6").
+	AddNames("objectClasses").
+	SetEqRule(util.Unwrap(GetMatchingRule("objectIdentifierFirstComponentMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.37")), 0).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var LdapSyntaxesAttribute = NewAttributeBuilder().
+	SetOid("1.3.6.1.4.1.1466.101.120.16").
+	AddNames("ldapSyntaxes").
+	SetEqRule(util.Unwrap(GetMatchingRule("objectIdentifierFirstComponentMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.54")), 0).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var MatchingRulesAttribute = NewAttributeBuilder().
+	SetOid("2.5.21.4").
+	AddNames("matchingRules").
+	SetEqRule(util.Unwrap(GetMatchingRule("objectIdentifierFirstComponentMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.30")), 0).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var MatchingRuleUseAttribute = NewAttributeBuilder().
+	SetOid("2.5.21.8").
+	AddNames("matchingRuleUse").
+	SetEqRule(util.Unwrap(GetMatchingRule("objectIdentifierFirstComponentMatch"))).
+	SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.31")), 0).
+	SetUsage(DirectoryOperations).
+	Build()
+
+var SubschemaObjectClass = NewObjectClassBuilder().
+	SetOid("2.5.20.1").
+	AddName("subschema").
+	SetKind(Auxiliary).
+	AddMayAttr(
+		AttributeTypesAttribute,
+		ObjectClassesAttribute,
+		LdapSyntaxesAttribute,
+		MatchingRulesAttribute,
+		MatchingRuleUseAttribute,
+	).
+	Build()
+
+// object classes the server provides itself, which every schema has
+var operationalObjectClasses = []*ObjectClass{TopObjectClass, SubschemaObjectClass}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// NAME 'a' or NAME ( 'a' 'b' )
+func writeQdescrs(sb *strings.Builder, names []string) {
+	switch len(names) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(sb, " NAME '%s'", names[0])
+	default:
+		sb.WriteString(" NAME (")
+		for _, n := range names {
+			fmt.Fprintf(sb, " '%s'", n)
+		}
+		sb.WriteString(" )")
+	}
+}
+
+// oid or ( oid $ oid )
+func writeOids(sb *strings.Builder, keyword string, oids []string) {
+	switch len(oids) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(sb, " %s %s", keyword, oids[0])
+	default:
+		fmt.Fprintf(sb, " %s ( %s )", keyword, strings.Join(oids, " $ "))
+	}
+}
+
+func attrNames(attrs map[OID]*Attribute) []string {
+	names := []string{}
+	for _, a := range attrs {
+		names = append(names, a.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+// The AttributeTypeDescription of the attribute (RFC 4512 4.1.2), only what
+// is set on the attribute itself is included and not what it inherits
+func (a *Attribute) Description() string {
+	var sb strings.Builder
+	sb.WriteString("( " + string(a.numericoid))
+	writeQdescrs(&sb, sortedKeys(a.names))
+
+	if a.desc != "" {
+		sb.WriteString(" DESC " + quoteQdstring(a.desc))
+	}
+	if a.obsolete {
+		sb.WriteString(" OBSOLETE")
+	}
+	if a.sup != nil {
+		sb.WriteString(" SUP " + a.sup.Name())
+	}
+
+	var zero MatchingRule
+	if !a.eqRule.Eq(zero) {
+		sb.WriteString(" EQUALITY " + a.eqRule.name)
+	}
+	if !a.ordRule.Eq(zero) {
+		sb.WriteString(" ORDERING " + a.ordRule.name)
+	}
+	if !a.subStrRule.Eq(zero) {
+		sb.WriteString(" SUBSTR " + a.subStrRule.name)
+	}
+
+	if !a.syntax.Eq(Syntax{}) {
+		sb.WriteString(" SYNTAX " + string(a.syntax.numericoid))
+		if a.syntaxLen > 0 {
+			fmt.Fprintf(&sb, "{%d}", a.syntaxLen)
+		}
+	}
+
+	if a.singleVal {
+		sb.WriteString(" SINGLE-VALUE")
+	}
+	if a.collective {
+		sb.WriteString(" COLLECTIVE")
+	}
+	if a.noUserMod {
+		sb.WriteString(" NO-USER-MODIFICATION")
+	}
+	if a.usage != UserApplications {
+		sb.WriteString(" USAGE " + a.usage.String())
+	}
+
+	sb.WriteString(" )")
+	return sb.String()
+}
+
+// The ObjectClassDescription of the object class (RFC 4512 4.1.1)
+func (o *ObjectClass) Description() string {
+	var sb strings.Builder
+	sb.WriteString("( " + string(o.numericoid))
+	writeQdescrs(&sb, sortedKeys(o.names))
+
+	if o.desc != "" {
+		sb.WriteString(" DESC " + quoteQdstring(o.desc))
+	}
+	if o.obsolete {
+		sb.WriteString(" OBSOLETE")
+	}
+
+	sups := []string{}
+	for _, sup := range o.sups {
+		sups = append(sups, sup.Name())
+	}
+	slices.Sort(sups)
+	writeOids(&sb, "SUP", sups)
+
+	sb.WriteString(" " + o.kind.String())
+	writeOids(&sb, "MUST", attrNames(o.mustAttrs))
+	writeOids(&sb, "MAY", attrNames(o.mayAttrs))
+
+	sb.WriteString(" )")
+	return sb.String()
+}
+
+// The MatchingRuleDescription of the rule (RFC 4512 4.1.3)
+func (m MatchingRule) Description() string {
+	return fmt.Sprintf("( %s NAME '%s' SYNTAX %s )", m.numericoid, m.name, m.syntax)
+}
+
+// The SyntaxDescription of the syntax (RFC 4512 4.1.5)
+func (s Syntax) Description() string {
+	if s.desc == "" {
+		return fmt.Sprintf("( %s )", s.numericoid)
+	}
+	return fmt.Sprintf("( %s DESC %s )", s.numericoid, quoteQdstring(s.desc))
+}
+
+// The MatchingRuleUseDescription of the rule (RFC 4512 4.1.4), the rule applies
+// to the attributes with its syntax. False if no attribute has its syntax.
+func matchingRuleUseDescription(m MatchingRule, attrs []*Attribute) (string, bool) {
+	applies := map[OID]*Attribute{}
+	for _, a := range attrs {
+		if s, _, ok := a.Syntax(); ok && s.numericoid == m.syntax {
+			applies[a.numericoid] = a
+		}
+	}
+
+	if len(applies) == 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "( %s NAME '%s'", m.numericoid, m.name)
+	writeOids(&sb, "APPLIES", attrNames(applies))
+	sb.WriteString(" )")
+	return sb.String(), true
+}
+
+// Every attribute the schema can find, including the ones the server
+// provides, sorted by numericoid
+func (s *Schema) Attributes() []*Attribute {
+	attrs := []*Attribute{ObjectClassAttribute}
+	attrs = append(attrs, operationalAttributes...)
+	s.mu.RLock()
+	for _, a := range s.attributes {
+		attrs = append(attrs, a)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(attrs, func(a1, a2 *Attribute) int {
+		return strings.Compare(string(a1.numericoid), string(a2.numericoid))
+	})
+	return attrs
+}
+
+// Every object class the schema can find, including the ones the server
+// provides, sorted by numericoid
+func (s *Schema) ObjectClasses() []*ObjectClass {
+	ocs := slices.Clone(operationalObjectClasses)
+	s.mu.RLock()
+	for _, o := range s.objClasses {
+		ocs = append(ocs, o)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(ocs, func(o1, o2 *ObjectClass) int {
+		return strings.Compare(string(o1.numericoid), string(o2.numericoid))
+	})
+	return ocs
+}
+
+// Builds the subschema subentry (RFC 4512 4.2) at dn, which publishes the
+// schema along with the syntaxes and matching rules the server implements.
+// Like the root DSE it is not part of the DIT.
+func (s *Schema) SubschemaEntry(dn DN) *Entry {
+	attrs := s.Attributes()
+
+	vals := map[*Attribute][]string{}
+	for _, a := range attrs {
+		vals[AttributeTypesAttribute] = append(vals[AttributeTypesAttribute], a.Description())
+	}
+	for _, o := range s.ObjectClasses() {
+		vals[ObjectClassesAttribute] = append(vals[ObjectClassesAttribute], o.Description())
+	}
+	for _, oid := range sortedKeys(syntaxes) {
+		vals[LdapSyntaxesAttribute] = append(vals[LdapSyntaxesAttribute], syntaxes[oid].Description())
+	}
+	for _, name := range sortedKeys(matchingRules) {
+		m := matchingRules[name]
+		vals[MatchingRulesAttribute] = append(vals[MatchingRulesAttribute], m.Description())
+		if use, ok := matchingRuleUseDescription(m, attrs); ok {
+			vals[MatchingRuleUseAttribute] = append(vals[MatchingRuleUseAttribute], use)
+		}
+	}
+
+	for attr, val := range dn.GetRDN().avas {
+		vals[attr] = append(vals[attr], val)
+	}
+
+	e := newUncheckedEntry(s, dn, vals)
+	e.auxiliary[SubschemaObjectClass] = struct{}{}
+	return e
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+func TestDescriptionsRoundTrip(t *testing.T) {
+	escaped := NewAttributeBuilder().
+		SetOid("1.2.3.4").
+		AddNames("escaped", "esc").
+		SetDesc(`it's a \ test`).
+		SetSyntax(util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.15")), 64).
+		Build()
+
+	attrs := append(schema.Attributes(), escaped)
+
+	descs := []string{}
+	for _, a := range attrs {
+		descs = append(descs, a.Description())
+	}
+
+	parsedAttrs, err := ParseAttributes(strings.NewReader(strings.Join(descs, "\n")))
+	if err != nil {
+		t.Fatalf("could not parse attribute descriptions: %s", err)
+	}
+
+	if len(parsedAttrs) != len(attrs) {
+		t.Fatalf("expected %d attributes, got %d", len(attrs), len(parsedAttrs))
+	}
+
+	for _, a := range attrs {
+		if err := AttributesAreEqual(a, parsedAttrs[a.Oid()]); err != nil {
+			t.Errorf("%s did not round trip: %s\n%s", a.Name(), err, a.Description())
+		}
+	}
+
+	ocs := schema.ObjectClasses()
+
+	descs = []string{}
+	for _, o := range ocs {
+		descs = append(descs, o.Description())
+	}
+
+	parsedOcs, err := ParseObjectClasses(strings.NewReader(strings.Join(descs, "\n")), parsedAttrs)
+	if err != nil {
+		t.Fatalf("could not parse object class descriptions: %s", err)
+	}
+
+	if len(parsedOcs) != len(ocs) {
+		t.Fatalf("expected %d object classes, got %d", len(ocs), len(parsedOcs))
+	}
+
+	for _, o := range ocs {
+		if err := ObjectClassesAreEqual(o, parsedOcs[o.Oid()]); err != nil {
+			t.Errorf("%s did not round trip: %s\n%s", o.Name(), err, o.Description())
+		}
+	}
+}
+
+func TestDescriptions(t *testing.T) {
+	tests := []struct {
+		desc, exp string
+	}{
+		{
+			desc: util.UnwrapOk(schema.FindAttribute("cn")).Description(),
+			exp:  "( 2.5.4.3 NAME 'cn' SUP name )",
+		},
+		{
+			desc: SubschemaSubentryAttribute.Description(),
+			exp:  "( 2.5.18.10 NAME 'subschemaSubentry' EQUALITY distinguishedNameMatch SYNTAX 1.3.6.1.4.1.1466.115.121.1.12 SINGLE-VALUE NO-USER-MODIFICATION USAGE directoryOperation )",
+		},
+		{
+			desc: SupportedLDAPVersionAttribute.Description(),
+			exp:  "( 1.3.6.1.4.1.1466.101.120.15 NAME 'supportedLDAPVersion' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 USAGE dSAOperation )",
+		},
+		{
+			desc: TopObjectClass.Description(),
+			exp:  "( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		},
+		{
+			desc: util.UnwrapOk(schema.FindObjectClass("person")).Description(),
+			exp:  "( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( cn $ sn ) MAY ( description $ seeAlso $ telephoneNumber $ userPassword ) )",
+		},
+		{
+			desc: util.Unwrap(GetMatchingRule("integerMatch")).Description(),
+			exp:  "( 2.5.13.14 NAME 'integerMatch' SYNTAX 1.3.6.1.4.1.1466.115.121.1.27 )",
+		},
+		{
+			desc: util.Unwrap(GetSyntax("1.3.6.1.4.1.1466.115.121.1.27")).Description(),
+			exp:  "( 1.3.6.1.4.1.1466.115.121.1.27 DESC 'INTEGER' )",
+		},
+	}
+
+	for _, test := range tests {
+		if test.desc != test.exp {
+			t.Errorf("expected %s\ngot      %s", test.exp, test.desc)
+		}
+	}
+}
+
+func TestSubschemaEntry(t *testing.T) {
+	dn := util.Unwrap(NormaliseDN(schema, "cn=Subschema"))
+	e := schema.SubschemaEntry(dn)
+
+	ocs, _ := e.AttrVals(ObjectClassAttribute)
+	slices.Sort(ocs)
+	if !slices.Equal(ocs, []string{"subschema", "top"}) {
+		t.Errorf("expected object classes subschema and top, got %v", ocs)
+	}
+
+	if ok, _ := e.ContainsAttrVal(util.UnwrapOk(schema.FindAttribute("cn")), "Subschema"); !ok {
+		t.Errorf("expected the entry to have its rdn value")
+	}
+
+	for attr, exp := range map[*Attribute]int{
+		AttributeTypesAttribute: len(schema.Attributes()),
+		ObjectClassesAttribute:  len(schema.ObjectClasses()),
+		LdapSyntaxesAttribute:   len(syntaxes),
+		MatchingRulesAttribute:  len(matchingRules),
+	} {
+		if vals, _ := e.AttrVals(attr); len(vals) != exp {
+			t.Errorf("expected %d %s values, got %d", exp, attr.Name(), len(vals))
+		}
+	}
+
+	uses, _ := e.AttrVals(MatchingRuleUseAttribute)
+	use := "( 2.5.13.14 NAME 'integerMatch' APPLIES supportedLDAPVersion )"
+	if !slices.Contains(uses, use) {
+		t.Errorf("expected matchingRuleUse to contain %s, got %v", use, uses)
+	}
+}
@@ -1,5 +1,15 @@
 package domain
 
+import (
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
 type Syntax struct {
 	numericoid OID
 	desc       string
@@ -17,14 +27,17 @@ func (s Syntax) Eq(o Syntax) bool {
 	return s.numericoid == o.numericoid
 }
 
+// Every syntax defined in RFC 4517 3.3, along with UUID (RFC 4530 2.1)
 var syntaxes = map[string]Syntax{
 	"1.3.6.1.4.1.1466.115.121.1.3": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.3",
 		desc:       "Attribute Type Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.6": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.6",
 		desc:       "Bit String",
+		validate:   validateBitString,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.7": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.7",
@@ -34,10 +47,12 @@ var syntaxes = map[string]Syntax{
 	"1.3.6.1.4.1.1466.115.121.1.11": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.11",
 		desc:       "Country String",
+		validate:   validateCountryString,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.14": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.14",
 		desc:       "Delivery Method",
+		validate:   validateDeliveryMethod,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.15": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.15",
@@ -47,34 +62,42 @@ var syntaxes = map[string]Syntax{
 	"1.3.6.1.4.1.1466.115.121.1.16": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.16",
 		desc:       "DIT Content Rule Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.17": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.17",
 		desc:       "DIT Structure Rule Description",
+		validate:   validateRuleIdDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.12": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.12",
 		desc:       "DN",
+		validate:   validateDN,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.21": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.21",
 		desc:       "Enhanced Guide",
+		validate:   validateEnhancedGuide,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.22": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.22",
 		desc:       "Facsimile Telephone Number",
+		validate:   validateFacsimileTelephoneNumber,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.23": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.23",
 		desc:       "Fax",
+		validate:   validateFax,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.24": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.24",
 		desc:       "Generalized Time",
+		validate:   validateGeneralizedTime,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.25": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.25",
 		desc:       "Guide",
+		validate:   validateGuide,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.26": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.26",
@@ -84,38 +107,47 @@ var syntaxes = map[string]Syntax{
 	"1.3.6.1.4.1.1466.115.121.1.27": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.27",
 		desc:       "INTEGER",
+		validate:   validateInteger,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.28": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.28",
 		desc:       "JPEG",
+		validate:   validateJpeg,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.54": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.54",
 		desc:       "LDAP Syntax Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.30": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.30",
 		desc:       "Matching Rule Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.31": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.31",
 		desc:       "Matching Rule Use Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.34": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.34",
 		desc:       "Name And Optional UID",
+		validate:   validateNameAndOptionalUid,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.35": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.35",
 		desc:       "Name Form Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.36": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.36",
 		desc:       "Numeric String",
+		validate:   validateNumericString,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.37": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.37",
 		desc:       "Object Class Description",
+		validate:   validateOidDescription,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.40": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.40",
@@ -125,38 +157,52 @@ var syntaxes = map[string]Syntax{
 	"1.3.6.1.4.1.1466.115.121.1.38": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.38",
 		desc:       "OID",
+		validate:   validateOid,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.39": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.39",
 		desc:       "Other Mailbox",
+		validate:   validateOtherMailbox,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.41": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.41",
 		desc:       "Postal Address",
+		validate:   validatePostalAddress,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.44": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.44",
 		desc:       "Printable String",
+		validate:   validatePrintableString,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.58": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.58",
 		desc:       "Substring Assertion",
+		validate:   validateSubstringAssertion,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.50": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.50",
 		desc:       "Telephone Number",
+		validate:   validateTelephoneNumber,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.51": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.51",
 		desc:       "Teletex Terminal Identifier",
+		validate:   validateTeletexTerminalIdentifier,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.52": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.52",
 		desc:       "Telex Number",
+		validate:   validateTelexNumber,
 	},
 	"1.3.6.1.4.1.1466.115.121.1.53": Syntax{
 		numericoid: "1.3.6.1.4.1.1466.115.121.1.53",
 		desc:       "UTC Time",
+		validate:   validateUtcTime,
+	},
+	"1.3.6.1.1.16.1": Syntax{
+		numericoid: "1.3.6.1.1.16.1",
+		desc:       "UUID",
+		validate:   validateUUID,
 	},
 }
 
@@ -179,9 +225,14 @@ func validateBoolean(s string) error {
 	}
 }
 
+// ia5 strings are a bit like ascii but in different order, any byte with the
+// highest bit set is invalid
 func validateIA5String(s string) error {
-	// ia5 strings are a bit like ascii but in different order,
-	// technically could be invalid (highest bit set is invalid)
+	for i := 0; i < len(s); i++ {
+		if s[i] > 0x7F {
+			return NewLdapError(InvalidAttributeSyntax, nil, "invalid IA5 string %q", s)
+		}
+	}
 	return nil
 }
 
@@ -190,9 +241,570 @@ func validateDirectoryString(s string) error {
 		return NewLdapError(InvalidAttributeSyntax, nil, "directory strings cannot be empty")
 	}
 
+	if !utf8.ValidString(s) {
+		return NewLdapError(InvalidAttributeSyntax, nil, "directory string %q is not valid UTF-8", s)
+	}
+
 	return nil
 }
 
 func validateOctetString(s string) error {
 	return nil //anything goes
 }
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses an INTEGER value (RFC 4517 3.3.16), which is an optionally negative
+// number with no leading zeros
+func parseInteger(s string) (*big.Int, error) {
+	digits := strings.TrimPrefix(s, "-")
+	if !isDigits(digits) || (len(digits) > 1 && digits[0] == '0') || s == "-0" {
+		return nil, NewLdapError(InvalidAttributeSyntax, nil, "invalid integer %q", s)
+	}
+
+	i, _ := new(big.Int).SetString(s, 10)
+	return i, nil
+}
+
+// Parses a Generalized Time value (RFC 4517 3.3.13). Minutes and seconds are
+// optional, the last unit given can have a fraction and a time zone is required
+func parseGeneralizedTime(s string) (time.Time, error) {
+	invalid := NewLdapError(InvalidAttributeSyntax, nil, "invalid generalized time %q", s)
+
+	tzIdx := strings.IndexAny(s, "Z+-")
+	if tzIdx < 0 {
+		return time.Time{}, invalid
+	}
+	dateTime, tz := s[:tzIdx], s[tzIdx:]
+
+	dateTime, fraction, hasFraction := strings.Cut(strings.ReplaceAll(dateTime, ",", "."), ".")
+	if hasFraction && !isDigits(fraction) {
+		return time.Time{}, invalid
+	}
+
+	if !isDigits(dateTime) {
+		return time.Time{}, invalid
+	}
+
+	// the unit the fraction applies to
+	var unit time.Duration
+	switch len(dateTime) {
+	case 10:
+		unit = time.Hour
+		dateTime += "0000"
+	case 12:
+		unit = time.Minute
+		dateTime += "00"
+	case 14:
+		unit = time.Second
+	default:
+		return time.Time{}, invalid
+	}
+
+	var loc *time.Location
+	switch {
+	case tz == "Z":
+		loc = time.UTC
+	case (len(tz) == 3 || len(tz) == 5) && isDigits(tz[1:]):
+		hours, _ := strconv.Atoi(tz[1:3])
+		mins := 0
+		if len(tz) == 5 {
+			mins, _ = strconv.Atoi(tz[3:5])
+		}
+		if hours > 23 || mins > 59 {
+			return time.Time{}, invalid
+		}
+		offset := hours*60*60 + mins*60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone(tz, offset)
+	default:
+		return time.Time{}, invalid
+	}
+
+	t, err := time.ParseInLocation("20060102150405", dateTime, loc)
+	if err != nil {
+		return time.Time{}, invalid
+	}
+
+	if hasFraction {
+		f, _ := strconv.ParseFloat("0."+fraction, 64)
+		t = t.Add(time.Duration(f * float64(unit)))
+	}
+
+	return t, nil
+}
+
+// Splits a Postal Address (RFC 4517 3.3.28) into its lines, a $ separates
+// lines and a literal $ or \\ is escaped as \\24 or \\5C
+func parsePostalAddress(s string) ([]string, error) {
+	lines := []string{}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '$':
+			lines = append(lines, sb.String())
+			sb.Reset()
+		case '\\':
+			if i+2 >= len(s) {
+				return nil, NewLdapError(InvalidAttributeSyntax, nil, "incomplete escape in postal address %q", s)
+			}
+			switch strings.ToUpper(s[i+1 : i+3]) {
+			case "24":
+				sb.WriteByte('$')
+			case "5C":
+				sb.WriteByte('\\')
+			default:
+				return nil, NewLdapError(InvalidAttributeSyntax, nil, "invalid escape in postal address %q", s)
+			}
+			i += 2
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return append(lines, sb.String()), nil
+}
+
+func invalidSyntax(desc, s string) error {
+	return NewLdapError(InvalidAttributeSyntax, nil, "invalid %s %q", desc, s)
+}
+
+var (
+	bitStringRe = regexp.MustCompile(`^'[01]*'B$`)
+	utcTimeRe   = regexp.MustCompile(`^[0-9]{10}([0-9]{2})?(Z|[+-][0-9]{4})?$`)
+	extKeyRe    = regexp.MustCompile(`^X-[A-Za-z_-]+$`)
+	uuidRe      = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+)
+
+func validateBitString(s string) error {
+	if !bitStringRe.MatchString(s) {
+		return invalidSyntax("bit string", s)
+	}
+	return nil
+}
+
+// letters, digits, space and '()+,-./:?= (RFC 4517 3.2)
+func isPrintableChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte(" '()+,-./:?=", c) >= 0
+}
+
+func isPrintableString(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isPrintableChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func validatePrintableString(s string) error {
+	if !isPrintableString(s) {
+		return invalidSyntax("printable string", s)
+	}
+	return nil
+}
+
+// a two letter ISO 3166 country code
+func validateCountryString(s string) error {
+	if len(s) != 2 || !isPrintableString(s) {
+		return invalidSyntax("country string", s)
+	}
+	return nil
+}
+
+var deliveryMethods = []string{"any", "mhs", "physical", "telex", "teletex", "g3fax", "g4fax", "ia5", "videotex", "telephone"}
+
+func validateDeliveryMethod(s string) error {
+	for _, pdm := range strings.Split(s, "$") {
+		if !slices.Contains(deliveryMethods, strings.TrimSpace(pdm)) {
+			return invalidSyntax("delivery method", s)
+		}
+	}
+	return nil
+}
+
+func validateDN(s string) error {
+	if _, err := parseDnString(s); err != nil {
+		return invalidSyntax("DN", s)
+	}
+	return nil
+}
+
+func validateNameAndOptionalUid(s string) error {
+	dn, uid := splitNameAndOptionalUid(s)
+	if err := validateDN(dn); err != nil {
+		return invalidSyntax("name and optional UID", s)
+	}
+
+	if uid != "" && !bitStringRe.MatchString(uid) {
+		return invalidSyntax("name and optional UID", s)
+	}
+	return nil
+}
+
+var faxParameters = []string{"twoDimensional", "fineResolution", "unlimitedLength", "b4Length", "a3Width", "b4Width", "uncompressed"}
+
+func validateFacsimileTelephoneNumber(s string) error {
+	parts := strings.Split(s, "$")
+	if !isPrintableString(parts[0]) {
+		return invalidSyntax("facsimile telephone number", s)
+	}
+
+	for _, param := range parts[1:] {
+		if !slices.Contains(faxParameters, param) {
+			return invalidSyntax("facsimile telephone number", s)
+		}
+	}
+	return nil
+}
+
+// fax images are not checked beyond having some content
+func validateFax(s string) error {
+	if len(s) == 0 {
+		return invalidSyntax("fax", s)
+	}
+	return nil
+}
+
+func validateGeneralizedTime(s string) error {
+	_, err := parseGeneralizedTime(s)
+	return err
+}
+
+func validateInteger(s string) error {
+	_, err := parseInteger(s)
+	return err
+}
+
+// only checks for the JPEG start of image marker
+func validateJpeg(s string) error {
+	if !strings.HasPrefix(s, "\xFF\xD8\xFF") {
+		return NewLdapError(InvalidAttributeSyntax, nil, "value is not a JPEG image")
+	}
+	return nil
+}
+
+func validateNumericString(s string) error {
+	if len(s) == 0 || strings.Trim(s, "0123456789 ") != "" {
+		return invalidSyntax("numeric string", s)
+	}
+	return nil
+}
+
+func isOid(s string) bool {
+	return numericoid_re.MatchString(s) || descr_re.MatchString(s)
+}
+
+func validateOid(s string) error {
+	if !isOid(s) {
+		return invalidSyntax("OID", s)
+	}
+	return nil
+}
+
+func validateOtherMailbox(s string) error {
+	mailboxType, mailbox, ok := strings.Cut(s, "$")
+	if !ok || !isPrintableString(mailboxType) || len(mailbox) == 0 || validateIA5String(mailbox) != nil {
+		return invalidSyntax("other mailbox", s)
+	}
+	return nil
+}
+
+func validatePostalAddress(s string) error {
+	lines, err := parsePostalAddress(s)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if len(line) == 0 || !utf8.ValidString(line) {
+			return invalidSyntax("postal address", s)
+		}
+	}
+	return nil
+}
+
+func validateSubstringAssertion(s string) error {
+	if _, err := parseSubstringAssertion(s); err != nil {
+		return invalidSyntax("substring assertion", s)
+	}
+	return nil
+}
+
+// E.123 is not enforced, only that the number is a printable string
+func validateTelephoneNumber(s string) error {
+	if !isPrintableString(s) {
+		return invalidSyntax("telephone number", s)
+	}
+	return nil
+}
+
+var ttxKeys = []string{"graphic", "control", "misc", "page", "private"}
+
+func validateTeletexTerminalIdentifier(s string) error {
+	parts := strings.Split(s, "$")
+	if !isPrintableString(parts[0]) {
+		return invalidSyntax("teletex terminal identifier", s)
+	}
+
+	for _, param := range parts[1:] {
+		key, val, ok := strings.Cut(param, ":")
+		if !ok || !slices.Contains(ttxKeys, key) {
+			return invalidSyntax("teletex terminal identifier", s)
+		}
+
+		// a $ or \ in the value must be escaped as \24 or \5C
+		for i := 0; i < len(val); i++ {
+			if val[i] != '\\' {
+				continue
+			}
+			if i+2 >= len(val) || (!strings.EqualFold(val[i+1:i+3], "24") && !strings.EqualFold(val[i+1:i+3], "5C")) {
+				return invalidSyntax("teletex terminal identifier", s)
+			}
+			i += 2
+		}
+	}
+	return nil
+}
+
+// actual number, country code and answerback
+func validateTelexNumber(s string) error {
+	parts := strings.Split(s, "$")
+	if len(parts) != 3 {
+		return invalidSyntax("telex number", s)
+	}
+
+	for _, part := range parts {
+		if !isPrintableString(part) {
+			return invalidSyntax("telex number", s)
+		}
+	}
+	return nil
+}
+
+func validateUtcTime(s string) error {
+	if !utcTimeRe.MatchString(s) {
+		return invalidSyntax("UTC time", s)
+	}
+
+	if _, err := time.Parse("0601021504", s[:10]); err != nil {
+		return invalidSyntax("UTC time", s)
+	}
+	return nil
+}
+
+// The string form of a UUID (RFC 4122 3)
+func validateUUID(s string) error {
+	if !uuidRe.MatchString(s) {
+		return invalidSyntax("UUID", s)
+	}
+	return nil
+}
+
+// Parses the criteria of a Guide or Enhanced Guide (RFC 4517 3.3.14)
+type guideParser struct {
+	s   string
+	pos int
+}
+
+func (p *guideParser) peek() byte {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *guideParser) criteria() bool {
+	if !p.andTerm() {
+		return false
+	}
+	for p.peek() == '|' {
+		p.pos++
+		if !p.andTerm() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *guideParser) andTerm() bool {
+	if !p.term() {
+		return false
+	}
+	for p.peek() == '&' {
+		p.pos++
+		if !p.term() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *guideParser) term() bool {
+	switch p.peek() {
+	case '!':
+		p.pos++
+		return p.term()
+	case '(':
+		p.pos++
+		if !p.criteria() || p.peek() != ')' {
+			return false
+		}
+		p.pos++
+		return true
+	case '?':
+		for _, b := range []string{"?true", "?false"} {
+			if strings.HasPrefix(p.s[p.pos:], b) {
+				p.pos += len(b)
+				return true
+			}
+		}
+		return false
+	}
+
+	// attributetype $ match-type
+	attrType, rest, ok := strings.Cut(p.s[p.pos:], "$")
+	if !ok || !isOid(attrType) {
+		return false
+	}
+	p.pos += len(attrType) + 1
+
+	for _, matchType := range []string{"EQ", "SUBSTR", "GE", "LE", "APPROX"} {
+		if strings.HasPrefix(rest, matchType) {
+			p.pos += len(matchType)
+			return true
+		}
+	}
+	return false
+}
+
+func validateCriteria(s string) bool {
+	p := &guideParser{s: s}
+	return p.criteria() && p.peek() == 0
+}
+
+// [ object-class # ] criteria
+func validateGuide(s string) error {
+	criteria := s
+	if oc, c, ok := strings.Cut(s, "#"); ok {
+		if !isOid(oc) {
+			return invalidSyntax("guide", s)
+		}
+		criteria = c
+	}
+
+	if !validateCriteria(criteria) {
+		return invalidSyntax("guide", s)
+	}
+	return nil
+}
+
+// object-class # criteria # subset
+func validateEnhancedGuide(s string) error {
+	parts := strings.Split(s, "#")
+	if len(parts) != 3 || !isOid(strings.TrimSpace(parts[0])) || !validateCriteria(strings.TrimSpace(parts[1])) {
+		return invalidSyntax("enhanced guide", s)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(parts[2])) {
+	case "baseobject", "onelevel", "wholesubtree":
+		return nil
+	default:
+		return invalidSyntax("enhanced guide", s)
+	}
+}
+
+// splits a schema description into parens, dollars, quoted strings and words
+func descriptionTokens(s string) ([]string, bool) {
+	tokens := []string{}
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case c == '(' || c == ')' || c == '$':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end <= 0 {
+				return nil, false
+			}
+			tokens = append(tokens, s[i:i+end+2])
+			i += end + 2
+		default:
+			end := strings.IndexAny(s[i:], " ()$'")
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, s[i:i+end])
+			i += end
+		}
+	}
+	return tokens, true
+}
+
+func isDescriptionKeyword(s string) bool {
+	return keyword_re.MatchString(s) || extKeyRe.MatchString(s)
+}
+
+// Checks the general form of the schema descriptions in RFC 4512 4.1, ie
+// ( first-component *( keyword [ value ] ) ) where a value is a word, quoted
+// string or a parenthesised list of them
+func validateDescription(s string, first func(string) bool) error {
+	tokens, ok := descriptionTokens(s)
+	if !ok || len(tokens) < 3 || tokens[0] != "(" || tokens[len(tokens)-1] != ")" || !first(tokens[1]) {
+		return invalidSyntax("schema description", s)
+	}
+
+	rest := tokens[2 : len(tokens)-1]
+	for len(rest) > 0 {
+		if !isDescriptionKeyword(rest[0]) {
+			return invalidSyntax("schema description", s)
+		}
+		rest = rest[1:]
+
+		switch {
+		case len(rest) == 0:
+		case rest[0] == "(":
+			end := slices.Index(rest, ")")
+			if end < 0 || slices.Contains(rest[1:end], "(") {
+				return invalidSyntax("schema description", s)
+			}
+			rest = rest[end+1:]
+		case rest[0] == ")" || rest[0] == "$":
+			return invalidSyntax("schema description", s)
+		case strings.HasPrefix(rest[0], "'") || !isDescriptionKeyword(rest[0]):
+			rest = rest[1:]
+		}
+	}
+	return nil
+}
+
+func validateOidDescription(s string) error {
+	return validateDescription(s, numericoid_re.MatchString)
+}
+
+// DIT structure rules are identified by an integer rule id
+func validateRuleIdDescription(s string) error {
+	return validateDescription(s, func(ruleId string) bool {
+		return isDigits(ruleId)
+	})
+}
@@ -0,0 +1,210 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/util"
+)
+
+type syntaxTest struct {
+	v     string
+	valid bool
+}
+
+func testSyntax(tests []syntaxTest, oid OID, t *testing.T) {
+	syntax := util.Unwrap(GetSyntax(oid))
+	for _, s := range tests {
+		err := syntax.Validate(s.v)
+		if s.valid && err != nil {
+			t.Errorf("%s: expected %q to be valid, got error: %s", oid, s.v, err)
+		}
+		if !s.valid && err == nil {
+			t.Errorf("%s: expected %q to be invalid", oid, s.v)
+		}
+	}
+}
+
+func TestRfc4517Syntaxes(t *testing.T) {
+	tests := map[OID][]syntaxTest{
+		// Attribute Type Description
+		"1.3.6.1.4.1.1466.115.121.1.3": {
+			{v: "( 2.5.4.3 NAME 'cn' SUP name )", valid: true},
+			{v: "( 2.5.4.0 NAME ( 'objectClass' 'oc' ) EQUALITY objectIdentifierMatch X-ORIGIN 'RFC 4512' )", valid: true},
+			{v: "( 2.5.18.1 NAME 'createTimestamp' NO-USER-MODIFICATION USAGE directoryOperation )", valid: true},
+			{v: "( cn NAME 'cn' )", valid: false},
+			{v: "( 2.5.4.3 NAME 'cn' ", valid: false},
+			{v: "( 2.5.4.3 NAME 'cn )", valid: false},
+		},
+		// Bit String
+		"1.3.6.1.4.1.1466.115.121.1.6": {
+			{v: "'0101'B", valid: true},
+			{v: "''B", valid: true},
+			{v: "'0102'B", valid: false},
+			{v: "0101", valid: false},
+		},
+		// Boolean
+		"1.3.6.1.4.1.1466.115.121.1.7": {
+			{v: "TRUE", valid: true},
+			{v: "FALSE", valid: true},
+			{v: "true", valid: false},
+		},
+		// Country String
+		"1.3.6.1.4.1.1466.115.121.1.11": {
+			{v: "AU", valid: true},
+			{v: "AUS", valid: false},
+			{v: "A!", valid: false},
+		},
+		// DN
+		"1.3.6.1.4.1.1466.115.121.1.12": {
+			{v: "cn=Test,dc=example,dc=com", valid: true},
+			{v: "", valid: true},
+			{v: "cn=a\\,b+uid=1,dc=com", valid: true},
+			{v: "cn", valid: false},
+			{v: "cn=test,", valid: false},
+		},
+		// Delivery Method
+		"1.3.6.1.4.1.1466.115.121.1.14": {
+			{v: "telephone", valid: true},
+			{v: "videotex $ telephone", valid: true},
+			{v: "pigeon", valid: false},
+		},
+		// Directory String
+		"1.3.6.1.4.1.1466.115.121.1.15": {
+			{v: "Test \u00e9", valid: true},
+			{v: "", valid: false},
+			{v: "\xff", valid: false},
+		},
+		// DIT Structure Rule Description
+		"1.3.6.1.4.1.1466.115.121.1.17": {
+			{v: "( 2 DESC 'organization' FORM orgNameForm SUP ( 1 3 ) )", valid: true},
+			{v: "( 2.5 FORM orgNameForm )", valid: false},
+		},
+		// Enhanced Guide
+		"1.3.6.1.4.1.1466.115.121.1.21": {
+			{v: "person#(sn$EQ)#oneLevel", valid: true},
+			{v: "person # (sn$EQ|cn$SUBSTR)&!c$APPROX # wholeSubtree", valid: true},
+			{v: "person#(sn$EQ)#everywhere", valid: false},
+			{v: "person#(sn$EQ", valid: false},
+		},
+		// Facsimile Telephone Number
+		"1.3.6.1.4.1.1466.115.121.1.22": {
+			{v: "+61 3 9999 0000", valid: true},
+			{v: "+61 3 9999 0000$twoDimensional$b4Width", valid: true},
+			{v: "+61 3 9999 0000$colour", valid: false},
+		},
+		// Generalized Time
+		"1.3.6.1.4.1.1466.115.121.1.24": {
+			{v: "199412161032Z", valid: true},
+			{v: "199412160532-0500", valid: true},
+			{v: "19941216", valid: false},
+			{v: "199413161032Z", valid: false},
+		},
+		// Guide
+		"1.3.6.1.4.1.1466.115.121.1.25": {
+			{v: "person#sn$EQ", valid: true},
+			{v: "?true", valid: true},
+			{v: "sn$EQ&cn", valid: false},
+		},
+		// IA5 String
+		"1.3.6.1.4.1.1466.115.121.1.26": {
+			{v: "test@example.com", valid: true},
+			{v: "t\u00e9st", valid: false},
+		},
+		// INTEGER
+		"1.3.6.1.4.1.1466.115.121.1.27": {
+			{v: "0", valid: true},
+			{v: "-1234", valid: true},
+			{v: "01", valid: false},
+			{v: "-0", valid: false},
+			{v: "1a", valid: false},
+		},
+		// JPEG
+		"1.3.6.1.4.1.1466.115.121.1.28": {
+			{v: "\xFF\xD8\xFF\xE0\x00\x10JFIF", valid: true},
+			{v: "GIF89a", valid: false},
+		},
+		// Name And Optional UID
+		"1.3.6.1.4.1.1466.115.121.1.34": {
+			{v: "cn=Test,dc=com#'0101'B", valid: true},
+			{v: "cn=Test,dc=com", valid: true},
+			{v: "cn=Test,dc=com#'0102'B", valid: false},
+		},
+		// Numeric String
+		"1.3.6.1.4.1.1466.115.121.1.36": {
+			{v: "15 079 672 281", valid: true},
+			{v: "15-079", valid: false},
+			{v: "", valid: false},
+		},
+		// OID
+		"1.3.6.1.4.1.1466.115.121.1.38": {
+			{v: "1.2.3.4", valid: true},
+			{v: "cn", valid: true},
+			{v: "1.2.", valid: false},
+			{v: "-cn", valid: false},
+		},
+		// Other Mailbox
+		"1.3.6.1.4.1.1466.115.121.1.39": {
+			{v: "smtp$test@example.com", valid: true},
+			{v: "smtp", valid: false},
+			{v: "smtp$", valid: false},
+		},
+		// Postal Address
+		"1.3.6.1.4.1.1466.115.121.1.41": {
+			{v: "1 Test St$Melbourne$Australia", valid: true},
+			{v: "1 Test St\\24$Melbourne", valid: true},
+			{v: "1 Test St$$Australia", valid: false},
+		},
+		// Printable String
+		"1.3.6.1.4.1.1466.115.121.1.44": {
+			{v: "Test (1)", valid: true},
+			{v: "Test!", valid: false},
+		},
+		// Telephone Number
+		"1.3.6.1.4.1.1466.115.121.1.50": {
+			{v: "+1 512 315 0280", valid: true},
+			{v: "", valid: false},
+		},
+		// Teletex Terminal Identifier
+		"1.3.6.1.4.1.1466.115.121.1.51": {
+			{v: "terminal$graphic:abc$page:\\24", valid: true},
+			{v: "terminal$colour:abc", valid: false},
+			{v: "terminal$graphic:\\41", valid: false},
+		},
+		// Telex Number
+		"1.3.6.1.4.1.1466.115.121.1.52": {
+			{v: "812374$ch$ehhg", valid: true},
+			{v: "812374$ch", valid: false},
+		},
+		// UTC Time
+		"1.3.6.1.4.1.1466.115.121.1.53": {
+			{v: "9412161032Z", valid: true},
+			{v: "941216103212-0500", valid: true},
+			{v: "9413161032Z", valid: false},
+		},
+		// Substring Assertion
+		"1.3.6.1.4.1.1466.115.121.1.58": {
+			{v: "a*b*c", valid: true},
+			{v: "*b", valid: true},
+			{v: "a**c", valid: false},
+		},
+		// UUID (RFC 4530)
+		"1.3.6.1.1.16.1": {
+			{v: "597ae2f6-16a6-1027-98f4-d28b5365dc14", valid: true},
+			{v: "597AE2F6-16A6-1027-98F4-D28B5365DC14", valid: true},
+			{v: "597ae2f616a6102798f4d28b5365dc14", valid: false},
+			{v: "597ae2f6-16a6-1027-98f4-d28b5365dc1g", valid: false},
+		},
+	}
+
+	for oid, syntaxTests := range tests {
+		testSyntax(syntaxTests, oid, t)
+	}
+}
+
+func TestEverySyntaxValidates(t *testing.T) {
+	for oid, s := range syntaxes {
+		if s.validate == nil {
+			t.Errorf("syntax %s has no validator", oid)
+		}
+	}
+}
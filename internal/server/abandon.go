@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Returned by handlers that stopped because their operation was abandoned,
+// no response is sent for an abandoned operation
+var AbandonedError = errors.New("operation abandoned")
+
+// The operations in flight on a connection, keyed by message id
+type operations struct {
+	mu      sync.Mutex
+	cancels map[int]context.CancelFunc
+}
+
+func newOperations() *operations {
+	return &operations{cancels: map[int]context.CancelFunc{}}
+}
+
+// Returns a context for the operation that is cancelled when it is abandoned.
+// Message ids must be unique among the operations in flight (RFC 4511
+// 4.1.1.1), ok is false if msgId is already in use.
+func (o *operations) start(ctx context.Context, msgId int) (opCtx context.Context, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, inUse := o.cancels[msgId]; inUse {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	o.cancels[msgId] = cancel
+	return ctx, true
+}
+
+func (o *operations) finish(msgId int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if cancel, ok := o.cancels[msgId]; ok {
+		cancel()
+		delete(o.cancels, msgId)
+	}
+}
+
+// Returns false if there is no operation in flight with the message id. An
+// abandoned operation keeps its message id until it finishes.
+func (o *operations) abandon(msgId int) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cancel, ok := o.cancels[msgId]
+	if !ok {
+		return false
+	}
+
+	cancel()
+	return true
+}
+
+// The number of operations in flight
+func (o *operations) outstanding() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.cancels)
+}
+
+func (o *operations) abandonAll() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, cancel := range o.cancels {
+		cancel()
+	}
+}
+
+// Abandon has no response, unknown or finished message ids are ignored (RFC 4511 4.11)
+var AbandonHandler = HandleFunc(AbandonRequestTag, AbandonRequestTag, func(ctx context.Context, w io.Writer, msg LdapMsg) error {
+	_, req, ok := msg.Request.Chosen()
+	if !ok {
+		return nil
+	}
+
+	abandonId, ok := req.(*int)
+	if !ok {
+		logger.Printf("abandon request did not contain a message id")
+		return nil
+	}
+
+	ops, ok := ctx.Value(OperationsKey).(*operations)
+	if !ok {
+		return nil
+	}
+
+	if ops.abandon(*abandonId) {
+		logger.Printf("abandoned operation %d", *abandonId)
+	}
+	return nil
+})
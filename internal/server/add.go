@@ -81,7 +81,7 @@ func (a *AddHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (err
 		return
 	}
 
-	entry, addErr := a.as.AddEntry(ar)
+	entry, addErr := a.as.AddEntry(boundEntry(ctx), ar)
 	if addErr != nil {
 		err = addErr
 		return
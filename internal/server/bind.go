@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -69,6 +70,52 @@ func (br BindRequest) SaslCredentials() (string, bool) {
 	return "", false
 }
 
+// A bind request with the state of its connection, the SASL session and the
+// verified TLS client certificate used by the SASL EXTERNAL mechanism if any
+type connBindRequest struct {
+	*BindRequest
+	session *app.SaslSession
+	cert    *x509.Certificate
+}
+
+func (r connBindRequest) SaslSession() *app.SaslSession {
+	return r.session
+}
+
+func (r connBindRequest) ExternalIdentity() (app.ExternalIdentity, bool) {
+	if r.cert == nil {
+		return app.ExternalIdentity{}, false
+	}
+
+	return app.ExternalIdentity{
+		SubjectDN:  r.cert.Subject.String(),
+		CommonName: r.cert.Subject.CommonName,
+	}, true
+}
+
+type BindResponse struct {
+	ResultCode        d.ResultCode `ber:"class=universal,cons=primitive,val=10"` // enumerated
+	MatchedDN         string
+	DiagnosticMessage string
+	Referral          *ber.Optional[[]byte]
+	ServerSaslCreds   *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=7"`
+}
+
+// The server SASL credentials are left out of the response if nil
+func NewBindResponse(msgId int, rc d.ResultCode, matchedDn string, creds *ber.Optional[string], format string, a ...any) LdapMsg {
+	res := BindResponse{
+		ResultCode:        rc,
+		MatchedDN:         matchedDn,
+		DiagnosticMessage: fmt.Sprintf(format, a...),
+		ServerSaslCreds:   creds,
+	}
+
+	return LdapMsg{
+		MessageId: msgId,
+		Request:   ber.NewChosen[LdapMsgChoice](BindResponseTag, res),
+	}
+}
+
 type BindHandler struct {
 	bs app.BindService
 }
@@ -118,14 +165,40 @@ func (b *BindHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (err
 	}
 	logger.Print("extracted bind request")
 
-	entry, autherr := b.bs.Bind(br)
-	_ = autherr
+	boundEntryVal := ctx.Value(BoundEntryKey)
+	be, ok := boundEntryVal.(**d.Entry)
+	if !ok {
+		return fmt.Errorf("bound entry did not exist or was not an entry pointer pointer")
+	}
+
+	// the connection is anonymous until a bind succeeds (RFC 4511 4.2.1)
+	*be = nil
+
+	session, ok := ctx.Value(SaslSessionKey).(*app.SaslSession)
+	if !ok {
+		session = &app.SaslSession{}
+	}
+
+	cbr := connBindRequest{BindRequest: br, session: session}
+	if t, ok := ctx.Value(TransportKey).(*transport); ok {
+		if cert, ok := t.clientCertificate(); ok {
+			cbr.cert = cert
+		}
+	}
+
+	entry, autherr := b.bs.Bind(cbr)
+
+	var creds *ber.Optional[string]
+	if c, ok := session.ServerCredentials(); ok {
+		creds = ber.NewOptional(c)
+	}
+
 	if lerr, ok := autherr.(d.LdapError); ok {
 		logger.Print("caught ldaperror in simple")
-		res = NewResultMsg(BindResponseTag,
-			msg.MessageId,
+		res = NewBindResponse(msg.MessageId,
 			lerr.ResultCode,
 			lerr.MatchedDN.String(),
+			creds,
 			"%s",
 			lerr.DiagnosticMessage,
 		)
@@ -137,25 +210,27 @@ func (b *BindHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (err
 	}
 
 	logger.Print("auth success")
-
-	// TODO this is probably horribly unthreadsafe
-	boundEntryVal := ctx.Value(BoundEntryKey)
-	be, ok := boundEntryVal.(**d.Entry)
-	if !ok {
-		return fmt.Errorf("bound entry did not exist or was not an entry pointer pointer")
-	}
 	*be = entry
 
-	res = NewResultMsg(BindResponseTag,
-		msg.MessageId,
+	res = NewBindResponse(msg.MessageId,
 		d.Success,
 		br.Name,
-		"bind request successfult (no password checking yet)",
+		creds,
+		"",
 	)
 
 	return
 }
 
+// Returns the entry the connection is bound as, or nil if it is anonymous
+func boundEntry(ctx context.Context) *d.Entry {
+	be, ok := ctx.Value(BoundEntryKey).(**d.Entry)
+	if !ok {
+		return nil
+	}
+	return *be
+}
+
 var UnbindError = errors.New("unbind request recieved")
 
 var UnbindHandler = HandleFunc(UnbindRequestTag, UnbindRequestTag, func(ctx context.Context, w io.Writer, msg LdapMsg) error {
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/internal/util"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+// the server-final-message of the SCRAM-SHA-1 exchange in RFC 5802 5
+const scramServerFinal = "v=rmF9pqV8S7suAoZWja4dJRkFsKQ="
+
+func TestBindResponseWire(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds *ber.Optional[string]
+		b     []byte
+	}{
+		{
+			name:  "with server sasl creds",
+			creds: ber.NewOptional(scramServerFinal),
+			b: append([]byte{
+				0x30, 0x2C, // ldapmsg tag/len
+				0x02, 0x01, 0x01, // msgid: 1
+				0x61, 0x27, // bind response tag/len
+				0x0A, 0x01, 0x00, // resultCode: success
+				0x04, 0x00, // matchedDN: ""
+				0x04, 0x00, // diagnosticMessage: ""
+				0x87, 0x1E, // serverSaslCreds [7] tag/len
+			}, scramServerFinal...),
+		},
+		{
+			name:  "without server sasl creds",
+			creds: nil,
+			b: []byte{
+				0x30, 0x0C, // ldapmsg tag/len
+				0x02, 0x01, 0x01, // msgid: 1
+				0x61, 0x07, // bind response tag/len
+				0x0A, 0x01, 0x00, // resultCode: success
+				0x04, 0x00, // matchedDN: ""
+				0x04, 0x00, // diagnosticMessage: ""
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if _, err := ber.Encode(&buf, NewBindResponse(1, d.Success, "", test.creds, "")); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), test.b) {
+			t.Fatalf("%s: encoding:\n%s\ndid not match expected:\n%s\n", test.name, util.BytesAsHex(buf.Bytes()), util.BytesAsHex(test.b))
+		}
+
+		var msg LdapMsg
+		if err := ber.Decode(bytes.NewReader(test.b), &msg); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		tag, _, ok := msg.Request.Chosen()
+		if !ok || !tag.Equals(BindResponseTag) {
+			t.Fatalf("%s: decoded %s, expected a bind response", test.name, tag)
+		}
+
+		res := msg.Request.Choices.BindResponse
+		if res.ResultCode != d.Success {
+			t.Fatalf("%s: decoded result code %d, expected success", test.name, res.ResultCode)
+		}
+
+		creds, hasCreds := res.ServerSaslCreds.Get()
+		expCreds, expHasCreds := test.creds.Get()
+		if creds != expCreds || hasCreds != expHasCreds {
+			t.Fatalf("%s: decoded creds %q (%t), expected %q (%t)", test.name, creds, hasCreds, expCreds, expHasCreds)
+		}
+	}
+}
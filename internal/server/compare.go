@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/georgib0y/relientldap/internal/app"
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+type CompareRequest struct {
+	Entry string
+	Ava   AttributeValueAssertion
+}
+
+func (cr CompareRequest) Dn() string {
+	return cr.Entry
+}
+
+func (cr CompareRequest) Attribute() string {
+	return cr.Ava.AttributeDesc
+}
+
+func (cr CompareRequest) Value() string {
+	return cr.Ava.AssertionValue
+}
+
+func NewCompareResponse(msgId int, rc d.ResultCode, matchedDn, format string, a ...any) LdapMsg {
+	return NewResultMsg(CompareResponseTag, msgId, rc, matchedDn, format, a...)
+}
+
+type CompareHandler struct {
+	cs *app.CompareService
+}
+
+func NewCompareHandler(cs *app.CompareService) *CompareHandler {
+	return &CompareHandler{cs}
+}
+
+func (c *CompareHandler) RequestTag() ber.Tag {
+	return CompareRequestTag
+}
+
+func (c *CompareHandler) ResponseTag() ber.Tag {
+	return CompareResponseTag
+}
+
+func (c *CompareHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (err error) {
+	var res LdapMsg
+	defer func() {
+		if err == nil {
+			err = writeResponse(w, res)
+		}
+	}()
+
+	logger.Print("in compare request")
+
+	_, req, ok := msg.Request.Chosen()
+	if !ok {
+		res = NewCompareResponse(msg.MessageId, d.ProtocolError, "", "could not get compare req choice")
+		return
+	}
+
+	cr, ok := req.(*CompareRequest)
+	if !ok {
+		res = NewCompareResponse(
+			msg.MessageId,
+			d.ProtocolError,
+			"",
+			"expected *CompareRequest, got %s", reflect.TypeOf(req),
+		)
+		return
+	}
+
+	matched, compareErr := c.cs.Compare(boundEntry(ctx), cr)
+	if compareErr != nil {
+		err = compareErr
+		return
+	}
+
+	if matched {
+		res = NewCompareResponse(msg.MessageId, d.CompareTrue, "", "")
+	} else {
+		res = NewCompareResponse(msg.MessageId, d.CompareFalse, "", "")
+	}
+	return
+}
@@ -9,9 +9,10 @@ import (
 	"log"
 	"net"
 	"os"
+	"sync"
 
+	"github.com/georgib0y/relientldap/internal/app"
 	d "github.com/georgib0y/relientldap/internal/domain"
-	"github.com/georgib0y/relientldap/internal/util"
 	"github.com/georgib0y/relientldap/pkg/ber"
 )
 
@@ -19,6 +20,9 @@ type ContextKey int
 
 const (
 	BoundEntryKey ContextKey = iota
+	OperationsKey
+	TransportKey
+	SaslSessionKey
 )
 
 var logger = log.New(os.Stderr, "server: ", log.Lshortfile)
@@ -57,10 +61,13 @@ func HandleFunc(reqTag, resTag ber.Tag, handle func(context.Context, io.Writer,
 
 type Mux struct {
 	handlers map[ber.Tag]Handler
+	extended map[string]ExtendedHandler
 }
 
 func NewMux() *Mux {
-	return &Mux{map[ber.Tag]Handler{}}
+	m := &Mux{handlers: map[ber.Tag]Handler{}, extended: map[string]ExtendedHandler{}}
+	m.AddHandler(&extendedDispatcher{m})
+	return m
 }
 
 func (m *Mux) AddHandler(h Handler) *Mux {
@@ -104,20 +111,144 @@ func tryWriteErr(h Handler, w io.Writer, msgId int, err error) error {
 	return writeResponse(w, res)
 }
 
+// runs a request's handler, returning an error only if the connection can
+// no longer be written to
+func (m *Mux) handle(ctx context.Context, w io.Writer, h Handler, msg LdapMsg) error {
+	if oid, ok := unsupportedCriticalControl(h.RequestTag(), msg); ok {
+		res := NewResultMsg(h.ResponseTag(), msg.MessageId, d.UnavailableCriticalExtension, "", "critical control %s is not supported", oid)
+		return writeResponse(w, res)
+	}
+
+	err := h.Handle(ctx, w, msg)
+	if errors.Is(err, AbandonedError) {
+		logger.Printf("operation %d was abandoned", msg.MessageId)
+		return nil
+	} else if err != nil {
+		return tryWriteErr(h, w, msg.MessageId, err)
+	}
+
+	logger.Print("... sent response")
+	return nil
+}
+
+var ErrConnClosed = errors.New("connection closed")
+
+// Serialises the responses of the operations running on a connection, each
+// call to Write must be one whole message
+type connWriter struct {
+	w      io.Writer
+	msgs   chan pendingWrite
+	failed chan struct{}
+	done   chan struct{}
+}
+
+// a message to write, or a request to be told once everything before it has
+// been written if flushed is set
+type pendingWrite struct {
+	b       []byte
+	flushed chan struct{}
+}
+
+func newConnWriter(w io.Writer) *connWriter {
+	return &connWriter{
+		w:      w,
+		msgs:   make(chan pendingWrite, 64),
+		failed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (cw *connWriter) Write(p []byte) (int, error) {
+	select {
+	case cw.msgs <- pendingWrite{b: bytes.Clone(p)}:
+		return len(p), nil
+	case <-cw.failed:
+		return 0, ErrConnClosed
+	}
+}
+
+// waits until every message written so far is on the connection
+func (cw *connWriter) flush() error {
+	flushed := make(chan struct{})
+	select {
+	case cw.msgs <- pendingWrite{flushed: flushed}:
+	case <-cw.failed:
+		return ErrConnClosed
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-cw.failed:
+		return ErrConnClosed
+	}
+}
+
+// writes messages until close is called or a write fails, onFail is called
+// if a write fails
+func (cw *connWriter) run(onFail func()) {
+	defer close(cw.done)
+	for pw := range cw.msgs {
+		if pw.flushed != nil {
+			close(pw.flushed)
+			continue
+		}
+
+		if _, err := cw.w.Write(pw.b); err != nil {
+			logger.Printf("could not write to connection: %s", err)
+			close(cw.failed)
+			onFail()
+			return
+		}
+	}
+}
+
+// waits for every queued message to be written, nothing can be written after
+func (cw *connWriter) close() {
+	close(cw.msgs)
+	<-cw.done
+}
+
+// Serve reads messages continuously and runs each operation in its own
+// goroutine, responses are written by a single writer. A bind waits for all
+// outstanding operations to finish, and operations read after it wait for
+// the bind to finish (RFC 4511 4.2.1). StartTLS is run by the reader so that
+// nothing is read until the TLS handshake is done, and is refused if other
+// operations are outstanding. A request that reuses the message id of an
+// outstanding operation is refused with protocolError.
 func (m *Mux) Serve(c net.Conn) {
 	defer c.Close()
 
-	r := io.TeeReader(c, util.NewHexLogger("in"))
-	w := io.MultiWriter(util.NewHexLogger("out"), c)
+	t := newTransport(c)
+	cw := t.cw
+	go cw.run(func() { c.Close() })
 
 	boundEntry := new(*d.Entry)
+	ops := newOperations()
 	ctx := context.WithValue(context.Background(), BoundEntryKey, boundEntry)
+	ctx = context.WithValue(ctx, OperationsKey, ops)
+	ctx = context.WithValue(ctx, TransportKey, t)
+	ctx = context.WithValue(ctx, SaslSessionKey, &app.SaslSession{})
+
+	var (
+		running sync.WaitGroup
+		bindMu  sync.RWMutex
+	)
+
+	defer func() {
+		ops.abandonAll()
+		running.Wait()
+		cw.close()
+	}()
 
 	for {
 		logger.Print("recieving message...")
 		var msg LdapMsg
-		if err := ber.Decode(r, &msg); err != nil {
+		if err := ber.Decode(t.r, &msg); err != nil {
 			logger.Print(err)
+			if !errors.Is(err, io.EOF) {
+				writeResponse(cw, NewNoticeOfDisconnection(d.ProtocolError, "could not decode message"))
+			}
 			return
 		}
 
@@ -126,27 +257,75 @@ func (m *Mux) Serve(c net.Conn) {
 		tag, _, ok := msg.Request.Chosen()
 		if !ok {
 			logger.Printf("no choices was made for incoming ldap message")
+			writeResponse(cw, NewNoticeOfDisconnection(d.ProtocolError, "message has no protocol op"))
 			return
 		}
 		h, ok := m.handlers[tag]
 		if !ok {
 			logger.Printf("unkown ldapmsg tag %s", tag)
+			writeResponse(cw, NewNoticeOfDisconnection(d.ProtocolError, "unsupported protocol op %s", tag))
 			return
 		}
 
-		err := h.Handle(ctx, w, msg)
-		if errors.Is(err, UnbindError) {
-			logger.Print("recieved unbind request, closing connection")
-			return
-		} else if err != nil {
-			err = tryWriteErr(h, w, msg.MessageId, err)
+		// neither of these have a response so are handled by the reader
+		if tag == UnbindRequestTag || tag == AbandonRequestTag {
+			if err := h.Handle(ctx, cw, msg); errors.Is(err, UnbindError) {
+				logger.Print("recieved unbind request, closing connection")
+				return
+			}
+			continue
+		}
+
+		opCtx, ok := ops.start(ctx, msg.MessageId)
+		if !ok {
+			logger.Printf("message id %d is already in use", msg.MessageId)
+			res := NewResultMsg(h.ResponseTag(), msg.MessageId, d.ProtocolError, "", "message id %d is already in use by an outstanding operation", msg.MessageId)
+			if err := writeResponse(cw, res); err != nil {
+				return
+			}
+			continue
+		}
+
+		if isStartTLS(msg) {
+			// the client must wait for its other operations to finish before
+			// starting tls (RFC 4511 4.14.1)
+			if ops.outstanding() > 1 {
+				ops.finish(msg.MessageId)
+				res := NewResultMsg(h.ResponseTag(), msg.MessageId, d.OperationsError, "", "tls cannot be started while other operations are outstanding")
+				if err := writeResponse(cw, res); err != nil {
+					return
+				}
+				continue
+			}
+
+			bindMu.Lock()
+			err := m.handle(opCtx, cw, h, msg)
+			ops.finish(msg.MessageId)
+			bindMu.Unlock()
+
 			if err != nil {
-				logger.Printf("unrecoverable err: %s", err)
+				logger.Printf("could not start tls: %s", err)
 				return
 			}
+			continue
+		}
+
+		lock, unlock := bindMu.RLock, bindMu.RUnlock
+		if tag == BindRequestTag {
+			lock, unlock = bindMu.Lock, bindMu.Unlock
 		}
+		lock()
 
-		logger.Print("... sent response")
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			defer unlock()
+			defer ops.finish(msg.MessageId)
 
+			if err := m.handle(opCtx, cw, h, msg); err != nil {
+				logger.Printf("unrecoverable err: %s", err)
+				c.Close()
+			}
+		}()
 	}
 }
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+// how long a test waits for the server before giving up
+const testTimeout = 5 * time.Second
+
+// Serves one end of a pipe with m and returns the other end, which is closed
+// when the test ends. done is closed once Serve returns.
+func servePipe(t *testing.T, m *Mux) (client net.Conn, done <-chan struct{}) {
+	server, client := net.Pipe()
+	served := make(chan struct{})
+	go func() {
+		m.Serve(server)
+		close(served)
+	}()
+
+	t.Cleanup(func() {
+		client.Close()
+		select {
+		case <-served:
+		case <-time.After(testTimeout):
+			t.Error("Serve did not return after the connection was closed")
+		}
+	})
+	return client, served
+}
+
+func sendMsg(t *testing.T, c net.Conn, msg LdapMsg) {
+	t.Helper()
+	c.SetWriteDeadline(time.Now().Add(testTimeout))
+	if err := writeResponse(c, msg); err != nil {
+		t.Fatalf("could not send message %d: %s", msg.MessageId, err)
+	}
+}
+
+func receiveMsg(t *testing.T, c net.Conn) (LdapMsg, ber.Tag, any) {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(testTimeout))
+	var msg LdapMsg
+	if err := ber.Decode(c, &msg); err != nil {
+		t.Fatalf("could not receive a message: %s", err)
+	}
+
+	tag, res, ok := msg.Request.Chosen()
+	if !ok {
+		t.Fatalf("message %d has no protocol op", msg.MessageId)
+	}
+	return msg, tag, res
+}
+
+func searchRequest(msgId int, base string) LdapMsg {
+	sr := SearchRequest{
+		BaseObject: base,
+		Scope:      d.BaseObject,
+		Filter:     ber.NewChosen[FilterChoice](FilterPresentTag, "objectClass"),
+		Attributes: []string{},
+	}
+	return LdapMsg{MessageId: msgId, Request: ber.NewChosen[LdapMsgChoice](SearchRequestTag, sr)}
+}
+
+func simpleBindRequest(msgId int, name, password string) LdapMsg {
+	br := BindRequest{Ver: 3, Name: name, Auth: ber.NewChosen[BindReqChoice](BrSimpleTag, password)}
+	return LdapMsg{MessageId: msgId, Request: ber.NewChosen[LdapMsgChoice](BindRequestTag, br)}
+}
+
+func unbindRequest(msgId int) LdapMsg {
+	return LdapMsg{MessageId: msgId, Request: ber.NewChosen[LdapMsgChoice](UnbindRequestTag, "")}
+}
+
+// A search handler that returns its base object as the only entry
+func echoSearchHandler(before func(msgId int)) Handler {
+	return HandleFunc(SearchRequestTag, SearchResultDoneTag, func(ctx context.Context, w io.Writer, msg LdapMsg) error {
+		before(msg.MessageId)
+
+		_, req, _ := msg.Request.Chosen()
+		sr := req.(*SearchRequest)
+		res := SearchResultEntry{ObjectName: sr.BaseObject, Attributes: []PartialAttribute{}}
+		entry := LdapMsg{MessageId: msg.MessageId, Request: ber.NewChosen[LdapMsgChoice](SearchResultEntryTag, res)}
+		if err := writeResponse(w, entry); err != nil {
+			return err
+		}
+		return writeResponse(w, NewSearchResultDone(msg.MessageId, d.Success, "", ""))
+	})
+}
+
+func TestPipelinedSearchesKeepTheirMessageIds(t *testing.T) {
+	const searches = 10
+
+	// later searches finish first so that the responses interleave
+	m := NewMux().AddHandler(echoSearchHandler(func(msgId int) {
+		time.Sleep(time.Duration(searches-msgId) * 5 * time.Millisecond)
+	}))
+	c, _ := servePipe(t, m)
+
+	for i := 1; i <= searches; i++ {
+		sendMsg(t, c, searchRequest(i, fmt.Sprintf("cn=%d", i)))
+	}
+
+	entries := map[int]int{}
+	done := map[int]bool{}
+	for len(done) < searches {
+		msg, tag, res := receiveMsg(t, c)
+		switch {
+		case tag.Equals(SearchResultEntryTag):
+			if base := res.(*SearchResultEntry).ObjectName; base != fmt.Sprintf("cn=%d", msg.MessageId) {
+				t.Fatalf("message %d returned the entry of search %s", msg.MessageId, base)
+			}
+			if done[msg.MessageId] {
+				t.Fatalf("message %d returned an entry after it was done", msg.MessageId)
+			}
+			entries[msg.MessageId]++
+		case tag.Equals(SearchResultDoneTag):
+			if done[msg.MessageId] {
+				t.Fatalf("message %d was done twice", msg.MessageId)
+			}
+			done[msg.MessageId] = true
+		default:
+			t.Fatalf("unexpected response %s to message %d", tag, msg.MessageId)
+		}
+	}
+
+	for i := 1; i <= searches; i++ {
+		if entries[i] != 1 {
+			t.Errorf("search %d returned %d entries, expected 1", i, entries[i])
+		}
+	}
+}
+
+func TestBindWaitsForOutstandingOperations(t *testing.T) {
+	release := make(chan struct{})
+	bound := make(chan struct{})
+
+	m := NewMux().
+		AddHandler(echoSearchHandler(func(int) { <-release })).
+		AddHandler(HandleFunc(BindRequestTag, BindResponseTag, func(ctx context.Context, w io.Writer, msg LdapMsg) error {
+			close(bound)
+			return writeResponse(w, NewBindResponse(msg.MessageId, d.Success, "", nil, ""))
+		}))
+	c, _ := servePipe(t, m)
+
+	sendMsg(t, c, searchRequest(1, "cn=1"))
+	sendMsg(t, c, simpleBindRequest(2, "", ""))
+
+	select {
+	case <-bound:
+		t.Fatal("bind ran while a search was outstanding")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	exp := []struct {
+		msgId int
+		tag   ber.Tag
+	}{
+		{1, SearchResultEntryTag},
+		{1, SearchResultDoneTag},
+		{2, BindResponseTag},
+	}
+	for _, e := range exp {
+		msg, tag, _ := receiveMsg(t, c)
+		if msg.MessageId != e.msgId || !tag.Equals(e.tag) {
+			t.Fatalf("received %s for message %d, expected %s for message %d", tag, msg.MessageId, e.tag, e.msgId)
+		}
+	}
+}
+
+// A search handler that writes entries until its operation is abandoned or
+// the connection cannot be written to
+func endlessSearchHandler() Handler {
+	return HandleFunc(SearchRequestTag, SearchResultDoneTag, func(ctx context.Context, w io.Writer, msg LdapMsg) error {
+		for i := 0; ; i++ {
+			if ctx.Err() != nil {
+				return AbandonedError
+			}
+
+			res := SearchResultEntry{ObjectName: fmt.Sprintf("cn=%d", i), Attributes: []PartialAttribute{}}
+			entry := LdapMsg{MessageId: msg.MessageId, Request: ber.NewChosen[LdapMsgChoice](SearchResultEntryTag, res)}
+			if err := writeResponse(w, entry); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+func TestUnbindWhileWritingResponses(t *testing.T) {
+	c, done := servePipe(t, NewMux().AddHandler(endlessSearchHandler()).AddHandler(UnbindHandler))
+
+	sendMsg(t, c, searchRequest(1, ""))
+	// make sure the search is writing before the unbind is sent
+	receiveMsg(t, c)
+	sendMsg(t, c, unbindRequest(2))
+
+	// the responses already queued are written before the connection closes
+	for {
+		c.SetReadDeadline(time.Now().Add(testTimeout))
+		var msg LdapMsg
+		err := ber.Decode(c, &msg)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("could not receive a message: %s", err)
+		}
+
+		if tag, _, _ := msg.Request.Chosen(); msg.MessageId != 1 || !tag.Equals(SearchResultEntryTag) {
+			t.Fatalf("received %s for message %d after unbinding", tag, msg.MessageId)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after an unbind")
+	}
+}
+
+func TestCloseWhileWritingResponses(t *testing.T) {
+	c, done := servePipe(t, NewMux().AddHandler(endlessSearchHandler()))
+
+	sendMsg(t, c, searchRequest(1, ""))
+	receiveMsg(t, c)
+	c.Close()
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after the client closed the connection")
+	}
+}
+
+func TestDuplicateMessageIdIsRefused(t *testing.T) {
+	release := make(chan struct{})
+	m := NewMux().AddHandler(echoSearchHandler(func(int) { <-release }))
+	c, _ := servePipe(t, m)
+
+	sendMsg(t, c, searchRequest(1, "cn=first"))
+	sendMsg(t, c, searchRequest(1, "cn=second"))
+
+	// the second search is refused while the first is still running
+	msg, tag, res := receiveMsg(t, c)
+	if !tag.Equals(SearchResultDoneTag) || res.(*LdapResult).ResultCode != d.ProtocolError {
+		t.Fatalf("expected a protocol error for the duplicate message id, got %s %v", tag, res)
+	}
+	if msg.MessageId != 1 {
+		t.Fatalf("expected the refusal to be for message 1, got %d", msg.MessageId)
+	}
+
+	close(release)
+
+	_, tag, res = receiveMsg(t, c)
+	if !tag.Equals(SearchResultEntryTag) || res.(*SearchResultEntry).ObjectName != "cn=first" {
+		t.Fatalf("expected the entry of the first search, got %s %v", tag, res)
+	}
+	if _, tag, _ = receiveMsg(t, c); !tag.Equals(SearchResultDoneTag) {
+		t.Fatalf("expected the first search to be done, got %s", tag)
+	}
+}
+
+func TestAbandonedOperationKeepsItsMessageId(t *testing.T) {
+	ops := newOperations()
+
+	ctx, ok := ops.start(context.Background(), 1)
+	if !ok {
+		t.Fatal("could not start operation 1")
+	}
+
+	if !ops.abandon(1) {
+		t.Fatal("operation 1 was not in flight")
+	}
+	if ctx.Err() == nil {
+		t.Fatal("abandoning operation 1 did not cancel its context")
+	}
+
+	// still running until its handler returns
+	if _, ok := ops.start(context.Background(), 1); ok {
+		t.Fatal("message id 1 was reused while its abandoned operation was running")
+	}
+
+	ops.finish(1)
+	if _, ok := ops.start(context.Background(), 1); !ok {
+		t.Fatal("message id 1 could not be reused after its operation finished")
+	}
+}
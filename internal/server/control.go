@@ -0,0 +1,72 @@
+package server
+
+import (
+	"slices"
+
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+const (
+	TreeDeleteControlOid = "1.2.840.113556.1.4.805"
+)
+
+type Control struct {
+	ControlType  string
+	Criticality  *ber.Optional[bool]
+	ControlValue *ber.Optional[string]
+}
+
+func (c Control) Critical() bool {
+	critical, _ := c.Criticality.Get()
+	return critical
+}
+
+// the controls each request type understands, any other critical control
+// fails the request with unavailableCriticalExtension (RFC 4511 4.1.11)
+var supportedControls = map[ber.Tag][]string{
+	DelRequestTag: {TreeDeleteControlOid},
+}
+
+// The controls understood by the request types with a handler on the mux, for
+// the supportedControl attribute of the root DSE
+func (m *Mux) SupportedControls() []string {
+	oids := []string{}
+	for tag, controls := range supportedControls {
+		if _, ok := m.handlers[tag]; !ok {
+			continue
+		}
+
+		for _, oid := range controls {
+			if !slices.Contains(oids, oid) {
+				oids = append(oids, oid)
+			}
+		}
+	}
+	slices.Sort(oids)
+	return oids
+}
+
+// Returns the control with the oid if it was sent with the message
+func (m LdapMsg) Control(oid string) (Control, bool) {
+	controls, _ := m.Controls.Get()
+	for _, c := range controls {
+		if c.ControlType == oid {
+			return c, true
+		}
+	}
+	return Control{}, false
+}
+
+func unsupportedCriticalControl(tag ber.Tag, msg LdapMsg) (string, bool) {
+	controls, _ := msg.Controls.Get()
+	for _, c := range controls {
+		if !c.Critical() {
+			continue
+		}
+
+		if !slices.Contains(supportedControls[tag], c.ControlType) {
+			return c.ControlType, true
+		}
+	}
+	return "", false
+}
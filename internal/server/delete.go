@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/georgib0y/relientldap/internal/app"
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+// DelRequest is just an LDAPDN, so the tree delete control is carried with it
+type DelRequest struct {
+	Entry      string
+	treeDelete bool
+}
+
+func (dr DelRequest) Dn() string {
+	return dr.Entry
+}
+
+func (dr DelRequest) TreeDelete() bool {
+	return dr.treeDelete
+}
+
+func NewDelResponse(msgId int, rc d.ResultCode, matchedDn, format string, a ...any) LdapMsg {
+	return NewResultMsg(DelResponseTag, msgId, rc, matchedDn, format, a...)
+}
+
+type DeleteHandler struct {
+	ds *app.DeleteService
+}
+
+func NewDeleteHandler(ds *app.DeleteService) *DeleteHandler {
+	return &DeleteHandler{ds}
+}
+
+func (h *DeleteHandler) RequestTag() ber.Tag {
+	return DelRequestTag
+}
+
+func (h *DeleteHandler) ResponseTag() ber.Tag {
+	return DelResponseTag
+}
+
+func (h *DeleteHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (err error) {
+	var res LdapMsg
+	defer func() {
+		if err == nil {
+			err = writeResponse(w, res)
+		}
+	}()
+
+	logger.Print("in delete request")
+
+	_, req, ok := msg.Request.Chosen()
+	if !ok {
+		res = NewDelResponse(msg.MessageId, d.ProtocolError, "", "could not get delete req choice")
+		return
+	}
+
+	entry, ok := req.(*string)
+	if !ok {
+		res = NewDelResponse(
+			msg.MessageId,
+			d.ProtocolError,
+			"",
+			"expected *string, got %s", reflect.TypeOf(req),
+		)
+		return
+	}
+
+	_, treeDelete := msg.Control(TreeDeleteControlOid)
+	dr := DelRequest{Entry: *entry, treeDelete: treeDelete}
+
+	delErr := h.ds.DeleteEntry(dr)
+	if delErr != nil {
+		err = delErr
+		return
+	}
+
+	logger.Printf("deleted entry: %s", dr.Dn())
+	res = NewDelResponse(msg.MessageId, d.Success, "", "deleted entry at: %s", dr.Dn())
+	return
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+const (
+	NoticeOfDisconnectionOid = "1.3.6.1.4.1.1466.20036"
+)
+
+type ExtendedRequest struct {
+	RequestName  string                `ber:"class=context-specific,cons=primitive,val=0"`
+	RequestValue *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=1"`
+}
+
+func (er ExtendedRequest) Name() string {
+	return er.RequestName
+}
+
+func (er ExtendedRequest) Value() (string, bool) {
+	return er.RequestValue.Get()
+}
+
+type ExtendedResponse struct {
+	ResultCode        d.ResultCode `ber:"class=universal,cons=primitive,val=10"` // enumerated
+	MatchedDN         string
+	DiagnosticMessage string
+	Referral          *ber.Optional[[]byte]
+	ResponseName      *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=10"`
+	ResponseValue     *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=11"`
+}
+
+// The response name and value are left out of the response if nil
+func NewExtendedResponse(msgId int, rc d.ResultCode, name, value *ber.Optional[string], format string, a ...any) LdapMsg {
+	res := ExtendedResponse{
+		ResultCode:        rc,
+		DiagnosticMessage: fmt.Sprintf(format, a...),
+		ResponseName:      name,
+		ResponseValue:     value,
+	}
+
+	return LdapMsg{
+		MessageId: msgId,
+		Request:   ber.NewChosen[LdapMsgChoice](ExtendedResponseTag, res),
+	}
+}
+
+// Sent before the server closes a connection it can no longer use (RFC 4511 4.4.1)
+func NewNoticeOfDisconnection(rc d.ResultCode, format string, a ...any) LdapMsg {
+	return NewExtendedResponse(0, rc, ber.NewOptional(NoticeOfDisconnectionOid), nil, format, a...)
+}
+
+type IntermediateResponse struct {
+	ResponseName  *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=0"`
+	ResponseValue *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=1"`
+}
+
+func NewIntermediateResponse(msgId int, name, value *ber.Optional[string]) LdapMsg {
+	res := IntermediateResponse{ResponseName: name, ResponseValue: value}
+
+	return LdapMsg{
+		MessageId: msgId,
+		Request:   ber.NewChosen[LdapMsgChoice](IntermediateResponseTag, res),
+	}
+}
+
+// Handles the extended operation identified by its request name
+type ExtendedHandler interface {
+	RequestName() string
+	Handle(ctx context.Context, w io.Writer, msgId int, req *ExtendedRequest) error
+}
+
+type extendedHandleFunc struct {
+	name   string
+	handle func(context.Context, io.Writer, int, *ExtendedRequest) error
+}
+
+func (h *extendedHandleFunc) RequestName() string {
+	return h.name
+}
+
+func (h *extendedHandleFunc) Handle(ctx context.Context, w io.Writer, msgId int, req *ExtendedRequest) error {
+	return h.handle(ctx, w, msgId, req)
+}
+
+func ExtendedHandleFunc(name string, handle func(context.Context, io.Writer, int, *ExtendedRequest) error) ExtendedHandler {
+	return &extendedHandleFunc{name, handle}
+}
+
+func (m *Mux) AddExtendedHandler(h ExtendedHandler) *Mux {
+	m.extended[h.RequestName()] = h
+	return m
+}
+
+// The request names of the registered extended operations, for the
+// supportedExtension attribute of the root DSE
+func (m *Mux) SupportedExtensions() []string {
+	oids := []string{}
+	for oid := range m.extended {
+		oids = append(oids, oid)
+	}
+	slices.Sort(oids)
+	return oids
+}
+
+// Dispatches extended requests to the handler registered for the request name
+type extendedDispatcher struct {
+	m *Mux
+}
+
+func (e *extendedDispatcher) RequestTag() ber.Tag {
+	return ExtendedRequestTag
+}
+
+func (e *extendedDispatcher) ResponseTag() ber.Tag {
+	return ExtendedResponseTag
+}
+
+func (e *extendedDispatcher) Handle(ctx context.Context, w io.Writer, msg LdapMsg) error {
+	logger.Print("in extended request")
+
+	_, req, ok := msg.Request.Chosen()
+	if !ok {
+		return writeResponse(w, NewExtendedResponse(msg.MessageId, d.ProtocolError, nil, nil, "could not get extended req choice"))
+	}
+
+	er, ok := req.(*ExtendedRequest)
+	if !ok {
+		return writeResponse(w, NewExtendedResponse(msg.MessageId, d.ProtocolError, nil, nil, "expected *ExtendedRequest, got %s", reflect.TypeOf(req)))
+	}
+
+	h, ok := e.m.extended[er.Name()]
+	if !ok {
+		return writeResponse(w, NewExtendedResponse(msg.MessageId, d.ProtocolError, nil, nil, "unsupported extended operation %s", er.Name()))
+	}
+
+	return h.Handle(ctx, w, msg.MessageId, er)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+func extendedRequest(msgId int, name string, value *ber.Optional[string]) LdapMsg {
+	er := ExtendedRequest{RequestName: name, RequestValue: value}
+	return LdapMsg{MessageId: msgId, Request: ber.NewChosen[LdapMsgChoice](ExtendedRequestTag, er)}
+}
+
+func receiveExtendedResponse(t *testing.T, c net.Conn, msgId int) *ExtendedResponse {
+	t.Helper()
+	msg, tag, res := receiveMsg(t, c)
+	if !tag.Equals(ExtendedResponseTag) {
+		t.Fatalf("received %s, expected an extended response", tag)
+	}
+	if msg.MessageId != msgId {
+		t.Fatalf("received a response to message %d, expected %d", msg.MessageId, msgId)
+	}
+	return res.(*ExtendedResponse)
+}
+
+func TestExtendedUnknownRequestName(t *testing.T) {
+	c, _ := servePipe(t, NewMux())
+
+	sendMsg(t, c, extendedRequest(1, "1.2.3.4", nil))
+	res := receiveExtendedResponse(t, c, 1)
+
+	if res.ResultCode != d.ProtocolError {
+		t.Errorf("expected a protocol error, got %s", res.ResultCode)
+	}
+	if name, ok := res.ResponseName.Get(); ok {
+		t.Errorf("expected the response name to be omitted, got %q", name)
+	}
+}
+
+func TestExtendedDispatchesToRegisteredHandler(t *testing.T) {
+	const oid = "1.2.3.4"
+
+	var got *ExtendedRequest
+	m := NewMux().AddExtendedHandler(ExtendedHandleFunc(oid, func(ctx context.Context, w io.Writer, msgId int, req *ExtendedRequest) error {
+		got = req
+		return writeResponse(w, NewExtendedResponse(msgId, d.Success, ber.NewOptional(oid), ber.NewOptional("pong"), ""))
+	}))
+	c, _ := servePipe(t, m)
+
+	sendMsg(t, c, extendedRequest(1, oid, ber.NewOptional("ping")))
+	res := receiveExtendedResponse(t, c, 1)
+
+	if res.ResultCode != d.Success {
+		t.Fatalf("expected success, got %s: %s", res.ResultCode, res.DiagnosticMessage)
+	}
+	if v, _ := got.Value(); got.Name() != oid || v != "ping" {
+		t.Errorf("handler got request %q with value %q, expected %q with value %q", got.Name(), v, oid, "ping")
+	}
+	if v, _ := res.ResponseValue.Get(); v != "pong" {
+		t.Errorf("expected the handler's response, got value %q", v)
+	}
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"reflect"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+var (
+	FilterAndTag             = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 0}
+	FilterOrTag              = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 1}
+	FilterNotTag             = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 2}
+	FilterEqualityMatchTag   = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 3}
+	FilterSubstringsTag      = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 4}
+	FilterGreaterOrEqualTag  = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 5}
+	FilterLessOrEqualTag     = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 6}
+	FilterPresentTag         = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Primitive, Value: 7}
+	FilterApproxMatchTag     = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 8}
+	FilterExtensibleMatchTag = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Constructed, Value: 9}
+
+	SubstringInitialTag = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Primitive, Value: 0}
+	SubstringAnyTag     = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Primitive, Value: 1}
+	SubstringFinalTag   = ber.Tag{Class: ber.ContextSpecific, Construct: ber.Primitive, Value: 2}
+)
+
+type AttributeValueAssertion struct {
+	AttributeDesc  string
+	AssertionValue string
+}
+
+type SubstringChoice struct {
+	Initial string `ber:"class=context-specific,cons=primitive,val=0"`
+	Any     string `ber:"class=context-specific,cons=primitive,val=1"`
+	Final   string `ber:"class=context-specific,cons=primitive,val=2"`
+}
+
+type SubstringFilter struct {
+	Type       string
+	Substrings []*ber.Choice[SubstringChoice]
+}
+
+type MatchingRuleAssertion struct {
+	MatchingRule *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=1"`
+	Type         *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=2"`
+	MatchValue   string                `ber:"class=context-specific,cons=primitive,val=3"`
+	DnAttributes *ber.Optional[bool]   `ber:"class=context-specific,cons=primitive,val=4"`
+}
+
+type FilterChoice struct {
+	And             []*ber.Choice[FilterChoice] `ber:"class=context-specific,cons=constructed,val=0"`
+	Or              []*ber.Choice[FilterChoice] `ber:"class=context-specific,cons=constructed,val=1"`
+	Not             *ber.Choice[FilterChoice]   `ber:"class=context-specific,cons=constructed,val=2"`
+	EqualityMatch   AttributeValueAssertion     `ber:"class=context-specific,cons=constructed,val=3"`
+	Substrings      SubstringFilter             `ber:"class=context-specific,cons=constructed,val=4"`
+	GreaterOrEqual  AttributeValueAssertion     `ber:"class=context-specific,cons=constructed,val=5"`
+	LessOrEqual     AttributeValueAssertion     `ber:"class=context-specific,cons=constructed,val=6"`
+	Present         string                      `ber:"class=context-specific,cons=primitive,val=7"`
+	ApproxMatch     AttributeValueAssertion     `ber:"class=context-specific,cons=constructed,val=8"`
+	ExtensibleMatch MatchingRuleAssertion       `ber:"class=context-specific,cons=constructed,val=9"`
+}
+
+// attribute descriptions not in the schema are kept with a nil attribute so
+// that the filter item evaluates to undefined
+func findAttribute(schema *d.Schema, desc string) *d.Attribute {
+	attr, _ := schema.FindAttributeDesc(desc)
+	return attr
+}
+
+func domainFilters(schema *d.Schema, filters []*ber.Choice[FilterChoice]) ([]d.Filter, error) {
+	dfs := []d.Filter{}
+	for _, f := range filters {
+		df, err := domainFilter(schema, f)
+		if err != nil {
+			return nil, err
+		}
+		dfs = append(dfs, df)
+	}
+	return dfs, nil
+}
+
+func domainSubstringsFilter(schema *d.Schema, sf *SubstringFilter) (d.Filter, error) {
+	if len(sf.Substrings) == 0 {
+		return nil, d.NewLdapError(d.ProtocolError, nil, "substrings filter has no substrings")
+	}
+
+	filter := &d.SubstringsFilter{
+		Desc: sf.Type,
+		Attr: findAttribute(schema, sf.Type),
+		Any:  []string{},
+	}
+
+	for i, s := range sf.Substrings {
+		tag, val, ok := s.Chosen()
+		if !ok {
+			return nil, d.NewLdapError(d.ProtocolError, nil, "could not get substring choice")
+		}
+
+		str, ok := val.(*string)
+		if !ok {
+			return nil, d.NewLdapError(d.ProtocolError, nil, "unexpected substring value %s for %s", reflect.TypeOf(val), tag)
+		}
+
+		// initial may only be first and final may only be last (RFC 4511 4.5.1.7.2)
+		switch {
+		case tag.Equals(SubstringInitialTag) && i == 0:
+			filter.Initial = *str
+		case tag.Equals(SubstringAnyTag):
+			filter.Any = append(filter.Any, *str)
+		case tag.Equals(SubstringFinalTag) && i == len(sf.Substrings)-1:
+			filter.Final = *str
+		default:
+			return nil, d.NewLdapError(d.ProtocolError, nil, "unexpected substring %s at position %d", tag, i)
+		}
+	}
+
+	return filter, nil
+}
+
+func domainExtensibleFilter(schema *d.Schema, mra *MatchingRuleAssertion) (d.Filter, error) {
+	filter := &d.ExtensibleFilter{Value: mra.MatchValue}
+	filter.DnAttributes, _ = mra.DnAttributes.Get()
+
+	ruleId, hasRule := mra.MatchingRule.Get()
+	desc, hasType := mra.Type.Get()
+	if !hasRule && !hasType {
+		return nil, d.NewLdapError(d.ProtocolError, nil, "extensible match has neither a matching rule nor a type")
+	}
+
+	if hasRule {
+		filter.RuleId = ruleId
+		// unrecognised matching rules leave the filter undefined
+		if rule, err := d.GetMatchingRule(ruleId); err == nil {
+			filter.Rule = &rule
+		}
+	}
+
+	if hasType {
+		filter.Desc = desc
+		filter.Attr = findAttribute(schema, desc)
+	}
+
+	return filter, nil
+}
+
+func domainFilter(schema *d.Schema, f *ber.Choice[FilterChoice]) (d.Filter, error) {
+	if f == nil {
+		return nil, d.NewLdapError(d.ProtocolError, nil, "search request is missing a filter")
+	}
+
+	tag, val, ok := f.Chosen()
+	if !ok {
+		return nil, d.NewLdapError(d.ProtocolError, nil, "could not get filter choice")
+	}
+
+	switch {
+	case tag.Equals(FilterAndTag):
+		and, ok := val.(*[]*ber.Choice[FilterChoice])
+		if !ok {
+			break
+		}
+		filters, err := domainFilters(schema, *and)
+		if err != nil {
+			return nil, err
+		}
+		return d.FilterAnd(filters...), nil
+
+	case tag.Equals(FilterOrTag):
+		or, ok := val.(*[]*ber.Choice[FilterChoice])
+		if !ok {
+			break
+		}
+		filters, err := domainFilters(schema, *or)
+		if err != nil {
+			return nil, err
+		}
+		return d.FilterOr(filters...), nil
+
+	case tag.Equals(FilterNotTag):
+		not, ok := val.(**ber.Choice[FilterChoice])
+		if !ok {
+			break
+		}
+		filter, err := domainFilter(schema, *not)
+		if err != nil {
+			return nil, err
+		}
+		return d.FilterNot(filter), nil
+
+	case tag.Equals(FilterEqualityMatchTag):
+		ava, ok := val.(*AttributeValueAssertion)
+		if !ok {
+			break
+		}
+		return &d.EqualityFilter{
+			Desc:  ava.AttributeDesc,
+			Attr:  findAttribute(schema, ava.AttributeDesc),
+			Value: ava.AssertionValue,
+		}, nil
+
+	case tag.Equals(FilterSubstringsTag):
+		sf, ok := val.(*SubstringFilter)
+		if !ok {
+			break
+		}
+		return domainSubstringsFilter(schema, sf)
+
+	case tag.Equals(FilterGreaterOrEqualTag):
+		ava, ok := val.(*AttributeValueAssertion)
+		if !ok {
+			break
+		}
+		return &d.GreaterOrEqualFilter{
+			Desc:  ava.AttributeDesc,
+			Attr:  findAttribute(schema, ava.AttributeDesc),
+			Value: ava.AssertionValue,
+		}, nil
+
+	case tag.Equals(FilterLessOrEqualTag):
+		ava, ok := val.(*AttributeValueAssertion)
+		if !ok {
+			break
+		}
+		return &d.LessOrEqualFilter{
+			Desc:  ava.AttributeDesc,
+			Attr:  findAttribute(schema, ava.AttributeDesc),
+			Value: ava.AssertionValue,
+		}, nil
+
+	case tag.Equals(FilterPresentTag):
+		present, ok := val.(*string)
+		if !ok {
+			break
+		}
+		return &d.PresentFilter{
+			Desc: *present,
+			Attr: findAttribute(schema, *present),
+		}, nil
+
+	case tag.Equals(FilterApproxMatchTag):
+		ava, ok := val.(*AttributeValueAssertion)
+		if !ok {
+			break
+		}
+		return &d.ApproxFilter{
+			Desc:  ava.AttributeDesc,
+			Attr:  findAttribute(schema, ava.AttributeDesc),
+			Value: ava.AssertionValue,
+		}, nil
+
+	case tag.Equals(FilterExtensibleMatchTag):
+		mra, ok := val.(*MatchingRuleAssertion)
+		if !ok {
+			break
+		}
+		return domainExtensibleFilter(schema, mra)
+
+	default:
+		return nil, d.NewLdapError(d.ProtocolError, nil, "unknown filter type %s", tag)
+	}
+
+	return nil, d.NewLdapError(d.ProtocolError, nil, "unexpected filter value %s for %s", reflect.TypeOf(val), tag)
+}
@@ -95,7 +95,7 @@ func (m *ModifyHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (e
 		return
 	}
 
-	modErr := m.ms.ModifyEntry(mr)
+	modErr := m.ms.ModifyEntry(boundEntry(ctx), mr)
 	if modErr != nil {
 		err = modErr
 		return
@@ -176,7 +176,7 @@ func (m *ModifyDnHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg)
 		return
 	}
 
-	modDnErr := m.ms.ModifyEntryDn(mr)
+	modDnErr := m.ms.ModifyEntryDn(boundEntry(ctx), mr)
 	if modDnErr != nil {
 		err = modDnErr
 		return
@@ -20,21 +20,40 @@ var (
 
 	UnbindRequestTag = ber.Tag{Class: ber.Application, Construct: ber.Primitive, Value: 2}
 
+	SearchRequestTag     = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 3}
+	SearchResultEntryTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 4}
+	SearchResultDoneTag  = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 5}
+
 	ModifyRequestTag  = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 6}
 	ModifyResponseTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 7}
 
 	AddRequestTag  = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 8}
 	AddResponseTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 9}
 
+	DelRequestTag  = ber.Tag{Class: ber.Application, Construct: ber.Primitive, Value: 10}
+	DelResponseTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 11}
+
 	ModifyDnRequestTag  = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 12}
 	ModifyDnResponseTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 13}
+
+	CompareRequestTag  = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 14}
+	CompareResponseTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 15}
+
+	AbandonRequestTag = ber.Tag{Class: ber.Application, Construct: ber.Primitive, Value: 16}
+
+	ExtendedRequestTag      = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 23}
+	ExtendedResponseTag     = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 24}
+	IntermediateResponseTag = ber.Tag{Class: ber.Application, Construct: ber.Constructed, Value: 25}
 )
 
 type LdapMsgChoice struct {
-	BindRequest BindRequest `ber:"class=application,cons=constructed,val=0"`
-	// TODO proper BindResponse type
-	BindResponse  LdapResult `ber:"class=application,cons=constructed,val=1"`
-	UnbindRequest string     `ber:"class=application,cons=primitive,val=2"`
+	BindRequest   BindRequest  `ber:"class=application,cons=constructed,val=0"`
+	BindResponse  BindResponse `ber:"class=application,cons=constructed,val=1"`
+	UnbindRequest string       `ber:"class=application,cons=primitive,val=2"`
+
+	SearchRequest     SearchRequest     `ber:"class=application,cons=constructed,val=3"`
+	SearchResultEntry SearchResultEntry `ber:"class=application,cons=constructed,val=4"`
+	SearchResultDone  LdapResult        `ber:"class=application,cons=constructed,val=5"`
 
 	ModifyRequest  ModifyRequest `ber:"class=application,cons=constructed,val=6"`
 	ModifyResponse LdapResult    `ber:"class=application,cons=constructed,val=7"`
@@ -42,14 +61,26 @@ type LdapMsgChoice struct {
 	AddRequest  AddRequest `ber:"class=application,cons=constructed,val=8"`
 	AddResponse LdapResult `ber:"class=application,cons=constructed,val=9"`
 
+	DelRequest  string     `ber:"class=application,cons=primitive,val=10"`
+	DelResponse LdapResult `ber:"class=application,cons=constructed,val=11"`
+
 	ModifyDnRequest  ModifyDnRequest `ber:"class=application,cons=constructed,val=12"`
 	ModifyDnResponse LdapResult      `ber:"class=application,cons=constructed,val=13"`
+
+	CompareRequest  CompareRequest `ber:"class=application,cons=constructed,val=14"`
+	CompareResponse LdapResult     `ber:"class=application,cons=constructed,val=15"`
+
+	AbandonRequest int `ber:"class=application,cons=primitive,val=16"`
+
+	ExtendedRequest      ExtendedRequest      `ber:"class=application,cons=constructed,val=23"`
+	ExtendedResponse     ExtendedResponse     `ber:"class=application,cons=constructed,val=24"`
+	IntermediateResponse IntermediateResponse `ber:"class=application,cons=constructed,val=25"`
 }
 
 type LdapMsg struct {
 	MessageId int
 	Request   *ber.Choice[LdapMsgChoice]
-	Controls  *ber.Optional[[]byte] `ber:"class=context-specific,cons=constructed,val=0"`
+	Controls  *ber.Optional[[]Control] `ber:"class=context-specific,cons=constructed,val=0"`
 }
 
 // TODO implement embedded structs for en/decoding so i dont have to continually repeat myself
@@ -61,6 +92,24 @@ type LdapResult struct {
 }
 
 func NewResultMsg(tag ber.Tag, msgId int, rc d.ResultCode, matchedDn, format string, a ...any) LdapMsg {
+	// bind and extended responses carry more than an LdapResult so have their own type
+	if tag == BindResponseTag {
+		return NewBindResponse(msgId, rc, matchedDn, nil, format, a...)
+	}
+
+	if tag == ExtendedResponseTag {
+		res := ExtendedResponse{
+			ResultCode:        rc,
+			MatchedDN:         matchedDn,
+			DiagnosticMessage: fmt.Sprintf(format, a...),
+		}
+
+		return LdapMsg{
+			MessageId: msgId,
+			Request:   ber.NewChosen[LdapMsgChoice](tag, res),
+		}
+	}
+
 	res := LdapResult{
 		ResultCode:        rc,
 		MatchedDN:         matchedDn,
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/georgib0y/relientldap/internal/app"
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+const (
+	PasswordModifyOid = "1.3.6.1.4.1.4203.1.11.1"
+)
+
+type PasswdModifyRequestValue struct {
+	Identity  *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=0"`
+	OldPasswd *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=1"`
+	NewPasswd *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=2"`
+}
+
+func (pr PasswdModifyRequestValue) UserIdentity() (string, bool) {
+	return pr.Identity.Get()
+}
+
+func (pr PasswdModifyRequestValue) OldPassword() (string, bool) {
+	return pr.OldPasswd.Get()
+}
+
+func (pr PasswdModifyRequestValue) NewPassword() (string, bool) {
+	return pr.NewPasswd.Get()
+}
+
+type PasswdModifyResponseValue struct {
+	GenPasswd *ber.Optional[string] `ber:"class=context-specific,cons=primitive,val=0"`
+}
+
+// Changes the password of a user, responding with the generated password if
+// the request did not have a new one (RFC 3062)
+func NewPasswordModifyHandler(ps *app.PasswordService) ExtendedHandler {
+	return ExtendedHandleFunc(PasswordModifyOid, func(ctx context.Context, w io.Writer, msgId int, req *ExtendedRequest) error {
+		logger.Print("in password modify request")
+
+		pr := PasswdModifyRequestValue{}
+		if val, ok := req.Value(); ok {
+			if err := ber.Decode(strings.NewReader(val), &pr); err != nil {
+				return writeResponse(w, NewExtendedResponse(msgId, d.ProtocolError, nil, nil, "could not decode password modify request: %s", err))
+			}
+		}
+
+		generated, err := ps.ModifyPassword(boundEntry(ctx), pr)
+		if err != nil {
+			return err
+		}
+
+		if generated == "" {
+			return writeResponse(w, NewExtendedResponse(msgId, d.Success, nil, nil, ""))
+		}
+
+		var buf bytes.Buffer
+		if _, err := ber.Encode(&buf, PasswdModifyResponseValue{ber.NewOptional(generated)}); err != nil {
+			return err
+		}
+
+		return writeResponse(w, NewExtendedResponse(msgId, d.Success, nil, ber.NewOptional(buf.String()), ""))
+	})
+}
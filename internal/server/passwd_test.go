@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/util"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+func TestPasswdModifyRequestValueWire(t *testing.T) {
+	tests := []struct {
+		name string
+		pr   PasswdModifyRequestValue
+		b    []byte
+	}{
+		{
+			name: "every field",
+			pr: PasswdModifyRequestValue{
+				Identity:  ber.NewOptional("cn=a,dc=dev"),
+				OldPasswd: ber.NewOptional("old"),
+				NewPasswd: ber.NewOptional("new"),
+			},
+			b: []byte{
+				0x30, 0x17, // PasswdModifyRequestValue tag/len
+				0x80, 0x0B, 0x63, 0x6E, 0x3D, 0x61, 0x2C, 0x64, 0x63, 0x3D, 0x64, 0x65, 0x76, // userIdentity [0]: "cn=a,dc=dev"
+				0x81, 0x03, 0x6F, 0x6C, 0x64, // oldPasswd [1]: "old"
+				0x82, 0x03, 0x6E, 0x65, 0x77, // newPasswd [2]: "new"
+			},
+		},
+		{
+			name: "only new password",
+			pr:   PasswdModifyRequestValue{NewPasswd: ber.NewOptional("new")},
+			b: []byte{
+				0x30, 0x05, // PasswdModifyRequestValue tag/len
+				0x82, 0x03, 0x6E, 0x65, 0x77, // newPasswd [2]: "new"
+			},
+		},
+		{
+			name: "no fields",
+			pr:   PasswdModifyRequestValue{},
+			b:    []byte{0x30, 0x00},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if _, err := ber.Encode(&buf, test.pr); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), test.b) {
+			t.Fatalf("%s: encoding:\n%s\ndid not match expected:\n%s\n", test.name, util.BytesAsHex(buf.Bytes()), util.BytesAsHex(test.b))
+		}
+
+		var pr PasswdModifyRequestValue
+		if err := ber.Decode(bytes.NewReader(test.b), &pr); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		decoded := []*ber.Optional[string]{pr.Identity, pr.OldPasswd, pr.NewPasswd}
+		exp := []*ber.Optional[string]{test.pr.Identity, test.pr.OldPasswd, test.pr.NewPasswd}
+		for i := range decoded {
+			v, ok := decoded[i].Get()
+			expV, expOk := exp[i].Get()
+			if v != expV || ok != expOk {
+				t.Fatalf("%s: decoded field %d as %q (%t), expected %q (%t)", test.name, i, v, ok, expV, expOk)
+			}
+		}
+	}
+}
+
+func TestPasswdModifyResponseValueWire(t *testing.T) {
+	b := []byte{
+		0x30, 0x0A, // PasswdModifyResponseValue tag/len
+		0x80, 0x08, 0x73, 0x33, 0x63, 0x72, 0x33, 0x74, 0x21, 0x21, // genPasswd [0]: "s3cr3t!!"
+	}
+
+	var buf bytes.Buffer
+	if _, err := ber.Encode(&buf, PasswdModifyResponseValue{ber.NewOptional("s3cr3t!!")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Fatalf("encoding:\n%s\ndid not match expected:\n%s\n", util.BytesAsHex(buf.Bytes()), util.BytesAsHex(b))
+	}
+
+	var res PasswdModifyResponseValue
+	if err := ber.Decode(bytes.NewReader(b), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if gen, ok := res.GenPasswd.Get(); !ok || gen != "s3cr3t!!" {
+		t.Fatalf("decoded generated password %q (%t), expected %q", gen, ok, "s3cr3t!!")
+	}
+}
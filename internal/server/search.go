@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/georgib0y/relientldap/internal/app"
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+type SearchRequest struct {
+	BaseObject   string
+	Scope        d.SearchScope    `ber:"class=universal,cons=primitive,val=10"` // enumerated
+	DerefAliases app.DerefAliases `ber:"class=universal,cons=primitive,val=10"` // enumerated
+	SizeLimit    int
+	TimeLimit    int
+	TypesOnly    bool
+	Filter       *ber.Choice[FilterChoice]
+	Attributes   []string
+}
+
+func (sr SearchRequest) BaseDn() string {
+	return sr.BaseObject
+}
+
+func (sr SearchRequest) SearchScope() d.SearchScope {
+	return sr.Scope
+}
+
+func (sr SearchRequest) Deref() app.DerefAliases {
+	return sr.DerefAliases
+}
+
+func (sr SearchRequest) MaxEntries() int {
+	return sr.SizeLimit
+}
+
+func (sr SearchRequest) MaxTime() time.Duration {
+	return time.Duration(sr.TimeLimit) * time.Second
+}
+
+func (sr SearchRequest) AttrsOnly() bool {
+	return sr.TypesOnly
+}
+
+func (sr SearchRequest) DomainFilter(schema *d.Schema) (d.Filter, error) {
+	filter, err := domainFilter(schema, sr.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Printf("search filter: %s", d.FilterString(filter))
+	return filter, nil
+}
+
+func (sr SearchRequest) RequestedAttrs() []string {
+	return sr.Attributes
+}
+
+type SearchResultEntry struct {
+	ObjectName string
+	Attributes []PartialAttribute
+}
+
+func NewSearchResultEntry(msgId int, e app.SearchEntry) LdapMsg {
+	attrs := []PartialAttribute{}
+	for _, name := range slices.Sorted(maps.Keys(e.Attrs)) {
+		vals := ber.Set[string]{}
+		for _, v := range e.Attrs[name] {
+			vals[v] = struct{}{}
+		}
+		attrs = append(attrs, PartialAttribute{AType: name, Vals: vals})
+	}
+
+	return LdapMsg{
+		MessageId: msgId,
+		Request: ber.NewChosen[LdapMsgChoice](SearchResultEntryTag, SearchResultEntry{
+			ObjectName: e.Dn,
+			Attributes: attrs,
+		}),
+	}
+}
+
+func NewSearchResultDone(msgId int, rc d.ResultCode, matchedDn, format string, a ...any) LdapMsg {
+	return NewResultMsg(SearchResultDoneTag, msgId, rc, matchedDn, format, a...)
+}
+
+type SearchHandler struct {
+	ss *app.SearchService
+}
+
+func NewSearchHandler(ss *app.SearchService) *SearchHandler {
+	return &SearchHandler{ss}
+}
+
+func (s *SearchHandler) RequestTag() ber.Tag {
+	return SearchRequestTag
+}
+
+func (s *SearchHandler) ResponseTag() ber.Tag {
+	return SearchResultDoneTag
+}
+
+func (s *SearchHandler) Handle(ctx context.Context, w io.Writer, msg LdapMsg) (err error) {
+	var res LdapMsg
+	defer func() {
+		if err == nil {
+			err = writeResponse(w, res)
+		}
+	}()
+
+	logger.Print("in search request")
+
+	_, req, ok := msg.Request.Chosen()
+	if !ok {
+		res = NewSearchResultDone(msg.MessageId, d.ProtocolError, "", "could not get search request choice")
+		return
+	}
+
+	sr, ok := req.(*SearchRequest)
+	if !ok {
+		res = NewSearchResultDone(
+			msg.MessageId,
+			d.ProtocolError,
+			"",
+			"expected *SearchRequest, got %s", reflect.TypeOf(req),
+		)
+		return
+	}
+
+	entries, searchErr := s.ss.Search(ctx, sr)
+
+	// entries are still sent when a size or time limit is hit
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			err = AbandonedError
+			return
+		}
+
+		if err = writeResponse(w, NewSearchResultEntry(msg.MessageId, e)); err != nil {
+			return
+		}
+	}
+
+	// an abandoned search gets no SearchResultDone (RFC 4511 4.11)
+	if ctx.Err() != nil {
+		err = AbandonedError
+		return
+	}
+
+	if searchErr != nil {
+		err = searchErr
+		return
+	}
+
+	logger.Printf("search at %s returned %d entries", sr.BaseDn(), len(entries))
+	res = NewSearchResultDone(msg.MessageId, d.Success, "", "returned %d entries", len(entries))
+	return
+}
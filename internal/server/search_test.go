@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/app"
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+func loadTestSchema(t *testing.T) *d.Schema {
+	t.Helper()
+	attrs, err := os.Open("../../ldif/attributes.ldif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer attrs.Close()
+
+	ocs, err := os.Open("../../ldif/objClasses.ldif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ocs.Close()
+
+	schema, err := d.LoadSchemaFromReaders(attrs, ocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func newTestSearchHandler(t *testing.T) *SearchHandler {
+	schema := loadTestSchema(t)
+	scheduler := app.NewScheduler(d.GenerateTestDIT(schema), schema)
+	t.Cleanup(scheduler.Close)
+	return NewSearchHandler(app.NewSearchService(schema, scheduler))
+}
+
+func TestSearchHandler(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	var buf bytes.Buffer
+	if err := h.Handle(context.Background(), &buf, searchRequest(1, "dc=georgiboy,dc=dev")); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []ber.Tag{SearchResultEntryTag, SearchResultDoneTag}
+	for _, e := range exp {
+		var msg LdapMsg
+		if err := ber.Decode(&buf, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if tag, _, _ := msg.Request.Chosen(); !tag.Equals(e) {
+			t.Fatalf("received %s, expected %s", tag, e)
+		}
+	}
+}
+
+func TestAbandonedSearchHasNoResponse(t *testing.T) {
+	h := newTestSearchHandler(t)
+
+	// abandoned before the walk of the DIT started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	if err := h.Handle(ctx, &buf, searchRequest(1, "dc=georgiboy,dc=dev")); !errors.Is(err, AbandonedError) {
+		t.Fatalf("expected the search to be abandoned, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing to be written for an abandoned search, got %d bytes", buf.Len())
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/internal/util"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+const (
+	StartTLSOid = "1.3.6.1.4.1.1466.20037"
+)
+
+// What a connection is read from and written to, which changes when the
+// connection is upgraded with StartTLS
+type transport struct {
+	r     io.Reader
+	cw    *connWriter
+	conn  net.Conn
+	isTLS bool
+}
+
+func newTransport(c net.Conn) *transport {
+	_, isTLS := c.(*tls.Conn)
+	return &transport{
+		r:     io.TeeReader(c, util.NewHexLogger("in")),
+		cw:    newConnWriter(io.MultiWriter(util.NewHexLogger("out"), c)),
+		conn:  c,
+		isTLS: isTLS,
+	}
+}
+
+// Upgrades the connection to TLS once every response has been written. No
+// other operations can be running and nothing can be reading the connection.
+func (t *transport) startTLS(config *tls.Config) error {
+	if err := t.cw.flush(); err != nil {
+		return err
+	}
+
+	tc := tls.Server(t.conn, config)
+	if err := tc.Handshake(); err != nil {
+		return err
+	}
+
+	t.r = io.TeeReader(tc, util.NewHexLogger("in"))
+	t.cw.w = io.MultiWriter(util.NewHexLogger("out"), tc)
+	t.conn = tc
+	t.isTLS = true
+	return nil
+}
+
+// The client certificate of a TLS connection, only if it was verified
+func (t *transport) clientCertificate() (*x509.Certificate, bool) {
+	tc, ok := t.conn.(*tls.Conn)
+	if !ok {
+		return nil, false
+	}
+
+	state := tc.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil, false
+	}
+	return state.PeerCertificates[0], true
+}
+
+func isStartTLS(msg LdapMsg) bool {
+	_, req, ok := msg.Request.Chosen()
+	if !ok {
+		return false
+	}
+
+	er, ok := req.(*ExtendedRequest)
+	return ok && er.Name() == StartTLSOid
+}
+
+// Handles the StartTLS extended operation (RFC 4511 4.14), the response is
+// sent in the clear and then the TLS handshake is done
+func NewStartTLSHandler(config *tls.Config) ExtendedHandler {
+	return ExtendedHandleFunc(StartTLSOid, func(ctx context.Context, w io.Writer, msgId int, req *ExtendedRequest) error {
+		logger.Print("in start tls request")
+
+		t, ok := ctx.Value(TransportKey).(*transport)
+		if !ok {
+			return d.NewLdapError(d.Other, nil, "connection cannot be upgraded to tls")
+		}
+
+		if t.isTLS {
+			return d.NewLdapError(d.OperationsError, nil, "tls is already established")
+		}
+
+		res := NewExtendedResponse(msgId, d.Success, ber.NewOptional(StartTLSOid), nil, "")
+		if err := writeResponse(w, res); err != nil {
+			return err
+		}
+
+		if err := t.startTLS(config); err != nil {
+			return err
+		}
+
+		logger.Print("upgraded connection to tls")
+		return nil
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+// A self signed certificate for localhost, returned as the server's config and
+// a client config that trusts it
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	return server, client
+}
+
+func startTLSRequest(msgId int) LdapMsg {
+	return extendedRequest(msgId, StartTLSOid, nil)
+}
+
+// Sends a StartTLS request and does the handshake once it succeeds
+func startTLS(t *testing.T, c net.Conn, msgId int, config *tls.Config) *tls.Conn {
+	t.Helper()
+	sendMsg(t, c, startTLSRequest(msgId))
+	res := receiveExtendedResponse(t, c, msgId)
+	if res.ResultCode != d.Success {
+		t.Fatalf("could not start tls: %s: %s", res.ResultCode, res.DiagnosticMessage)
+	}
+
+	tc := tls.Client(c, config)
+	tc.SetDeadline(time.Now().Add(testTimeout))
+	if err := tc.Handshake(); err != nil {
+		t.Fatalf("tls handshake failed: %s", err)
+	}
+	return tc
+}
+
+func TestStartTLSUpgradesConnection(t *testing.T) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+	m := NewMux().
+		AddExtendedHandler(NewStartTLSHandler(serverConfig)).
+		AddHandler(echoSearchHandler(func(int) {}))
+	c, _ := servePipe(t, m)
+
+	tc := startTLS(t, c, 1, clientConfig)
+
+	// later requests are read and answered over tls
+	sendMsg(t, tc, searchRequest(2, "cn=secure"))
+	msg, tag, res := receiveMsg(t, tc)
+	if msg.MessageId != 2 || !tag.Equals(SearchResultEntryTag) || res.(*SearchResultEntry).ObjectName != "cn=secure" {
+		t.Fatalf("expected the entry of search 2 over tls, got %s for message %d", tag, msg.MessageId)
+	}
+	if _, tag, _ = receiveMsg(t, tc); !tag.Equals(SearchResultDoneTag) {
+		t.Fatalf("expected search 2 to be done, got %s", tag)
+	}
+}
+
+func TestStartTLSWhenAlreadyTLS(t *testing.T) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+	c, _ := servePipe(t, NewMux().AddExtendedHandler(NewStartTLSHandler(serverConfig)))
+
+	tc := startTLS(t, c, 1, clientConfig)
+
+	sendMsg(t, tc, startTLSRequest(2))
+	if res := receiveExtendedResponse(t, tc, 2); res.ResultCode != d.OperationsError {
+		t.Fatalf("expected an operations error, got %s", res.ResultCode)
+	}
+}
+
+func TestStartTLSWithOutstandingOperations(t *testing.T) {
+	serverConfig, _ := testTLSConfigs(t)
+	release := make(chan struct{})
+	m := NewMux().
+		AddExtendedHandler(NewStartTLSHandler(serverConfig)).
+		AddHandler(echoSearchHandler(func(int) { <-release }))
+	c, _ := servePipe(t, m)
+
+	sendMsg(t, c, searchRequest(1, "cn=outstanding"))
+	sendMsg(t, c, startTLSRequest(2))
+
+	// refused without waiting for the search
+	if res := receiveExtendedResponse(t, c, 2); res.ResultCode != d.OperationsError {
+		t.Fatalf("expected an operations error, got %s", res.ResultCode)
+	}
+
+	close(release)
+	exp := []ber.Tag{SearchResultEntryTag, SearchResultDoneTag}
+	for _, e := range exp {
+		if msg, tag, _ := receiveMsg(t, c); msg.MessageId != 1 || !tag.Equals(e) {
+			t.Fatalf("received %s for message %d, expected %s for message 1", tag, msg.MessageId, e)
+		}
+	}
+}
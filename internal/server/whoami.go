@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	d "github.com/georgib0y/relientldap/internal/domain"
+	"github.com/georgib0y/relientldap/pkg/ber"
+)
+
+const (
+	WhoAmIOid = "1.3.6.1.4.1.4203.1.11.3"
+)
+
+// Returns the authorization identity of the connection as dn:<bound dn>, or
+// an empty identity if the connection is anonymous (RFC 4532)
+var WhoAmIHandler = ExtendedHandleFunc(WhoAmIOid, func(ctx context.Context, w io.Writer, msgId int, req *ExtendedRequest) error {
+	logger.Print("in who am i request")
+
+	if _, ok := req.Value(); ok {
+		return writeResponse(w, NewExtendedResponse(msgId, d.ProtocolError, nil, nil, "who am i requests do not have a value"))
+	}
+
+	authzId := ""
+	if entry := boundEntry(ctx); entry != nil {
+		dn := entry.Dn()
+		authzId = "dn:" + dn.String()
+	}
+
+	return writeResponse(w, NewExtendedResponse(msgId, d.Success, nil, ber.NewOptional(authzId), ""))
+})
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/georgib0y/relientldap/internal/app"
+	d "github.com/georgib0y/relientldap/internal/domain"
+)
+
+func TestWhoAmI(t *testing.T) {
+	schema := loadTestSchema(t)
+	scheduler := app.NewScheduler(d.GenerateTestDIT(schema), schema)
+	t.Cleanup(scheduler.Close)
+
+	m := NewMux().
+		AddHandler(NewBindHandler(app.NewBindService(schema, scheduler))).
+		AddExtendedHandler(WhoAmIHandler)
+
+	boundDn := "cn=Test1,dc=georgiboy,dc=dev"
+	dn, err := d.NormaliseDN(schema, boundDn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		bindDn   string
+		password string
+		authzId  string
+	}{
+		{name: "anonymous"},
+		{name: "bound", bindDn: boundDn, password: "password123", authzId: "dn:" + dn.String()},
+	}
+
+	for _, test := range tests {
+		c, _ := servePipe(t, m)
+
+		if test.bindDn != "" {
+			sendMsg(t, c, simpleBindRequest(1, test.bindDn, test.password))
+			_, _, res := receiveMsg(t, c)
+			if rc := res.(*BindResponse).ResultCode; rc != d.Success {
+				t.Fatalf("%s: could not bind: %s", test.name, rc)
+			}
+		}
+
+		sendMsg(t, c, extendedRequest(2, WhoAmIOid, nil))
+		res := receiveExtendedResponse(t, c, 2)
+		if res.ResultCode != d.Success {
+			t.Fatalf("%s: expected success, got %s: %s", test.name, res.ResultCode, res.DiagnosticMessage)
+		}
+
+		authzId, ok := res.ResponseValue.Get()
+		if !ok || authzId != test.authzId {
+			t.Errorf("%s: expected authzId %q, got %q (%t)", test.name, test.authzId, authzId, ok)
+		}
+	}
+}
@@ -549,3 +549,87 @@ func TestEnDeCodeSet(t *testing.T) {
 		}
 	}
 }
+
+type TestFilterChoice struct {
+	And     []*Choice[TestFilterChoice] `ber:"class=context-specific,cons=constructed,val=0"`
+	Not     *Choice[TestFilterChoice]   `ber:"class=context-specific,cons=constructed,val=2"`
+	Present string                      `ber:"class=context-specific,cons=primitive,val=7"`
+}
+
+type TestFilterHolder struct {
+	Filter *Choice[TestFilterChoice]
+}
+
+func TestDecodeNestedChoices(t *testing.T) {
+	// (&(a=*)(!(b=*)))
+	b := []byte{
+		0x30, 0x0C, // seq tag/len
+		0xA0, 0x0A, // and tag/len
+		0x87, 0x01, 0x61, // present "a"
+		0xA2, 0x05, // not tag/len
+		0x87, 0x03, 0x62, 0x62, 0x62, // present "bbb"
+	}
+
+	var fh TestFilterHolder
+	if err := Decode(bytes.NewBuffer(b), &fh); err != nil {
+		t.Fatal(err)
+	}
+
+	and := fh.Filter.Choices.And
+	if len(and) != 2 {
+		t.Fatalf("expected 2 filters in and, got %d", len(and))
+	}
+
+	if and[0].Choices.Present != "a" {
+		t.Fatalf("expected first present to be %q, got %q", "a", and[0].Choices.Present)
+	}
+
+	not := and[1].Choices.Not
+	if not == nil {
+		t.Fatal("expected second filter to be a not")
+	}
+
+	if not.Choices.Present != "bbb" {
+		t.Fatalf("expected not present to be %q, got %q", "bbb", not.Choices.Present)
+	}
+}
+
+type AllOptional struct {
+	First  *Optional[string] `ber:"class=context-specific,cons=primitive,val=0"`
+	Second *Optional[string] `ber:"class=context-specific,cons=primitive,val=1"`
+}
+
+func TestDecodeEmptySequence(t *testing.T) {
+	var ao AllOptional
+	if err := Decode(bytes.NewBuffer([]byte{0x30, 0x00}), &ao); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ao.First.Get(); ok {
+		t.Fatal("expected first to be empty")
+	}
+	if _, ok := ao.Second.Get(); ok {
+		t.Fatal("expected second to be empty")
+	}
+
+	// the fields of a bind request are not optional
+	var br BindRequest
+	if err := Decode(bytes.NewBuffer([]byte{0x30, 0x00}), &br); err == nil {
+		t.Fatal("expected an empty bind request to fail to decode")
+	}
+}
+
+func TestDecodeEmptyLastOptional(t *testing.T) {
+	// the first optional is not present and the second is an empty string
+	var ao AllOptional
+	if err := Decode(bytes.NewBuffer([]byte{0x30, 0x02, 0x81, 0x00}), &ao); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ao.First.Get(); ok {
+		t.Fatal("expected first to be empty")
+	}
+	if v, ok := ao.Second.Get(); !ok || v != "" {
+		t.Fatalf("expected second to be an empty string, got %q (%t)", v, ok)
+	}
+}
@@ -152,8 +152,21 @@ func decodeSlice(r io.Reader, len int, s any) (int, error) {
 			return read, err
 		}
 
-		// TODO enforce cant be choice or optional
 		elem := reflect.New(v.Type().Elem())
+
+		// choices take their tag from whatever was decoded
+		if c, ok := newChoice(elem.Elem()); ok {
+			n, err = decodeChoice(r, c, dt, len)
+			read += n
+			if err != nil {
+				return read, err
+			}
+
+			sv = reflect.Append(sv, elem.Elem())
+			continue
+		}
+
+		// TODO enforce cant be optional
 		t, err := defaultTag(elem.Interface())
 		if t.Class != dt.Class || t.Construct != dt.Construct || t.Value != dt.Value {
 			logger.Printf("decoded tag %s did not match expected %s", dt, t)
@@ -209,6 +222,14 @@ func decodeStructField(r io.Reader, sv reflect.Value, idx int, dt Tag, len int)
 			logger.Print("finished decoding optional (no tag match)")
 			return false, 0, nil
 		}
+
+		ov := reflect.New(reflect.TypeOf(val))
+		n, err := decodeContents(r, len, ov.Interface())
+		if err != nil {
+			return true, n, err
+		}
+
+		return true, n, o.setAny(ov.Elem().Interface())
 	}
 
 	if !f.CanAddr() {
@@ -306,6 +327,21 @@ func decodeStruct(r io.Reader, len int, s any) (int, error) {
 		return 0, nil
 	}
 
+	// an empty sequence has nothing to decode, which is only valid when every
+	// field is optional
+	if len == 0 {
+		for i := range v.NumField() {
+			f := v.Field(i)
+			if !f.CanInterface() {
+				return 0, fmt.Errorf("can't interface field %q (probably unexported)", v.Type().Field(i).Name)
+			}
+			if _, ok := f.Interface().(optional); !ok {
+				return 0, fmt.Errorf("empty %s is missing field %q", v.Type(), v.Type().Field(i).Name)
+			}
+		}
+		return 0, nil
+	}
+
 	read := 0
 
 	dt, n, err := decodeTag(r)
@@ -328,25 +364,30 @@ func decodeStruct(r io.Reader, len int, s any) (int, error) {
 			return read, err
 		}
 
+		// an optional that was not present leaves the tag for the next field,
+		// which may be an empty last element so is decoded even if every byte
+		// has been read
+		if !decoded {
+			continue
+		}
+
 		if read == len {
 			break
 		}
 
-		if decoded {
-			dt1, n, err := decodeTag(r)
-			read += n
-			if err != nil {
-				return read, err
-			}
-			dt = dt1
+		dt1, n, err := decodeTag(r)
+		read += n
+		if err != nil {
+			return read, err
+		}
+		dt = dt1
 
-			dl1, n, err := decodeLen(r)
-			read += n
-			if err != nil {
-				return read, err
-			}
-			dl = dl1
+		dl1, n, err := decodeLen(r)
+		read += n
+		if err != nil {
+			return read, err
 		}
+		dl = dl1
 	}
 
 	return read, nil
@@ -394,6 +435,7 @@ func decodeContents(r io.Reader, len int, contents any) (int, error) {
 				return read, err
 			}
 			v.Elem().Set(reflect.ValueOf(b))
+			break
 		}
 
 		n, err := decodeSlice(r, len, contents)
@@ -414,6 +456,31 @@ func decodeContents(r io.Reader, len int, contents any) (int, error) {
 		if err != nil {
 			return read, err
 		}
+	case reflect.Pointer:
+		// an explicitly tagged choice, e.g. the not filter in ldap
+		// search requests, wraps the tag of the chosen value
+		c, ok := newChoice(v.Elem())
+		if !ok {
+			return 0, fmt.Errorf("unknown decoding pointer type %q", v.Elem().Type())
+		}
+
+		dt, n, err := decodeTag(r)
+		read += n
+		if err != nil {
+			return read, err
+		}
+
+		dl, n, err := decodeLen(r)
+		read += n
+		if err != nil {
+			return read, err
+		}
+
+		n, err = decodeChoice(r, c, dt, dl)
+		read += n
+		if err != nil {
+			return read, err
+		}
 	default:
 		return 0, fmt.Errorf("unknown decoding kind %q", v.Elem().Kind())
 	}
@@ -421,6 +488,20 @@ func decodeContents(r io.Reader, len int, contents any) (int, error) {
 	return read, nil
 }
 
+// if v is a pointer to a choice, sets v to a newly allocated choice and returns it
+func newChoice(v reflect.Value) (choice, bool) {
+	if v.Kind() != reflect.Pointer || !v.CanSet() {
+		return nil, false
+	}
+
+	if !v.Type().Implements(reflect.TypeFor[choice]()) {
+		return nil, false
+	}
+
+	v.Set(reflect.New(v.Type().Elem()))
+	return v.Interface().(choice), true
+}
+
 func decodeChoice(r io.Reader, c choice, dt Tag, len int) (int, error) {
 	logger.Printf("chosen choice tag is: %s", dt)
 	v, err := c.Choose(dt)
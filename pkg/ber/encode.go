@@ -200,8 +200,7 @@ func encodeStruct(w io.Writer, s any) (int, error) {
 			if !some {
 				continue
 			}
-			// calling elem to get what the pointer points to
-			f = reflect.ValueOf(val).Elem()
+			f = reflect.ValueOf(val)
 		}
 
 		st := v.Type().Field(i).Tag.Get("ber")
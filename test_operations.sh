@@ -55,3 +55,9 @@ echo
 echo "--- did some more modifications to dexter ---"
 echo
 
+
+ldapsearch -vvv -d 5 -H "$LDAP_ADDR" -D "$BIND_DN" -w "$BIND_PW" -b "dc=georgiboy,dc=dev" "(&(objectClass=person)(cn=Dexter McClary))" cn sn description || die "could not search op"
+
+echo
+echo "--- searched for dexter ---"
+echo